@@ -1,4 +1,4 @@
-import apiClient from './axiosClient';
+import apiClient, { refreshSession } from './axiosClient';
 
 export interface LoginRequest {
   email: string;
@@ -22,6 +22,7 @@ export interface AuthResponse {
     updated_at: string;
   };
   token: string;
+  refresh_token: string;
   expires_in: number;
 }
 
@@ -45,6 +46,7 @@ class AuthAPI {
       // Lưu token và user info vào localStorage
       if (response.data.token) {
         localStorage.setItem('authToken', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refresh_token);
         localStorage.setItem('user', JSON.stringify(response.data.user));
       }
       
@@ -66,6 +68,7 @@ class AuthAPI {
       // Auto login sau khi đăng ký thành công
       if (response.data.token) {
         localStorage.setItem('authToken', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refresh_token);
         localStorage.setItem('user', JSON.stringify(response.data.user));
       }
       
@@ -78,13 +81,18 @@ class AuthAPI {
 
   // Đăng xuất
   async logout(): Promise<void> {
+    const refreshToken = localStorage.getItem('refreshToken');
     try {
-      await apiClient.post('/auth/logout');
+      if (refreshToken) {
+        // Thu hồi session phía server
+        await apiClient.post('/auth/logout', { refresh_token: refreshToken });
+      }
     } catch (error) {
       console.error('Logout failed:', error);
     } finally {
       // Luôn xóa token dù API call có lỗi hay không
       localStorage.removeItem('authToken');
+      localStorage.removeItem('refreshToken');
       localStorage.removeItem('user');
     }
   }
@@ -92,15 +100,8 @@ class AuthAPI {
   // Refresh token
   async refreshToken(): Promise<AuthResponse> {
     try {
-      const response = await apiClient.post<AuthResponse>(
-        '/auth/refresh'
-      );
-      
-      if (response.data.token) {
-        localStorage.setItem('authToken', response.data.token);
-      }
-      
-      return response.data;
+      // Dùng chung lần refresh với interceptor; token mới đã được lưu lại
+      return (await refreshSession()) as AuthResponse;
     } catch (error) {
       console.error('Token refresh failed:', error);
       // Nếu refresh fail thì logout
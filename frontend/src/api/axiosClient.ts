@@ -33,21 +33,78 @@ apiClient.interceptors.request.use(
   }
 );
 
+interface RefreshResponse {
+  token: string;
+  refresh_token: string;
+  user: unknown;
+  expires_in: number;
+}
+
+// Refresh token bị xoay vòng mỗi lần dùng và dùng lại token cũ sẽ thu hồi cả
+// session family, nên các request 401 cùng lúc phải chờ chung một lần refresh
+let refreshing: Promise<RefreshResponse> | null = null;
+
+// 401 ở các endpoint này là sai thông tin đăng nhập, không phải token hết hạn
+const noRefreshPaths = ['/auth/login', '/auth/register', '/auth/refresh', '/auth/logout'];
+
+const clearSession = () => {
+  localStorage.removeItem('authToken');
+  localStorage.removeItem('refreshToken');
+  localStorage.removeItem('user');
+};
+
+// Đổi refresh token lấy cặp token mới và lưu lại
+export const refreshSession = (): Promise<RefreshResponse> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refreshToken');
+    // Gọi bằng axios gốc để 401 của chính request này không lặp lại interceptor
+    refreshing = axios
+      .post<RefreshResponse>(`${BASE_URL}/auth/refresh`, { refresh_token: refreshToken })
+      .then((response) => {
+        localStorage.setItem('authToken', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refresh_token);
+        localStorage.setItem('user', JSON.stringify(response.data.user));
+        return response.data;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
 // Response interceptor - Xử lý response và error
 apiClient.interceptors.response.use(
   (response) => {
     console.log(`✅ API Response: ${response.status} ${response.config.url}`);
     return response;
   },
-  (error) => {
+  async (error) => {
     console.error(`❌ API Error: ${error.response?.status} ${error.config?.url}`);
     
     // Xử lý các lỗi chung
     if (error.response?.status === 401) {
+      const original = error.config;
+      // Access token hết hạn (15 phút): refresh một lần rồi gửi lại request
+      const canRefresh = typeof window !== 'undefined'
+        && original
+        && !original._retried
+        && !noRefreshPaths.includes(original.url)
+        && localStorage.getItem('refreshToken');
+      if (canRefresh) {
+        original._retried = true;
+        try {
+          const { token } = await refreshSession();
+          original.headers.Authorization = `Bearer ${token}`;
+          return apiClient(original);
+        } catch (refreshError) {
+          console.error('Token refresh failed:', refreshError);
+        }
+      }
+
       // Unauthorized - xóa token và redirect đến login
       if (typeof window !== 'undefined') {
-        localStorage.removeItem('authToken');
-        localStorage.removeItem('user');
+        clearSession();
         // Use setTimeout to avoid synchronous redirect during render
         setTimeout(() => {
           window.location.href = '/login';
@@ -7,28 +7,36 @@ export interface Post {
   user_id: number;
   username: string;
   status?: 'draft' | 'published' | 'archived';
+  tags?: string[];
   created_at: { Time: string; Valid: boolean };
   updated_at: { Time: string; Valid: boolean };
 }
 
+// Phân trang bằng cursor: gửi next_cursor của trang trước làm ?cursor= để lấy trang sau
 export interface PostsListResponse {
   posts: Post[];
-  totalCount?: number; // nếu BE chưa trả, để optional
-  total?: number;      // tạm giữ để tránh crash khi code chỗ khác còn dùng total
-  page?: number;
-  limit?: number;
+  next_cursor: string | null;
+  has_more: boolean;
 }
 
+const pageQuery = (limit: number, cursor?: string | null) => {
+  const params = new URLSearchParams({ limit: String(limit) });
+  if (cursor) params.set('cursor', cursor);
+  return params.toString();
+};
+
 export interface CreatePostRequest {
   title: string;
   content: string;
   status?: 'draft' | 'published';
+  tags?: string[];
 }
 
 export interface UpdatePostRequest {
   title?: string;
   content?: string;
   status?: 'draft' | 'published' | 'archived';
+  tags?: string[];
 }
 
 export interface ApiResponse<T> {
@@ -38,13 +46,13 @@ export interface ApiResponse<T> {
 }
 
 class PostAPI {
-  async getPosts(page = 1, limit = 10): Promise<PostsListResponse> {
-    const response = await apiClient.get<PostsListResponse>(`/posts?page=${page}&limit=${limit}`);
+  async getPosts(limit = 10, cursor?: string | null): Promise<PostsListResponse> {
+    const response = await apiClient.get<PostsListResponse>(`/posts?${pageQuery(limit, cursor)}`);
     return response.data;
   }
 
-  async getPostsByUser(userId: number, page = 1, limit = 10): Promise<PostsListResponse> {
-    const response = await apiClient.get<PostsListResponse>(`/posts/user/${userId}?page=${page}&limit=${limit}`);
+  async getPostsByUser(userId: number, limit = 10, cursor?: string | null): Promise<PostsListResponse> {
+    const response = await apiClient.get<PostsListResponse>(`/posts/user/${userId}?${pageQuery(limit, cursor)}`);
     return response.data;
   }
 
@@ -2,7 +2,8 @@ import apiClient from './axiosClient';
 
 export interface User {
   id: number;
-  email: string;
+  // only sent to admins and to the user themself
+  email?: string;
   username: string;
   role: string;
   created_at: string;
@@ -1,5 +1,5 @@
 ﻿// src/pages/HomePage.tsx
-import { useEffect, useState, useCallback, useMemo } from 'react';
+import { useEffect, useState, useCallback, useMemo, useRef } from 'react';
 import PostAPI from '../api/postApi';
 import type { Post, PostsListResponse } from '../api/postApi';
 import UserAPI from '../api/userApi';
@@ -37,15 +37,12 @@ const HomePage = () => {
   const [postsLoading, setPostsLoading] = useState(true);
   const [postsError, setPostsError] = useState<string | null>(null);
   const [page, setPage] = useState(1);
-  const [totalCount, setTotalCount] = useState<number | null>(null);
+  // cursors[i] là cursor của trang i + 1; trang đầu không cần cursor
+  const cursors = useRef<(string | null)[]>([null]);
+  const [hasMore, setHasMore] = useState(false);
 
   const canPrev = useMemo(() => page > 1 && !postsLoading, [page, postsLoading]);
-  const canNext = useMemo(() => {
-    if (typeof totalCount === 'number') {
-      return !postsLoading && page * POSTS_PER_PAGE < totalCount;
-    }
-    return !postsLoading && posts.length === POSTS_PER_PAGE;
-  }, [page, postsLoading, posts.length, totalCount]);
+  const canNext = useMemo(() => hasMore && !postsLoading, [hasMore, postsLoading]);
 
   const formatDate = useCallback((dt?: { Time: string; Valid: boolean }) => {
     if (!dt || !dt.Valid) return '';
@@ -71,20 +68,16 @@ const HomePage = () => {
     try {
       setPostsLoading(true);
       setPostsError(null);
-      const postsRes: PostsListResponse = await PostAPI.getPosts(p, POSTS_PER_PAGE);
+      const postsRes: PostsListResponse = await PostAPI.getPosts(POSTS_PER_PAGE, cursors.current[p - 1]);
       const fetchedPosts = Array.isArray(postsRes.posts) ? postsRes.posts : [];
       setPosts(fetchedPosts);
-      const derivedTotal = typeof postsRes.totalCount === 'number'
-        ? postsRes.totalCount
-        : typeof postsRes.total === 'number'
-        ? postsRes.total
-        : null;
-      setTotalCount(derivedTotal);
+      cursors.current[p] = postsRes.next_cursor;
+      setHasMore(postsRes.has_more);
     } catch (err) {
       console.error('Failed to load posts:', err);
       setPostsError('Không tải được danh sách bài viết.');
       setPosts([]);
-      setTotalCount(null);
+      setHasMore(false);
     } finally {
       setPostsLoading(false);
     }
@@ -210,7 +203,6 @@ const HomePage = () => {
           <div className="flex items-center gap-2 text-sm text-slate-500">
             <span>
               Page {page}
-              {typeof totalCount === 'number' && <span className="ml-1">• Tổng: {totalCount}</span>}
             </span>
             <div className="flex gap-2">
               <button
@@ -1,4 +1,4 @@
-﻿import React, { useCallback, useEffect, useMemo, useState } from 'react';
+﻿import React, { useCallback, useEffect, useMemo, useRef, useState } from 'react';
 import { useSearchParams } from 'react-router-dom';
 import PostAPI from '../api/postApi';
 import type { CreatePostRequest, Post } from '../api/postApi';
@@ -7,6 +7,9 @@ const PostsPage: React.FC = () => {
   const [searchParams, setSearchParams] = useSearchParams();
   const [page, setPage] = useState(1);
   const limit = 5;
+  // cursors[i] là cursor của trang i + 1; trang đầu không cần cursor
+  const cursors = useRef<(string | null)[]>([null]);
+  const [hasMore, setHasMore] = useState(false);
 
   const [posts, setPosts] = useState<Post[]>([]);
   const [loading, setLoading] = useState(true);
@@ -53,18 +56,22 @@ const PostsPage: React.FC = () => {
       setLoading(true);
       setError(null);
 
+      const cursor = cursors.current[page - 1];
       let data;
       if (userIdFilter) {
-        data = await PostAPI.getPostsByUser(userIdFilter, page, limit);
+        data = await PostAPI.getPostsByUser(userIdFilter, limit, cursor);
       } else {
-        data = await PostAPI.getPosts(page, limit);
+        data = await PostAPI.getPosts(limit, cursor);
       }
 
       setPosts(Array.isArray(data.posts) ? data.posts : []);
+      cursors.current[page] = data.next_cursor;
+      setHasMore(data.has_more);
     } catch (err) {
       console.error('Failed to fetch posts:', err);
       setError('Không tải được danh sách bài viết. Vui lòng thử lại sau.');
       setPosts([]);
+      setHasMore(false);
     } finally {
       setLoading(false);
     }
@@ -75,6 +82,7 @@ const PostsPage: React.FC = () => {
   }, [fetchPosts]);
 
   useEffect(() => {
+    cursors.current = [null];
     setPage(1);
   }, [userIdFilter]);
 
@@ -154,8 +162,6 @@ const PostsPage: React.FC = () => {
     [editingPost, fetchPosts, formData, resetForm, submitting]
   );
 
-  const hasMore = posts.length === limit;
-
   return (
     <div className="space-y-8">
       <section className="overflow-hidden rounded-3xl bg-gradient-to-br from-slate-950 via-slate-900 to-indigo-900 px-8 py-10 text-white shadow-2xl">
@@ -5,14 +5,15 @@ import apiClient from '../api/axiosClient';
 interface User {
   id: number;
   username: string;
-  email: string;
+  // only sent to admins and to the user themself
+  email?: string;
   role: string;
-  created_at: { Time: string; Valid: boolean };
+  created_at?: string;
 }
 
 const formatDate = (value: User['created_at']) => {
-  if (!value?.Valid) return '—';
-  return new Date(value.Time).toLocaleDateString('vi-VN');
+  if (!value) return '—';
+  return new Date(value).toLocaleDateString('vi-VN');
 };
 
 export default function UsersPage() {
@@ -91,7 +92,7 @@ export default function UsersPage() {
                         </div>
                       </div>
                     </td>
-                    <td className="px-6 py-4 text-sm text-slate-700">{user.email}</td>
+                    <td className="px-6 py-4 text-sm text-slate-700">{user.email ?? '—'}</td>
                     <td className="px-6 py-4 text-sm text-slate-700">
                       <span className={`rounded-full px-3 py-1 text-xs font-medium ${roleBadgeClass(user.role)}`}>
                         {user.role}
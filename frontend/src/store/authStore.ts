@@ -15,8 +15,8 @@ interface AuthState {
 
 const getErrorMessage = (error: unknown, fallback: string): string => {
   if (typeof error === 'object' && error !== null) {
-    const maybeResponse = error as { response?: { data?: { error?: string } }; message?: unknown };
-    const apiMessage = maybeResponse.response?.data?.error;
+    const maybeResponse = error as { response?: { data?: { message?: string } }; message?: unknown };
+    const apiMessage = maybeResponse.response?.data?.message;
     if (typeof apiMessage === 'string' && apiMessage.trim()) {
       return apiMessage;
     }
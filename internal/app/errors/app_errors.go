@@ -9,13 +9,40 @@ import (
 type AppError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 	Status  int    `json:"-"`
+	Err     error  `json:"-"` // underlying cause; logged, never sent to clients
+}
+
+// FieldError describes one invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of the error carrying extra details for the client
+func (e *AppError) WithDetails(details any) *AppError {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+// Wrap returns a copy of the error that keeps err as its cause
+func (e *AppError) Wrap(err error) *AppError {
+	clone := *e
+	clone.Err = err
+	return &clone
+}
+
 // Error constructors
 func NewBadRequestError(message string) *AppError {
 	return &AppError{
@@ -57,6 +84,46 @@ func NewConflictError(message string) *AppError {
 	}
 }
 
+func NewUnauthorizedError(message string) *AppError {
+	return &AppError{
+		Code:    "UNAUTHORIZED",
+		Message: message,
+		Status:  http.StatusUnauthorized,
+	}
+}
+
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Code:    "FORBIDDEN",
+		Message: message,
+		Status:  http.StatusForbidden,
+	}
+}
+
+func NewUnprocessableEntityError(message string) *AppError {
+	return &AppError{
+		Code:    "UNPROCESSABLE_ENTITY",
+		Message: message,
+		Status:  http.StatusUnprocessableEntity,
+	}
+}
+
+func NewTooManyRequestsError(message string) *AppError {
+	return &AppError{
+		Code:    "TOO_MANY_REQUESTS",
+		Message: message,
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
+func NewTimeoutError(message string) *AppError {
+	return &AppError{
+		Code:    "TIMEOUT",
+		Message: message,
+		Status:  http.StatusGatewayTimeout,
+	}
+}
+
 // User-specific errors
 func UserNotFound(id int64) *AppError {
 	return NewNotFoundError(fmt.Sprintf("User with ID %d not found", id))
@@ -73,3 +140,7 @@ func UserUsernameExists(username string) *AppError {
 func InvalidUserID() *AppError {
 	return NewBadRequestError("Invalid user ID")
 }
+
+func InvalidRole(role string) *AppError {
+	return NewValidationError(fmt.Sprintf("Invalid role %q", role))
+}
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL error codes mapped by FromDB
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqCheckViolation      = "23514"
+)
+
+// FromDB maps database errors to AppErrors; resource names the entity in messages
+// ("post", "user"). AppErrors and unrecognised errors are returned unchanged.
+func FromDB(err error, resource string) error {
+	if err == nil {
+		return nil
+	}
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NewNotFoundError(resource + " not found").Wrap(err)
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		return NewConflictError(resource + " already exists").Wrap(err)
+	case pqForeignKeyViolation:
+		return NewConflictError(fmt.Sprintf("%s refers to a record that does not exist or is still referenced", resource)).Wrap(err)
+	case pqCheckViolation:
+		return NewValidationError(resource + " has an invalid value").Wrap(err)
+	}
+	return err
+}
+
+// UniqueViolation returns the constraint name when err is a unique-key violation
+func UniqueViolation(err error) (string, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return pqErr.Constraint, true
+	}
+	return "", false
+}
@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFromDB(t *testing.T) {
+	plain := errors.New("connection reset")
+	forbidden := NewForbiddenError("no")
+
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"no rows":       {fmt.Errorf("get post: %w", sql.ErrNoRows), http.StatusNotFound},
+		"unique":        {&pq.Error{Code: pqUniqueViolation}, http.StatusConflict},
+		"foreign key":   {&pq.Error{Code: pqForeignKeyViolation}, http.StatusConflict},
+		"check":         {&pq.Error{Code: pqCheckViolation}, http.StatusBadRequest},
+		"app error":     {forbidden, http.StatusForbidden},
+		"other pq code": {&pq.Error{Code: "40001"}, 0},
+		"plain error":   {plain, 0},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := FromDB(tt.err, "post")
+
+			var appErr *AppError
+			if !errors.As(got, &appErr) {
+				if tt.status != 0 {
+					t.Fatalf("expected AppError with status %d, got %v", tt.status, got)
+				}
+				if got != tt.err {
+					t.Errorf("expected unrecognised error to pass through, got %v", got)
+				}
+				return
+			}
+			if appErr.Status != tt.status {
+				t.Errorf("status = %d, want %d", appErr.Status, tt.status)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("expected the cause to be kept")
+			}
+		})
+	}
+}
+
+func TestUniqueViolation(t *testing.T) {
+	constraint, ok := UniqueViolation(fmt.Errorf("create: %w", &pq.Error{Code: pqUniqueViolation, Constraint: "users_email_key"}))
+	if !ok || constraint != "users_email_key" {
+		t.Errorf("got (%q, %v), want (users_email_key, true)", constraint, ok)
+	}
+	if _, ok := UniqueViolation(sql.ErrNoRows); ok {
+		t.Errorf("expected no unique violation for sql.ErrNoRows")
+	}
+}
@@ -0,0 +1,93 @@
+package permissions
+
+// Permission is a single action a role may perform, written as "resource:action"
+type Permission string
+
+const (
+	UsersCreate      Permission = "users:create"
+	UsersDelete      Permission = "users:delete"
+	UsersManageRoles Permission = "users:manage_roles"
+	UsersUnlock      Permission = "users:unlock"
+	// UsersViewPrivate reveals other users' email and account details
+	UsersViewPrivate Permission = "users:view_private"
+	// UsersManage edits other accounts, suspends them and signs them out
+	UsersManage Permission = "users:manage"
+	// AuditRead lists the audit log
+	AuditRead Permission = "audit:read"
+
+	PostsCreate   Permission = "posts:create"
+	PostsUpdate   Permission = "posts:update"
+	PostsDelete   Permission = "posts:delete"
+	PostsModerate Permission = "posts:moderate"
+
+	CommentsCreate   Permission = "comments:create"
+	CommentsUpdate   Permission = "comments:update"
+	CommentsDelete   Permission = "comments:delete"
+	CommentsModerate Permission = "comments:moderate"
+)
+
+// Roles stored in users.role
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser: {
+		PostsCreate,
+		PostsUpdate,
+		PostsDelete,
+		CommentsCreate,
+		CommentsUpdate,
+		CommentsDelete,
+	},
+	RoleModerator: {
+		PostsCreate,
+		PostsUpdate,
+		PostsDelete,
+		PostsModerate,
+		CommentsCreate,
+		CommentsUpdate,
+		CommentsDelete,
+		CommentsModerate,
+	},
+	RoleAdmin: {
+		UsersCreate,
+		UsersDelete,
+		UsersManageRoles,
+		UsersUnlock,
+		UsersViewPrivate,
+		UsersManage,
+		AuditRead,
+		PostsCreate,
+		PostsUpdate,
+		PostsDelete,
+		PostsModerate,
+		CommentsCreate,
+		CommentsUpdate,
+		CommentsDelete,
+		CommentsModerate,
+	},
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// Has reports whether role grants the permission
+func Has(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// For returns the permissions granted to role
+func For(role string) []Permission {
+	return append([]Permission(nil), rolePermissions[role]...)
+}
@@ -0,0 +1,48 @@
+package permissions
+
+import "strings"
+
+// API key scopes, written as "resource:read" or "resource:write". A write scope
+// includes the matching read scope.
+const (
+	ScopePostsRead     = "posts:read"
+	ScopePostsWrite    = "posts:write"
+	ScopeCommentsRead  = "comments:read"
+	ScopeCommentsWrite = "comments:write"
+	ScopeUsersRead     = "users:read"
+	ScopeUsersWrite    = "users:write"
+)
+
+var scopes = map[string]bool{
+	ScopePostsRead:     true,
+	ScopePostsWrite:    true,
+	ScopeCommentsRead:  true,
+	ScopeCommentsWrite: true,
+	ScopeUsersRead:     true,
+	ScopeUsersWrite:    true,
+}
+
+// IsValidScope reports whether scope is one of the known API key scopes
+func IsValidScope(scope string) bool {
+	return scopes[scope]
+}
+
+// ScopeFor returns the API key scope needed to use perm, for a reading or a writing request
+func ScopeFor(perm Permission, write bool) string {
+	resource, _, _ := strings.Cut(string(perm), ":")
+	if write {
+		return resource + ":write"
+	}
+	return resource + ":read"
+}
+
+// HasScope reports whether granted covers the scope need
+func HasScope(granted []string, need string) bool {
+	resource, action, _ := strings.Cut(need, ":")
+	for _, s := range granted {
+		if s == need || (action == "read" && s == resource+":write") {
+			return true
+		}
+	}
+	return false
+}
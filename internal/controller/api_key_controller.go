@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	apiKeyService service.APIKeyService
+}
+
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService}
+}
+
+// POST /api/v1/me/api-keys
+// Key đầy đủ chỉ trả về một lần ở đây
+func (kc *APIKeyController) CreateAPIKeyHandler(c *gin.Context) {
+	var req struct {
+		Name      string     `json:"name" binding:"required,max=100"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	key, err := kc.apiKeyService.Create(c.Request.Context(), actor.UserID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		respondError(c, err, "failed to create api key")
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+// GET /api/v1/me/api-keys
+func (kc *APIKeyController) ListAPIKeysHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	keys, err := kc.apiKeyService.List(c.Request.Context(), actor.UserID)
+	if err != nil {
+		respondError(c, err, "failed to fetch api keys")
+		return
+	}
+
+	if keys == nil {
+		keys = []sqlc.ApiKey{}
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// DELETE /api/v1/me/api-keys/:id
+func (kc *APIKeyController) DeleteAPIKeyHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.NewBadRequestError("invalid api key id"))
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	if err := kc.apiKeyService.Delete(c.Request.Context(), actor.UserID, int32(id)); err != nil {
+		respondError(c, err, "failed to delete api key")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
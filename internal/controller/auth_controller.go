@@ -1,73 +1,447 @@
 package controller
 
 import (
+	"log"
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/controller/response"
+	"my_project/internal/database/sqlc"
 	"my_project/internal/service"
+	"my_project/utils"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthController struct {
-	userService service.UserService
+	userService              service.UserService
+	sessionService           service.SessionService
+	passwordResetService     service.PasswordResetService
+	emailVerificationService service.EmailVerificationService
+	mfaService               service.MFAService
+	oidcService              service.OIDCService
+	audit                    service.AuditLogger
 }
 
-func NewAuthController(userService service.UserService) *AuthController {
-	return &AuthController{userService}
+func NewAuthController(userService service.UserService, sessionService service.SessionService, passwordResetService service.PasswordResetService, emailVerificationService service.EmailVerificationService, mfaService service.MFAService, oidcService service.OIDCService, audit service.AuditLogger) *AuthController {
+	return &AuthController{userService, sessionService, passwordResetService, emailVerificationService, mfaService, oidcService, audit}
 }
 
 // POST /api/v1/auth/register
 func (ac *AuthController) RegisterHandler(c *gin.Context) {
 	var req struct {
-		Username string `json:"username" binding:"required"`
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required,min=6"`
+		Username   string `json:"username" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required,min=6"`
+		DeviceName string `json:"device_name" binding:"max=100"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	user, err := ac.userService.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to register")
 		return
 	}
 
+	event := auditEvent(c, service.AuditRegister)
+	event.ActorID = user.ID
+	event.TargetType, event.TargetID = service.AuditTargetUser, user.ID
+	event.After = response.NewAdminUser(user)
+	ac.audit.Record(c.Request.Context(), event)
+
+	// Gửi mail lỗi không làm hỏng đăng ký: người dùng có thể yêu cầu gửi lại
+	if err := ac.emailVerificationService.SendVerification(c.Request.Context(), user); err != nil {
+		log.Printf("failed to start email verification for user %d: %v", user.ID, err)
+	}
+
 	// Auto login sau khi đăng ký
-	token, _, err := ac.userService.Login(c.Request.Context(), req.Email, req.Password)
+	tokens, err := ac.sessionService.Issue(c.Request.Context(), user, sessionMetaFromRequest(c, req.DeviceName))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
+		respondError(c, err, "failed to create token")
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"token":      token,
-		"user":       user,
-		"expires_in": int((time.Hour * 1).Seconds()), // 3600s
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"user":          response.NewSelfUser(user),
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
 // POST /api/v1/auth/login
 func (ac *AuthController) LoginHandler(c *gin.Context) {
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		Password   string `json:"password" binding:"required"`
+		DeviceName string `json:"device_name" binding:"max=100"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := ac.userService.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP())
+	if err != nil {
+		ac.auditLoginFailed(c, "password", req.Email, err)
+		respondError(c, err, "failed to login")
+		return
+	}
+
+	ac.completeLogin(c, user, req.DeviceName, "password")
+}
+
+// completeLogin issues a session for a user whose first factor (method) checked out
+func (ac *AuthController) completeLogin(c *gin.Context, user sqlc.User, deviceName, method string) {
+	// Tài khoản bật 2FA: chưa cấp session, client đổi mfa_token + mã TOTP ở /auth/2fa/verify
+	if user.TotpEnabledAt.Valid {
+		mfaToken, err := ac.mfaService.Challenge(c.Request.Context(), user)
+		if err != nil {
+			respondError(c, err, "failed to create token")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(utils.MFATokenTTL.Seconds()),
+		})
+		return
+	}
+
+	tokens, err := ac.sessionService.Issue(c.Request.Context(), user, sessionMetaFromRequest(c, deviceName))
+	if err != nil {
+		respondError(c, err, "failed to create token")
+		return
+	}
+	ac.auditLogin(c, user, method)
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"user":          response.NewSelfUser(user),
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// POST /api/v1/auth/refresh
+func (ac *AuthController) RefreshHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+		DeviceName   string `json:"device_name" binding:"max=100"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	token, user, err := ac.userService.Login(c.Request.Context(), req.Email, req.Password)
+	tokens, user, err := ac.sessionService.Refresh(c.Request.Context(), req.RefreshToken, sessionMetaFromRequest(c, req.DeviceName))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to refresh token")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":      token,
-		"user":       user,
-		"expires_in": int((time.Hour * 1).Seconds()), // 3600s
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"user":          response.NewSelfUser(user),
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
+
+// POST /api/v1/auth/logout
+func (ac *AuthController) LogoutHandler(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID, err := ac.sessionService.Logout(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, err, "failed to logout")
+		return
+	}
+
+	event := auditEvent(c, service.AuditLogout)
+	event.ActorID = userID
+	event.TargetType, event.TargetID = service.AuditTargetUser, userID
+	ac.audit.Record(c.Request.Context(), event)
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// POST /api/v1/auth/password/forgot
+// Luôn trả 202 để không lộ email nào đã đăng ký
+func (ac *AuthController) ForgotPasswordHandler(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.passwordResetService.RequestReset(c.Request.Context(), req.Email); err != nil {
+		respondError(c, err, "failed to request password reset")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email is registered, a reset link has been sent"})
+}
+
+// POST /api/v1/auth/password/reset
+func (ac *AuthController) ResetPasswordHandler(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.passwordResetService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondError(c, err, "failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password updated"})
+}
+
+// GET /api/v1/auth/verify?token=...
+func (ac *AuthController) VerifyEmailHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		abortWithError(c, apperrors.NewBadRequestError("token is required"))
+		return
+	}
+
+	user, err := ac.emailVerificationService.Verify(c.Request.Context(), token)
+	if err != nil {
+		respondError(c, err, "failed to verify email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "email verified", "user": response.NewSelfUser(user)})
+}
+
+// POST /api/v1/auth/verify/resend
+func (ac *AuthController) ResendVerificationHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	if err := ac.emailVerificationService.Resend(c.Request.Context(), actor.UserID); err != nil {
+		respondError(c, err, "failed to resend verification email")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "verification email sent"})
+}
+
+// POST /api/v1/auth/2fa/verify
+func (ac *AuthController) VerifyMFAHandler(c *gin.Context) {
+	var req struct {
+		MFAToken   string `json:"mfa_token" binding:"required"`
+		Code       string `json:"code" binding:"required"`
+		DeviceName string `json:"device_name" binding:"max=100"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := ac.mfaService.CompleteLogin(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		ac.auditLoginFailed(c, "totp", "", err)
+		respondError(c, err, "failed to verify code")
+		return
+	}
+
+	tokens, err := ac.sessionService.Issue(c.Request.Context(), user, sessionMetaFromRequest(c, req.DeviceName))
+	if err != nil {
+		respondError(c, err, "failed to create token")
+		return
+	}
+	ac.auditLogin(c, user, "totp")
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"user":          response.NewSelfUser(user),
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// POST /api/v1/auth/2fa/setup
+func (ac *AuthController) SetupTOTPHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	setup, err := ac.mfaService.Setup(c.Request.Context(), actor.UserID)
+	if err != nil {
+		respondError(c, err, "failed to set up two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, setup)
+}
+
+// POST /api/v1/auth/2fa/enable
+// Mã khôi phục chỉ trả về đúng một lần ở đây
+func (ac *AuthController) EnableTOTPHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	codes, err := ac.mfaService.Enable(c.Request.Context(), actor.UserID, req.Code)
+	if err != nil {
+		respondError(c, err, "failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// POST /api/v1/auth/2fa/disable
+func (ac *AuthController) DisableTOTPHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := ac.mfaService.Disable(c.Request.Context(), actor.UserID, req.Code); err != nil {
+		respondError(c, err, "failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// GET /api/v1/auth/oidc
+func (ac *AuthController) ListOIDCProvidersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": ac.oidcService.Providers()})
+}
+
+// GET /.well-known/jwks.json
+// Public keys other services use to verify our access tokens; HS256 keys are never listed
+func (ac *AuthController) JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.JWKS())
+}
+
+// oidcStateCookie binds a pending external sign-in to the browser that started it
+const oidcStateCookie = "oidc_state"
+
+// GET /api/v1/auth/oidc/:provider/start
+func (ac *AuthController) StartOIDCHandler(c *gin.Context) {
+	authURL, state, err := ac.oidcService.Start(c.Request.Context(), c.Param("provider"), 0)
+	if err != nil {
+		respondError(c, err, "failed to start sign-in")
+		return
+	}
+	setOIDCStateCookie(c, state, int(service.OIDCAuthRequestTTL.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// POST /api/v1/auth/oidc/:provider/link
+// Liên kết provider với tài khoản đang đăng nhập; client chuyển trình duyệt tới auth_url
+func (ac *AuthController) LinkOIDCHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	authURL, state, err := ac.oidcService.Start(c.Request.Context(), c.Param("provider"), actor.UserID)
+	if err != nil {
+		respondError(c, err, "failed to start linking")
+		return
+	}
+	setOIDCStateCookie(c, state, int(service.OIDCAuthRequestTTL.Seconds()))
+	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+}
+
+// setOIDCStateCookie stores (or, with maxAge -1, clears) the state cookie. SameSite=Lax
+// lets it ride along on the provider's top-level redirect back to the callback.
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, maxAge, "/api/v1/auth/oidc", "", secure, true)
+}
+
+// GET /api/v1/auth/oidc/:provider/callback?state=...&code=...
+func (ac *AuthController) OIDCCallbackHandler(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		abortWithError(c, apperrors.NewUnauthorizedError("sign-in was not completed at the identity provider").
+			WithDetails(gin.H{"error": providerErr, "error_description": c.Query("error_description")}))
+		return
+	}
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		abortWithError(c, apperrors.NewBadRequestError("state and code are required"))
+		return
+	}
+
+	browserState, _ := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+
+	user, err := ac.oidcService.Callback(c.Request.Context(), c.Param("provider"), state, browserState, code)
+	if err != nil {
+		ac.auditLoginFailed(c, "oidc:"+c.Param("provider"), "", err)
+		respondError(c, err, "failed to sign in")
+		return
+	}
+
+	ac.completeLogin(c, user, c.Param("provider"), "oidc:"+c.Param("provider"))
+}
+
+// auditLogin records a session issued to user after signing in with method
+func (ac *AuthController) auditLogin(c *gin.Context, user sqlc.User, method string) {
+	event := auditEvent(c, service.AuditLogin)
+	event.ActorID = user.ID
+	event.TargetType, event.TargetID = service.AuditTargetUser, user.ID
+	event.Metadata["method"] = method
+	ac.audit.Record(c.Request.Context(), event)
+}
+
+// auditLoginFailed records a rejected sign-in; email is the address tried, if any.
+// The password is never recorded.
+func (ac *AuthController) auditLoginFailed(c *gin.Context, method, email string, err error) {
+	event := auditEvent(c, service.AuditLoginFailed)
+	event.Metadata["method"] = method
+	event.Metadata["error"] = auditErrorCode(err)
+	if email != "" {
+		event.Metadata["email"] = email
+	}
+	ac.audit.Record(c.Request.Context(), event)
+}
+
+func sessionMetaFromRequest(c *gin.Context, deviceName string) service.SessionMeta {
+	return service.SessionMeta{
+		DeviceName: deviceName,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+	}
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentController struct {
+	service service.CommentService
+}
+
+func NewCommentController(s service.CommentService) *CommentController {
+	return &CommentController{service: s}
+}
+
+// GET /api/v1/posts/:id/comments?limit=20&cursor=...[&view=flat]
+// Trả về cây bình luận (phân trang theo bình luận gốc); view=flat trả danh sách theo thời gian
+func (cc *CommentController) ListCommentsHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || postID <= 0 {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	page, ok := parsePageRequest(c, defaultPageSize)
+	if !ok {
+		return
+	}
+	var at offsetCursor
+	if !page.decodeCursor(c, cursorScopeComments, &at) {
+		return
+	}
+	next := offsetCursor{Offset: at.Offset + page.Limit}
+
+	if c.Query("view") != "flat" {
+		tree, err := cc.service.ListCommentTree(c.Request.Context(), int32(postID), page.fetchLimit(), at.Offset)
+		if err != nil {
+			respondError(c, err, "failed to fetch comments")
+			return
+		}
+		respondPage(c, "comments", cursorScopeComments, page, tree, func(*service.CommentNode) any { return next })
+		return
+	}
+
+	comments, err := cc.service.ListComments(c.Request.Context(), int32(postID), page.fetchLimit(), at.Offset)
+	if err != nil {
+		respondError(c, err, "failed to fetch comments")
+		return
+	}
+	respondPage(c, "comments", cursorScopeComments, page, comments, func(sqlc.ListCommentsByPostRow) any { return next })
+}
+
+// POST /api/v1/posts/:id/comments
+func (cc *CommentController) CreateCommentHandler(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || postID <= 0 {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	var req struct {
+		Content  string `json:"content" binding:"required"`
+		ParentID *int32 `json:"parent_id"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	params := sqlc.CreateCommentParams{
+		PostID:  int32(postID),
+		UserID:  actor.UserID,
+		Content: req.Content,
+	}
+	if req.ParentID != nil {
+		params.ParentID = sql.NullInt32{Int32: *req.ParentID, Valid: true}
+	}
+
+	comment, err := cc.service.CreateComment(c.Request.Context(), params)
+	if err != nil {
+		respondError(c, err, "failed to create comment")
+		return
+	}
+	c.JSON(http.StatusCreated, comment)
+}
+
+// PUT /api/v1/comments/:id
+func (cc *CommentController) UpdateCommentHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid comment id"))
+		return
+	}
+
+	var req struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	comment, err := cc.service.UpdateComment(c.Request.Context(), actor, sqlc.UpdateCommentParams{
+		ID:      int32(id),
+		Content: req.Content,
+	})
+	if err != nil {
+		respondError(c, err, "failed to update comment")
+		return
+	}
+	c.JSON(http.StatusOK, comment)
+}
+
+// DELETE /api/v1/comments/:id
+func (cc *CommentController) DeleteCommentHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid comment id"))
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	if err := cc.service.DeleteComment(c.Request.Context(), actor, int32(id)); err != nil {
+		respondError(c, err, "failed to delete comment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted"})
+}
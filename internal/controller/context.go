@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"my_project/internal/app/permissions"
+	"my_project/internal/controller/response"
+	"my_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// currentActor builds the service actor from the values set by AuthMiddleware
+func currentActor(c *gin.Context) (service.Actor, error) {
+	userID, err := castToInt32(c.MustGet("userID"))
+	if err != nil {
+		return service.Actor{}, err
+	}
+	return service.Actor{UserID: userID, Role: c.GetString("role")}, nil
+}
+
+// optionalActor returns the actor for requests that passed OptionalAuthMiddleware
+// with a valid token, or nil for anonymous requests
+func optionalActor(c *gin.Context) *service.Actor {
+	if _, ok := c.Get("userID"); !ok {
+		return nil
+	}
+	actor, err := currentActor(c)
+	if err != nil {
+		return nil
+	}
+	return &actor
+}
+
+// userView decides how much of user userID the caller may see. API keys need the
+// users:read scope for anything beyond the public profile.
+func userView(c *gin.Context, userID int32) response.View {
+	actor := optionalActor(c)
+	if actor == nil {
+		return response.ViewPublic
+	}
+	if scopes, isAPIKey := c.Get("apiKeyScopes"); isAPIKey && !permissions.HasScope(scopes.([]string), permissions.ScopeUsersRead) {
+		return response.ViewPublic
+	}
+
+	switch {
+	case actor.Can(permissions.UsersViewPrivate):
+		return response.ViewAdmin
+	case actor.UserID == userID:
+		return response.ViewSelf
+	default:
+		return response.ViewPublic
+	}
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"net/http"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/controller/response"
+	"my_project/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeController lets signed-in users manage their own account
+type MeController struct {
+	userService              service.UserService
+	sessionService           service.SessionService
+	emailVerificationService service.EmailVerificationService
+}
+
+func NewMeController(userService service.UserService, sessionService service.SessionService, emailVerificationService service.EmailVerificationService) *MeController {
+	return &MeController{
+		userService:              userService,
+		sessionService:           sessionService,
+		emailVerificationService: emailVerificationService,
+	}
+}
+
+// GET /api/v1/me
+func (mc *MeController) GetMeHandler(c *gin.Context) {
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	user, err := mc.userService.GetUser(c.Request.Context(), int64(actor.UserID))
+	if err != nil {
+		respondError(c, err, "failed to fetch account")
+		return
+	}
+	c.JSON(http.StatusOK, response.NewSelfUser(user))
+}
+
+// PATCH /api/v1/me
+// Trường không gửi lên được giữ nguyên; chuỗi rỗng xoá display_name, bio, avatar_url
+func (mc *MeController) UpdateMeHandler(c *gin.Context) {
+	var req struct {
+		Username    *string `json:"username" binding:"omitempty,max=50"`
+		DisplayName *string `json:"display_name" binding:"omitempty,max=100"`
+		Bio         *string `json:"bio" binding:"omitempty,max=500"`
+		AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=2048"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	user, err := mc.userService.UpdateProfile(c.Request.Context(), actor.UserID, service.ProfileUpdate{
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Bio:         req.Bio,
+		AvatarURL:   req.AvatarURL,
+	})
+	if err != nil {
+		respondError(c, err, "failed to update profile")
+		return
+	}
+	c.JSON(http.StatusOK, response.NewSelfUser(user))
+}
+
+// POST /api/v1/me/password
+// Mọi session bị thu hồi; thiết bị hiện tại nhận cặp token mới
+func (mc *MeController) ChangePasswordHandler(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=6"`
+		DeviceName      string `json:"device_name" binding:"max=100"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	user, err := mc.userService.ChangePassword(c.Request.Context(), actor.UserID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		respondError(c, err, "failed to change password")
+		return
+	}
+
+	tokens, err := mc.sessionService.Issue(c.Request.Context(), user, sessionMetaFromRequest(c, req.DeviceName))
+	if err != nil {
+		respondError(c, err, "failed to create token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"user":          response.NewSelfUser(user),
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// POST /api/v1/me/email
+// Email chỉ đổi sau khi người dùng mở link xác nhận gửi tới địa chỉ mới
+func (mc *MeController) ChangeEmailHandler(c *gin.Context) {
+	var req struct {
+		Email           string `json:"email" binding:"required,email,max=100"`
+		CurrentPassword string `json:"current_password" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	user, err := mc.userService.CheckEmailChange(c.Request.Context(), actor.UserID, req.CurrentPassword, req.Email)
+	if err != nil {
+		respondError(c, err, "failed to change email")
+		return
+	}
+	if err := mc.emailVerificationService.RequestEmailChange(c.Request.Context(), user, req.Email); err != nil {
+		respondError(c, err, "failed to change email")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "a confirmation link has been sent to the new address"})
+}
+
+// DELETE /api/v1/me
+func (mc *MeController) DeleteMeHandler(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	if err := mc.userService.DeleteAccount(c.Request.Context(), actor.UserID, req.CurrentPassword); err != nil {
+		respondError(c, err, "failed to delete account")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
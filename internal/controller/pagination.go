@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Page size bounds shared by every list endpoint
+const (
+	defaultPageSize = 20
+	minPageSize     = 1
+	maxPageSize     = 100
+)
+
+// Cursor scopes; a cursor issued by one listing is rejected by the others
+const (
+	cursorScopePosts      = "posts"
+	cursorScopeUserPosts  = "user-posts"
+	cursorScopeUsers      = "users"
+	cursorScopeAdminUsers = "admin-users"
+	cursorScopeComments   = "comments"
+	cursorScopeSearch     = "search"
+	cursorScopeAudit      = "audit"
+)
+
+// offsetCursor is used where there is no stable keyset (ranked search, comment threads)
+type offsetCursor struct {
+	Offset int32 `json:"o"`
+}
+
+// idCursor pages rows ordered by ascending id
+type idCursor struct {
+	ID int32 `json:"id"`
+}
+
+// pageRequest is the parsed ?limit=&cursor= pair
+type pageRequest struct {
+	Limit  int32
+	cursor string
+}
+
+// parsePageRequest reads limit and cursor from the query string; limit is clamped to
+// [minPageSize, maxPageSize]. On failure it writes a 400 response and returns false.
+func parsePageRequest(c *gin.Context, defaultLimit int32) (pageRequest, bool) {
+	// Old clients would otherwise get the first page back for every page number
+	if _, ok := c.GetQuery("page"); ok {
+		abortWithError(c, apperrors.NewBadRequestError("page is not supported, pass the previous next_cursor as cursor"))
+		return pageRequest{}, false
+	}
+	page := pageRequest{Limit: defaultLimit, cursor: c.Query("cursor")}
+
+	if l := c.Query("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil {
+			abortWithError(c, apperrors.NewBadRequestError("invalid limit"))
+			return pageRequest{}, false
+		}
+		page.Limit = int32(min(max(v, minPageSize), maxPageSize))
+	}
+	return page, true
+}
+
+// fetchLimit asks for one extra row so has_more can be answered without a COUNT
+func (p pageRequest) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+// decodeCursor fills position from the request cursor and leaves it untouched when no
+// cursor was sent (decode into a pointer-to-pointer to tell the cases apart). On an
+// invalid cursor it writes a 400 response and returns false.
+func (p pageRequest) decodeCursor(c *gin.Context, scope string, position any) bool {
+	if p.cursor == "" {
+		return true
+	}
+	if err := utils.DecodeCursor(scope, p.cursor, position); err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid cursor"))
+		return false
+	}
+	return true
+}
+
+// respondPage trims the look-ahead row and writes {key: items, next_cursor, has_more}.
+// positionOf builds the cursor payload from the last item of the page.
+func respondPage[T any](c *gin.Context, key, scope string, page pageRequest, items []T, positionOf func(T) any) {
+	hasMore := len(items) > int(page.Limit)
+	if hasMore {
+		items = items[:page.Limit]
+	}
+	if items == nil {
+		items = []T{}
+	}
+
+	var nextCursor *string
+	if hasMore {
+		cursor, err := utils.EncodeCursor(scope, positionOf(items[len(items)-1]))
+		if err != nil {
+			respondError(c, err, "failed to build cursor")
+			return
+		}
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		key:           items,
+		"next_cursor": nextCursor,
+		"has_more":    hasMore,
+	})
+}
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	apperrors "my_project/internal/app/errors"
 	"my_project/internal/database/sqlc"
 	"my_project/internal/service"
 
@@ -13,90 +17,86 @@ import (
 
 type PostController struct {
 	service service.PostService
+	audit   service.AuditLogger
 }
 
-func NewPostController(s service.PostService) *PostController {
-	return &PostController{service: s}
+func NewPostController(s service.PostService, audit service.AuditLogger) *PostController {
+	return &PostController{service: s, audit: audit}
 }
 
-// GET /api/v1/posts?page=1&limit=10
+// GET /api/v1/posts?limit=10&cursor=...&tag=go&tag=postgres&match=all
 func (pc *PostController) ListPostsHandler(c *gin.Context) {
-	limit := int32(5)
-	offset := int32(0)
-
-	if l := c.Query("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil {
-			limit = int32(v)
-		}
+	page, ok := parsePageRequest(c, 5)
+	if !ok {
+		return
 	}
-	if p := c.Query("page"); p != "" {
-		if v, err := strconv.Atoi(p); err == nil && v > 0 {
-			offset = int32((v - 1) * int(limit))
-		}
+	var after *service.PostKey
+	if !page.decodeCursor(c, cursorScopePosts, &after) {
+		return
 	}
 
-	posts, err := pc.service.ListPosts(c.Request.Context(), limit, offset)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch posts"})
+	filter := service.PostFilter{Tags: c.QueryArray("tag")}
+	switch c.DefaultQuery("match", "any") {
+	case "any":
+	case "all":
+		filter.MatchAll = true
+	default:
+		abortWithError(c, apperrors.NewBadRequestError("match must be any or all"))
 		return
 	}
 
-	// Ensure the response is always an array instead of null
-	if posts == nil {
-		posts = []sqlc.ListPostsRow{}
+	posts, err := pc.service.ListPosts(c.Request.Context(), filter, after, page.fetchLimit())
+	if err != nil {
+		respondError(c, err, "failed to fetch posts")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	respondPage(c, "posts", cursorScopePosts, page, posts, func(p sqlc.ListPostsRow) any {
+		return service.PostKey{CreatedAt: p.CreatedAt.Time, ID: p.ID}
+	})
 }
 
-// GET /api/v1/posts/user/:userID?page=1&limit=10
+// GET /api/v1/posts/user/:userID?limit=10&cursor=...
 func (pc *PostController) ListPostsByUserHandler(c *gin.Context) {
 	userIDStr := c.Param("userID")
 	userID, err := strconv.ParseInt(userIDStr, 10, 32)
 	if err != nil || userID <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		abortWithError(c, apperrors.NewBadRequestError("invalid user id"))
 		return
 	}
 
-	limit := int32(5)
-	offset := int32(0)
-
-	if l := c.Query("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil {
-			limit = int32(v)
-		}
+	page, ok := parsePageRequest(c, 5)
+	if !ok {
+		return
 	}
-	if p := c.Query("page"); p != "" {
-		if v, err := strconv.Atoi(p); err == nil && v > 0 {
-			offset = int32((v - 1) * int(limit))
-		}
+	var after *service.PostKey
+	if !page.decodeCursor(c, cursorScopeUserPosts, &after) {
+		return
 	}
 
-	posts, err := pc.service.ListPostsByUser(c.Request.Context(), int32(userID), limit, offset)
+	posts, err := pc.service.ListPostsByUser(c.Request.Context(), optionalActor(c), int32(userID), after, page.fetchLimit())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch user posts"})
+		respondError(c, err, "failed to fetch user posts")
 		return
 	}
 
-	if posts == nil {
-		posts = []sqlc.ListPostsByUserRow{}
-	}
-
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	respondPage(c, "posts", cursorScopeUserPosts, page, posts, func(p sqlc.ListPostsByUserRow) any {
+		return service.PostKey{CreatedAt: p.CreatedAt.Time, ID: p.ID}
+	})
 }
 
 // GET /api/v1/posts/:id
 func (pc *PostController) GetPostHandler(c *gin.Context) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
 		return
 	}
 
-	post, err := pc.service.GetPost(c.Request.Context(), int32(id))
+	post, err := pc.service.GetPost(c.Request.Context(), optionalActor(c), int32(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		respondError(c, err, "failed to fetch post")
 		return
 	}
 	c.JSON(http.StatusOK, post)
@@ -105,25 +105,26 @@ func (pc *PostController) GetPostHandler(c *gin.Context) {
 // POST /api/v1/posts
 func (pc *PostController) CreatePostHandler(c *gin.Context) {
 	var req struct {
-		Title   string `json:"title" binding:"required"`
-		Content string `json:"content" binding:"required"`
-		Status  string `json:"status"`
+		Title     string     `json:"title" binding:"required"`
+		Content   string     `json:"content" binding:"required"`
+		Status    string     `json:"status" binding:"omitempty,oneof=draft published"`
+		PublishAt *time.Time `json:"publish_at"`
+		Tags      []string   `json:"tags"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	userIDVal, ok := c.Get("userID")
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		abortWithError(c, apperrors.NewUnauthorizedError("unauthorized"))
 		return
 	}
 
 	userID, err := castToInt32(userIDVal)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user context"})
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
 		return
 	}
 
@@ -131,11 +132,19 @@ func (pc *PostController) CreatePostHandler(c *gin.Context) {
 		UserID:  userID,
 		Title:   req.Title,
 		Content: req.Content,
+		Status:  req.Status,
+	}
+	if req.PublishAt != nil {
+		// Bài hẹn giờ luôn bắt đầu ở trạng thái draft
+		if createParams.Status == "" {
+			createParams.Status = service.PostStatusDraft
+		}
+		createParams.PublishAt = sql.NullTime{Time: *req.PublishAt, Valid: true}
 	}
 
-	post, err := pc.service.CreatePost(c.Request.Context(), createParams)
+	post, err := pc.service.CreatePost(c.Request.Context(), createParams, req.Tags)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create post"})
+		respondError(c, err, "failed to create post")
 		return
 	}
 	c.JSON(http.StatusCreated, post)
@@ -146,20 +155,90 @@ func (pc *PostController) UpdatePostHandler(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	var req struct {
+		Title     string     `json:"title" binding:"required"`
+		Content   string     `json:"content" binding:"required"`
+		PublishAt *time.Time `json:"publish_at"`
+		Tags      []string   `json:"tags"` // omitted keeps the current tags, [] clears them
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	updateParams := sqlc.UpdatePostParams{
+		ID:      int32(id),
+		Title:   req.Title,
+		Content: req.Content,
+	}
+	if req.PublishAt != nil {
+		updateParams.PublishAt = sql.NullTime{Time: *req.PublishAt, Valid: true}
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	post, err := pc.service.UpdatePost(c.Request.Context(), actor, updateParams, req.Tags, auditEvent(c, service.AuditPostUpdate))
+	if err != nil {
+		respondError(c, err, "failed to update post")
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// GET /api/v1/tags?limit=50
+// Tag cloud trả về top N tag nên chỉ dùng limit, không có cursor
+func (pc *PostController) ListTagsHandler(c *gin.Context) {
+	page, ok := parsePageRequest(c, 50)
+	if !ok {
+		return
+	}
+
+	tags, err := pc.service.ListTags(c.Request.Context(), page.Limit)
+	if err != nil {
+		respondError(c, err, "failed to fetch tags")
 		return
 	}
 
-	var req sqlc.UpdatePostParams
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	if tags == nil {
+		tags = []sqlc.ListTagsWithCountsRow{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// POST /api/v1/posts/:id/publish
+func (pc *PostController) PublishPostHandler(c *gin.Context) {
+	pc.transitionPost(c, pc.service.PublishPost)
+}
+
+// POST /api/v1/posts/:id/archive
+func (pc *PostController) ArchivePostHandler(c *gin.Context) {
+	pc.transitionPost(c, pc.service.ArchivePost)
+}
+
+func (pc *PostController) transitionPost(c *gin.Context, transition func(context.Context, service.Actor, int32) (sqlc.Post, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
 		return
 	}
-	req.ID = int32(id)
 
-	post, err := pc.service.UpdatePost(c.Request.Context(), req)
+	actor, err := currentActor(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	post, err := transition(c.Request.Context(), actor, int32(id))
+	if err != nil {
+		respondError(c, err, "failed to change post status")
 		return
 	}
 	c.JSON(http.StatusOK, post)
@@ -168,19 +247,51 @@ func (pc *PostController) UpdatePostHandler(c *gin.Context) {
 // DELETE /api/v1/posts/:id
 func (pc *PostController) DeletePostHandler(c *gin.Context) {
 	idStr := c.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	actor, err := currentActor(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
 		return
 	}
 
-	if err := pc.service.DeletePost(c.Request.Context(), int32(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete post"})
+	if err := pc.service.DeletePost(c.Request.Context(), actor, int32(id), auditEvent(c, service.AuditPostDelete)); err != nil {
+		respondError(c, err, "failed to delete post")
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "post deleted"})
 }
 
+// POST /api/v1/admin/posts/:id/restore  (khôi phục bài đã xoá, trước khi bị purge)
+func (pc *PostController) RestorePostHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	post, err := pc.service.RestorePost(c.Request.Context(), int32(id))
+	if err != nil {
+		respondError(c, err, "failed to restore post")
+		return
+	}
+	pc.record(c, service.AuditPostRestore, post.ID, nil, post)
+	c.JSON(http.StatusOK, post)
+}
+
+// record adds an admin action on post id to the audit log; moderator edits and
+// deletes are recorded by PostService
+func (pc *PostController) record(c *gin.Context, action string, id int32, before, after any) {
+	event := auditEvent(c, action)
+	event.TargetType, event.TargetID = service.AuditTargetPost, id
+	event.Before, event.After = before, after
+	pc.audit.Record(c.Request.Context(), event)
+}
+
 func castToInt32(value interface{}) (int32, error) {
 	switch v := value.(type) {
 	case int32:
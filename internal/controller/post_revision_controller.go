@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	apperrors "my_project/internal/app/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /api/v1/posts/:id/revisions
+func (pc *PostController) ListRevisionsHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	revisions, err := pc.service.ListRevisions(c.Request.Context(), actor, int32(id))
+	if err != nil {
+		respondError(c, err, "failed to list revisions")
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GET /api/v1/posts/:id/revisions/:rev/diff?against=
+func (pc *PostController) RevisionDiffHandler(c *gin.Context) {
+	id, rev, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	var against int64
+	if s := c.Query("against"); s != "" {
+		var err error
+		against, err = strconv.ParseInt(s, 10, 32)
+		if err != nil || against < 1 {
+			abortWithError(c, apperrors.NewBadRequestError("invalid against revision"))
+			return
+		}
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	diff, err := pc.service.DiffRevisions(c.Request.Context(), actor, id, rev, int32(against))
+	if err != nil {
+		respondError(c, err, "failed to diff revisions")
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// POST /api/v1/posts/:id/revisions/:rev/restore
+func (pc *PostController) RestoreRevisionHandler(c *gin.Context) {
+	id, rev, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	post, err := pc.service.RestoreRevision(c.Request.Context(), actor, id, rev)
+	if err != nil {
+		respondError(c, err, "failed to restore revision")
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+func revisionParams(c *gin.Context) (int32, int32, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid post id"))
+		return 0, 0, false
+	}
+	rev, err := strconv.ParseInt(c.Param("rev"), 10, 32)
+	if err != nil || rev < 1 {
+		abortWithError(c, apperrors.NewBadRequestError("invalid revision"))
+		return 0, 0, false
+	}
+	return int32(id), int32(rev), true
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"errors"
+
+	apperrors "my_project/internal/app/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError stops the handler chain and records err for
+// middleware.ErrorHandlerMiddleware, which renders the error body.
+func abortWithError(c *gin.Context, err *apperrors.AppError) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// respondError records an AppError as is; any other error becomes a 500 with the
+// fallback message so internal details are not exposed.
+func respondError(c *gin.Context, err error, fallback string) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		appErr = apperrors.NewInternalError(fallback).Wrap(err)
+	}
+	abortWithError(c, appErr)
+}
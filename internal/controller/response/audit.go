@@ -0,0 +1,50 @@
+package response
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"my_project/internal/database/sqlc"
+)
+
+// AuditEvent is an audit log entry as listed to admins
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	Action     string          `json:"action"`
+	ActorID    *int32          `json:"actor_id"`
+	TargetType *string         `json:"target_type"`
+	TargetID   *int32          `json:"target_id"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	RequestID  string          `json:"request_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Metadata   json.RawMessage `json:"metadata"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// NewAuditEvent maps an audit_events row
+func NewAuditEvent(e sqlc.AuditEvent) AuditEvent {
+	return AuditEvent{
+		ID:         e.ID,
+		Action:     e.Action,
+		ActorID:    nullInt32(e.ActorID),
+		TargetType: nullString(e.TargetType),
+		TargetID:   nullInt32(e.TargetID),
+		IPAddress:  e.IpAddress,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Before:     e.BeforeState,
+		After:      e.AfterState,
+		Metadata:   e.Metadata,
+		CreatedAt:  e.CreatedAt,
+	}
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
@@ -0,0 +1,161 @@
+package response
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"my_project/internal/database/sqlc"
+	"my_project/internal/service"
+	"my_project/utils"
+)
+
+// apiResponseTypes lists every type a controller serializes into a response body
+var apiResponseTypes = []any{
+	PublicUser{},
+	SelfUser{},
+	AdminUser{},
+	AuditEvent{},
+	sqlc.ApiKey{},
+	service.CreatedAPIKey{},
+	service.TOTPSetup{},
+	sqlc.Post{},
+	sqlc.GetPostDetailRow{},
+	sqlc.ListPostsRow{},
+	sqlc.ListPostsByUserRow{},
+	sqlc.ListPostRevisionsRow{},
+	sqlc.ListTagsWithCountsRow{},
+	service.RevisionDiff{},
+	sqlc.Comment{},
+	sqlc.ListCommentsByPostRow{},
+	service.CommentNode{},
+	service.SearchResults{},
+	utils.JWKSet{},
+	// Never sent on purpose, but must stay safe if one slips through
+	sqlc.User{},
+	sqlc.Session{},
+}
+
+// sensitiveFieldWords mark JSON fields that carry credentials
+var sensitiveFieldWords = []string{"password", "hash", "secret", "verifier", "nonce", "private"}
+
+// intentionalDisclosures are shown once, to their owner, when they are created
+var intentionalDisclosures = map[string]bool{
+	"TOTPSetup.secret": true,
+}
+
+func TestResponseTypesHaveNoSecretFields(t *testing.T) {
+	for _, v := range apiResponseTypes {
+		typ := reflect.TypeOf(v)
+		for _, field := range jsonFields(typ, map[reflect.Type]bool{}) {
+			if intentionalDisclosures[field] {
+				continue
+			}
+			_, name, _ := strings.Cut(field, ".")
+			for _, word := range sensitiveFieldWords {
+				if strings.Contains(name, word) {
+					t.Errorf("%s serializes sensitive field %s", typ, field)
+				}
+			}
+		}
+	}
+}
+
+func TestUserViewsHideCredentials(t *testing.T) {
+	now := sql.NullTime{Time: time.Now(), Valid: true}
+	user := sqlc.User{
+		ID:              1,
+		Username:        "alice",
+		Email:           "alice@example.com",
+		PasswordHash:    "$2a$10$SENTINELHASH",
+		Role:            "user",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		EmailVerifiedAt: now,
+		TotpSecret:      sql.NullString{String: "SENTINELTOTP", Valid: true},
+		TotpEnabledAt:   now,
+	}
+
+	for _, view := range []View{ViewPublic, ViewSelf, ViewAdmin} {
+		data, err := json.Marshal(NewUser(user, view))
+		if err != nil {
+			t.Fatalf("failed to marshal view %d: %v", view, err)
+		}
+		body := string(data)
+		if strings.Contains(body, "SENTINEL") {
+			t.Errorf("view %d leaks a credential: %s", view, body)
+		}
+		if hasEmail := strings.Contains(body, user.Email); hasEmail != (view != ViewPublic) {
+			t.Errorf("view %d: email visible = %v: %s", view, hasEmail, body)
+		}
+	}
+
+	// The sqlc model itself must not serialize credentials either
+	data, _ := json.Marshal(user)
+	if strings.Contains(string(data), "SENTINEL") {
+		t.Errorf("sqlc.User leaks a credential: %s", data)
+	}
+}
+
+func TestUserViewsFormatTimestamps(t *testing.T) {
+	created := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	user := sqlc.User{
+		ID:        1,
+		Username:  "alice",
+		Email:     "alice@example.com",
+		Role:      "user",
+		CreatedAt: sql.NullTime{Time: created, Valid: true},
+	}
+
+	data, err := json.Marshal(NewAdminUser(user))
+	if err != nil {
+		t.Fatalf("failed to marshal admin view: %v", err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("failed to decode admin view: %v", err)
+	}
+	if body["created_at"] != created.Format(time.RFC3339) {
+		t.Errorf("created_at = %v, want %s", body["created_at"], created.Format(time.RFC3339))
+	}
+	for _, field := range []string{"updated_at", "email_verified_at", "totp_enabled_at", "suspended_at", "suspended_until"} {
+		if v, ok := body[field]; ok {
+			t.Errorf("unset %s should be omitted, got %v", field, v)
+		}
+	}
+}
+
+// jsonFields returns "Type.json_name" for every field encoding/json would emit,
+// following embedded structs, nested structs, pointers and slices
+func jsonFields(typ reflect.Type, seen map[reflect.Type]bool) []string {
+	for typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || seen[typ] || typ.PkgPath() == "database/sql" || typ.PkgPath() == "time" {
+		return nil
+	}
+	seen[typ] = true
+
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			fields = append(fields, jsonFields(f.Type, seen)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, typ.Name()+"."+strings.ToLower(name))
+		fields = append(fields, jsonFields(f.Type, seen)...)
+	}
+	return fields
+}
@@ -0,0 +1,127 @@
+// Package response holds the JSON bodies the API sends. Controllers map database
+// rows into these types instead of serializing sqlc models, so a new column only
+// reaches clients once a view exposes it.
+package response
+
+import (
+	"database/sql"
+	"time"
+
+	"my_project/internal/database/sqlc"
+)
+
+// View is how much of a user account the requester may see
+type View int
+
+const (
+	// ViewPublic is the profile anyone can see
+	ViewPublic View = iota
+	// ViewSelf adds the account details the user sees about themself
+	ViewSelf
+	// ViewAdmin adds the timestamps admins need for support
+	ViewAdmin
+)
+
+// User is one of PublicUser, SelfUser or AdminUser
+type User interface {
+	UserID() int32
+}
+
+// PublicUser is a user's public profile
+type PublicUser struct {
+	ID          int32      `json:"id"`
+	Username    string     `json:"username"`
+	DisplayName *string    `json:"display_name"`
+	Bio         *string    `json:"bio"`
+	AvatarURL   *string    `json:"avatar_url"`
+	Role        string     `json:"role"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// UserID implements User
+func (u PublicUser) UserID() int32 {
+	return u.ID
+}
+
+// SelfUser is the signed-in user's own account
+type SelfUser struct {
+	PublicUser
+	Email            string     `json:"email"`
+	EmailVerified    bool       `json:"email_verified"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// AdminUser is an account as seen by an admin
+type AdminUser struct {
+	SelfUser
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	TotpEnabledAt    *time.Time `json:"totp_enabled_at,omitempty"`
+	Suspended        bool       `json:"suspended"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason *string    `json:"suspension_reason"`
+}
+
+// NewPublicUser maps u to its public profile
+func NewPublicUser(u sqlc.User) PublicUser {
+	return PublicUser{
+		ID:          u.ID,
+		Username:    u.Username,
+		DisplayName: nullString(u.DisplayName),
+		Bio:         nullString(u.Bio),
+		AvatarURL:   nullString(u.AvatarUrl),
+		Role:        u.Role,
+		CreatedAt:   nullTime(u.CreatedAt),
+	}
+}
+
+// NewSelfUser maps u for the user themself
+func NewSelfUser(u sqlc.User) SelfUser {
+	return SelfUser{
+		PublicUser:       NewPublicUser(u),
+		Email:            u.Email,
+		EmailVerified:    u.EmailVerifiedAt.Valid,
+		TwoFactorEnabled: u.TotpEnabledAt.Valid,
+		UpdatedAt:        nullTime(u.UpdatedAt),
+	}
+}
+
+// NewAdminUser maps u for an admin
+func NewAdminUser(u sqlc.User) AdminUser {
+	return AdminUser{
+		SelfUser:         NewSelfUser(u),
+		EmailVerifiedAt:  nullTime(u.EmailVerifiedAt),
+		TotpEnabledAt:    nullTime(u.TotpEnabledAt),
+		Suspended:        u.SuspendedAt.Valid && (!u.SuspendedUntil.Valid || u.SuspendedUntil.Time.After(time.Now())),
+		SuspendedAt:      nullTime(u.SuspendedAt),
+		SuspendedUntil:   nullTime(u.SuspendedUntil),
+		SuspensionReason: nullString(u.SuspensionReason),
+	}
+}
+
+// NewUser maps u to the given view
+func NewUser(u sqlc.User, view View) User {
+	switch view {
+	case ViewAdmin:
+		return NewAdminUser(u)
+	case ViewSelf:
+		return NewSelfUser(u)
+	default:
+		return NewPublicUser(u)
+	}
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/service"
+	"my_project/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	service service.SearchService
+}
+
+func NewSearchController(s service.SearchService) *SearchController {
+	return &SearchController{service: s}
+}
+
+// GET /api/v1/search?q=...&type=all|posts|comments&author_id=1&from=2025-01-01&to=2025-12-31&limit=10&cursor=...
+// from/to nhận RFC3339 hoặc YYYY-MM-DD; "to" dạng ngày được tính trọn cả ngày đó
+func (sc *SearchController) SearchHandler(c *gin.Context) {
+	page, ok := parsePageRequest(c, 10)
+	if !ok {
+		return
+	}
+	var at offsetCursor
+	if !page.decodeCursor(c, cursorScopeSearch, &at) {
+		return
+	}
+
+	query := service.SearchQuery{
+		Text:   c.Query("q"),
+		Scope:  c.Query("type"),
+		Limit:  page.fetchLimit(),
+		Offset: at.Offset,
+	}
+
+	if a := c.Query("author_id"); a != "" {
+		authorID, err := strconv.ParseInt(a, 10, 32)
+		if err != nil || authorID <= 0 {
+			abortWithError(c, apperrors.NewBadRequestError("invalid author_id"))
+			return
+		}
+		query.AuthorID = int32(authorID)
+	}
+
+	var err error
+	if query.From, err = parseSearchDate(c.Query("from"), false); err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid from date"))
+		return
+	}
+	if query.To, err = parseSearchDate(c.Query("to"), true); err != nil {
+		abortWithError(c, apperrors.NewBadRequestError("invalid to date"))
+		return
+	}
+
+	results, err := sc.service.Search(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err, "failed to search")
+		return
+	}
+
+	// Posts and comments share one offset, so there is more while either list overflows
+	hasMore := len(results.Posts) > int(page.Limit) || len(results.Comments) > int(page.Limit)
+	results.Posts = results.Posts[:min(len(results.Posts), int(page.Limit))]
+	results.Comments = results.Comments[:min(len(results.Comments), int(page.Limit))]
+
+	var nextCursor *string
+	if hasMore {
+		cursor, err := utils.EncodeCursor(cursorScopeSearch, offsetCursor{Offset: at.Offset + page.Limit})
+		if err != nil {
+			respondError(c, err, "failed to build cursor")
+			return
+		}
+		nextCursor = &cursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":       results.Posts,
+		"comments":    results.Comments,
+		"next_cursor": nextCursor,
+		"has_more":    hasMore,
+	})
+}
+
+// parseSearchDate accepts RFC3339 or a plain date; a plain end date covers the whole day
+func parseSearchDate(value string, endOfRange bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
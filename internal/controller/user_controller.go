@@ -1,20 +1,25 @@
 package controller
 
 import (
-	"my_project/internal/database/sqlc"
+	"log"
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/controller/response"
 	"my_project/internal/service"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserController struct {
-	userService service.UserService
+	userService              service.UserService
+	emailVerificationService service.EmailVerificationService
+	audit                    service.AuditLogger
 }
 
-func NewUserController(userService service.UserService) *UserController {
-	return &UserController{userService}
+func NewUserController(userService service.UserService, emailVerificationService service.EmailVerificationService, audit service.AuditLogger) *UserController {
+	return &UserController{userService, emailVerificationService, audit}
 }
 
 // POST /api/v1/users  (admin tạo user mới)
@@ -24,50 +29,313 @@ func (uc *UserController) CreateUserHandler(c *gin.Context) {
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	user, err := uc.userService.Register(c.Request.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to create user")
 		return
 	}
-	c.JSON(http.StatusCreated, user)
+	uc.record(c, service.AuditUserCreate, user.ID, nil, response.NewAdminUser(user))
+	c.JSON(http.StatusCreated, response.NewAdminUser(user))
 }
 
-// GET /api/v1/users
+// GET /api/v1/users?limit=20&cursor=...
+// Email and account details are only included for the caller themself and admins
 func (uc *UserController) ListUsersHandler(c *gin.Context) {
-	users, err := uc.userService.ListUsers(c.Request.Context())
+	page, ok := parsePageRequest(c, defaultPageSize)
+	if !ok {
+		return
+	}
+	var after idCursor
+	if !page.decodeCursor(c, cursorScopeUsers, &after) {
+		return
+	}
+
+	users, err := uc.userService.ListUsers(c.Request.Context(), after.ID, page.fetchLimit())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to fetch users")
 		return
 	}
 
-	if users == nil {
-		users = []sqlc.User{}
+	views := make([]response.User, len(users))
+	for i, u := range users {
+		views[i] = response.NewUser(u, userView(c, u.ID))
 	}
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	respondPage(c, "users", cursorScopeUsers, page, views, func(u response.User) any {
+		return idCursor{ID: u.UserID()}
+	})
 }
 
 // GET /api/v1/users/:id
 func (uc *UserController) GetUserHandler(c *gin.Context) {
-	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
 	user, err := uc.userService.GetUser(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to fetch user")
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, response.NewUser(user, userView(c, user.ID)))
 }
 
 // DELETE /api/v1/users/:id
+// Xoá mềm: admin khôi phục được cho tới khi job purge xoá hẳn
 func (uc *UserController) DeleteUserHandler(c *gin.Context) {
-	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	before, err := uc.userService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to fetch user")
+		return
+	}
+
 	if err := uc.userService.DeleteUser(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, err, "failed to delete user")
 		return
 	}
+	uc.record(c, service.AuditUserDelete, before.ID, response.NewAdminUser(before), nil)
 	c.Status(http.StatusNoContent)
 }
+
+// PUT /api/v1/users/:id/role  (admin thăng/giáng quyền)
+func (uc *UserController) ChangeRoleHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	before, err := uc.userService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to fetch user")
+		return
+	}
+
+	user, err := uc.userService.ChangeRole(c.Request.Context(), actor.UserID, id, req.Role)
+	if err != nil {
+		respondError(c, err, "failed to change role")
+		return
+	}
+	uc.record(c, service.AuditUserRoleChange, user.ID, response.NewAdminUser(before), response.NewAdminUser(user))
+	c.JSON(http.StatusOK, response.NewAdminUser(user))
+}
+
+// POST /api/v1/users/:id/unlock  (admin mở khóa đăng nhập)
+func (uc *UserController) UnlockLoginHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	if err := uc.userService.UnlockLogin(c.Request.Context(), actor.UserID, id); err != nil {
+		respondError(c, err, "failed to unlock user")
+		return
+	}
+	uc.record(c, service.AuditUserUnlock, int32(id), nil, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "login unlocked"})
+}
+
+// GET /api/v1/admin/users?q=&role=&status=active|suspended|deleted&sort=-created_at&limit=20&cursor=...
+// q tìm theo username, email hoặc display name (không phân biệt hoa thường)
+func (uc *UserController) AdminListUsersHandler(c *gin.Context) {
+	page, ok := parsePageRequest(c, defaultPageSize)
+	if !ok {
+		return
+	}
+	var at offsetCursor
+	if !page.decodeCursor(c, cursorScopeAdminUsers, &at) {
+		return
+	}
+	next := offsetCursor{Offset: at.Offset + page.Limit}
+
+	users, err := uc.userService.SearchUsers(c.Request.Context(), service.UserFilter{
+		Query:  c.Query("q"),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+		Sort:   c.Query("sort"),
+		Limit:  page.fetchLimit(),
+		Offset: at.Offset,
+	})
+	if err != nil {
+		respondError(c, err, "failed to fetch users")
+		return
+	}
+
+	views := make([]response.AdminUser, len(users))
+	for i, u := range users {
+		views[i] = response.NewAdminUser(u)
+	}
+	respondPage(c, "users", cursorScopeAdminUsers, page, views, func(response.AdminUser) any { return next })
+}
+
+// PATCH /api/v1/admin/users/:id
+// Đổi email thì email mới phải được xác nhận lại
+func (uc *UserController) AdminUpdateUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	var req struct {
+		Username *string `json:"username" binding:"omitempty,max=50"`
+		Email    *string `json:"email" binding:"omitempty,email,max=100"`
+		Role     *string `json:"role"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	before, err := uc.userService.GetUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to fetch user")
+		return
+	}
+
+	user, err := uc.userService.UpdateUser(c.Request.Context(), actor.UserID, id, service.AdminUserUpdate{
+		Username: req.Username,
+		Email:    req.Email,
+		Role:     req.Role,
+	})
+	if err != nil {
+		respondError(c, err, "failed to update user")
+		return
+	}
+	uc.record(c, service.AuditUserUpdate, user.ID, response.NewAdminUser(before), response.NewAdminUser(user))
+
+	// Địa chỉ mới phải được xác nhận lại; gửi mail lỗi thì người dùng có thể yêu cầu gửi lại
+	if user.Email != before.Email {
+		if err := uc.emailVerificationService.SendVerification(c.Request.Context(), user); err != nil {
+			log.Printf("failed to start email verification for user %d: %v", user.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, response.NewAdminUser(user))
+}
+
+// POST /api/v1/admin/users/:id/suspend
+// Không có "until" thì tạm khoá cho tới khi admin mở lại
+func (uc *UserController) SuspendUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	var req struct {
+		Reason string     `json:"reason" binding:"required,max=500"`
+		Until  *time.Time `json:"until"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actor, err := currentActor(c)
+	if err != nil {
+		abortWithError(c, apperrors.NewInternalError("invalid user context"))
+		return
+	}
+
+	user, err := uc.userService.SuspendUser(c.Request.Context(), actor.UserID, id, req.Reason, req.Until)
+	if err != nil {
+		respondError(c, err, "failed to suspend user")
+		return
+	}
+	uc.record(c, service.AuditUserSuspend, user.ID, nil, response.NewAdminUser(user))
+	c.JSON(http.StatusOK, response.NewAdminUser(user))
+}
+
+// POST /api/v1/admin/users/:id/unsuspend
+func (uc *UserController) UnsuspendUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	user, err := uc.userService.UnsuspendUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to unsuspend user")
+		return
+	}
+	uc.record(c, service.AuditUserUnsuspend, user.ID, nil, response.NewAdminUser(user))
+	c.JSON(http.StatusOK, response.NewAdminUser(user))
+}
+
+// POST /api/v1/admin/users/:id/restore  (khôi phục user đã xoá, trước khi bị purge)
+func (uc *UserController) RestoreUserHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	user, err := uc.userService.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to restore user")
+		return
+	}
+	uc.record(c, service.AuditUserRestore, user.ID, nil, response.NewAdminUser(user))
+	c.JSON(http.StatusOK, response.NewAdminUser(user))
+}
+
+// POST /api/v1/admin/users/:id/logout  (đăng xuất user khỏi mọi thiết bị)
+func (uc *UserController) RevokeAccessHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 32)
+	if err != nil || id <= 0 {
+		abortWithError(c, apperrors.InvalidUserID())
+		return
+	}
+
+	if err := uc.userService.RevokeAccess(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to sign user out")
+		return
+	}
+	uc.record(c, service.AuditUserLogout, int32(id), nil, nil)
+	c.Status(http.StatusNoContent)
+}
+
+// record adds an admin action on user id to the audit log; before and after are
+// snapshots of the account, nil when there is none
+func (uc *UserController) record(c *gin.Context, action string, id int32, before, after any) {
+	event := auditEvent(c, action)
+	event.TargetType, event.TargetID = service.AuditTargetUser, id
+	event.Before, event.After = before, after
+	uc.audit.Record(c.Request.Context(), event)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	apperrors "my_project/internal/app/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report request fields by their JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds the request body into req; on failure it records a validation
+// error with per-field details and returns false.
+func bindJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		abortWithError(c, bindingError(err))
+		return false
+	}
+	return true
+}
+
+// bindingError translates gin binding failures into a VALIDATION_ERROR or BAD_REQUEST
+func bindingError(err error) *apperrors.AppError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]apperrors.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, apperrors.FieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return apperrors.NewValidationError("request validation failed").WithDetails(details)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperrors.NewValidationError("request validation failed").WithDetails([]apperrors.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+		}})
+	}
+
+	return apperrors.NewBadRequestError("invalid request body")
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	}
+	return "is invalid"
+}
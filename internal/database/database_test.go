@@ -156,7 +156,7 @@ func TestSQLCIntegration(t *testing.T) {
 	}
 
 	// Test listing users
-	users, err := queries.ListUsers(ctx)
+	users, err := queries.ListUsers(ctx, sqlc.ListUsersParams{PageLimit: 10})
 	if err != nil {
 		t.Fatalf("failed to list users: %v", err)
 	}
@@ -166,8 +166,11 @@ func TestSQLCIntegration(t *testing.T) {
 	}
 
 	// Test deleting the user
-	err = queries.DeleteUser(ctx, user.ID)
+	deleted, err := queries.DeleteUser(ctx, user.ID)
 	if err != nil {
 		t.Fatalf("failed to delete user: %v", err)
 	}
+	if deleted != 1 {
+		t.Errorf("expected 1 deleted user, got %d", deleted)
+	}
 }
@@ -0,0 +1,9 @@
+-- +goose Up
+-- updated_at stays NULL until the comment is edited
+ALTER TABLE comments ADD COLUMN updated_at TIMESTAMPTZ;
+
+CREATE INDEX idx_comments_post_id ON comments(post_id, created_at);
+
+-- +goose Down
+DROP INDEX idx_comments_post_id;
+ALTER TABLE comments DROP COLUMN updated_at;
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE comments ADD COLUMN parent_id INT REFERENCES comments(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_comments_parent_id ON comments(parent_id);
+
+-- +goose Down
+DROP INDEX idx_comments_parent_id;
+ALTER TABLE comments DROP COLUMN parent_id;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published'
+        CONSTRAINT posts_status_check CHECK (status IN ('draft', 'published', 'archived')),
+    ADD COLUMN published_at TIMESTAMPTZ;
+
+-- Existing posts were always public
+UPDATE posts SET published_at = created_at;
+
+CREATE INDEX idx_posts_status_created_at ON posts(status, created_at DESC);
+
+-- +goose Down
+DROP INDEX idx_posts_status_created_at;
+ALTER TABLE posts
+    DROP COLUMN published_at,
+    DROP COLUMN status;
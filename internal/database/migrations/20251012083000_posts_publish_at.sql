@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMPTZ;
+
+-- Lets the scheduler find due drafts without scanning every post
+CREATE INDEX idx_posts_scheduled ON posts(publish_at) WHERE status = 'draft' AND publish_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX idx_posts_scheduled;
+ALTER TABLE posts DROP COLUMN publish_at;
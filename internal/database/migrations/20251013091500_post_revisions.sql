@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    editor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    restored_from INT, -- revision number this one was restored from
+    created_at TIMESTAMPTZ DEFAULT now(),
+    UNIQUE (post_id, revision)
+);
+
+-- Existing posts start their history at revision 1
+INSERT INTO post_revisions (post_id, revision, editor_id, title, content, created_at)
+SELECT id, 1, user_id, title, content, COALESCE(updated_at, created_at, now())
+FROM posts;
+
+-- +goose Down
+DROP TABLE post_revisions;
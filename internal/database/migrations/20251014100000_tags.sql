@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(32) UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE post_tags (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+-- The primary key covers lookups by post; filtering by tag needs the reverse
+CREATE INDEX idx_post_tags_tag_id ON post_tags(tag_id);
+
+-- +goose Down
+DROP TABLE post_tags;
+DROP TABLE tags;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- 'simple' keeps words as written: posts are mostly Vietnamese, which has no built-in stemmer
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+) STORED;
+
+ALTER TABLE comments ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', coalesce(content, ''))
+) STORED;
+
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+CREATE INDEX idx_comments_search_vector ON comments USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_comments_search_vector;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+ALTER TABLE comments DROP COLUMN search_vector;
+ALTER TABLE posts DROP COLUMN search_vector;
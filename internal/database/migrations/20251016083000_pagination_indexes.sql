@@ -0,0 +1,8 @@
+-- +goose Up
+-- Keyset pagination walks posts by (created_at, id) newest first
+CREATE INDEX idx_posts_created_at_id ON posts (created_at DESC, id DESC);
+CREATE INDEX idx_posts_user_created_at_id ON posts (user_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_posts_user_created_at_id;
+DROP INDEX IF EXISTS idx_posts_created_at_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed are treated as verified
+UPDATE users SET email_verified_at = COALESCE(created_at, now());
+
+CREATE TABLE email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
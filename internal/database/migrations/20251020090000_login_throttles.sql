@@ -0,0 +1,31 @@
+-- +goose Up
+-- Failed login counters, one row per email (scope 'account') or client IP (scope 'ip').
+-- Emails are tracked whether or not an account exists, so lockouts reveal nothing.
+CREATE TABLE login_throttles (
+    scope TEXT NOT NULL CHECK (scope IN ('account', 'ip')),
+    key TEXT NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (scope, key)
+);
+
+-- One row per lockout, kept after it expires or is lifted
+CREATE TABLE login_lockouts (
+    id SERIAL PRIMARY KEY,
+    scope TEXT NOT NULL,
+    key TEXT NOT NULL,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,
+    ip_address TEXT NOT NULL,
+    failed_count INT NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    unlocked_at TIMESTAMPTZ,
+    unlocked_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_login_lockouts_scope_key ON login_lockouts(scope, key);
+
+-- +goose Down
+DROP TABLE login_lockouts;
+DROP TABLE login_throttles;
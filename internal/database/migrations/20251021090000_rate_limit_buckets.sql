@@ -0,0 +1,12 @@
+-- +goose Up
+-- Shared rate limit state for multi-instance deployments. tat is the GCRA
+-- theoretical arrival time; the data is disposable, so the table is not WAL-logged.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_tat ON rate_limit_buckets(tat);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Personal API keys. Only a hash of the key is stored; prefix is the start of
+-- the key, kept in clear so users can tell their keys apart.
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN display_name VARCHAR(100),
+    ADD COLUMN bio VARCHAR(500),
+    ADD COLUMN avatar_url VARCHAR(2048);
+
+-- Set when the token confirms a change of address rather than the current one
+ALTER TABLE email_verification_tokens ADD COLUMN new_email VARCHAR(100);
+
+-- +goose Down
+ALTER TABLE email_verification_tokens DROP COLUMN new_email;
+
+ALTER TABLE users
+    DROP COLUMN avatar_url,
+    DROP COLUMN bio,
+    DROP COLUMN display_name;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- A suspension without suspended_until lasts until an admin lifts it
+ALTER TABLE users
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN suspended_until TIMESTAMPTZ,
+    ADD COLUMN suspension_reason VARCHAR(500),
+    ADD COLUMN tokens_revoked_at TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN tokens_revoked_at,
+    DROP COLUMN suspension_reason,
+    DROP COLUMN suspended_until,
+    DROP COLUMN suspended_at;
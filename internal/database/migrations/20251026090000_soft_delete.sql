@@ -0,0 +1,28 @@
+-- +goose Up
+-- Deleted rows are hidden from every read query and hard-deleted by the purge job
+-- once the retention window has passed
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX posts_deleted_at_idx ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Placeholder author for the posts and comments of purged users when their content
+-- is anonymized; it cannot sign in (no password hash matches "!")
+INSERT INTO users (username, email, password_hash, role)
+VALUES ('[deleted user]', 'deleted-user@invalid', '!', 'user');
+
+-- +goose Down
+-- Anonymized posts and comments belong to the placeholder and would cascade away
+-- with it, so it is only removed while it owns nothing and otherwise stays behind
+-- as an ordinary account
+DELETE FROM users u
+WHERE u.username = '[deleted user]'
+  AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.user_id = u.id)
+  AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.user_id = u.id);
+
+DROP INDEX posts_deleted_at_idx;
+DROP INDEX users_deleted_at_idx;
+
+ALTER TABLE posts DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN deleted_at;
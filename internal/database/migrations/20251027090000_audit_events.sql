@@ -0,0 +1,43 @@
+-- +goose Up
+-- Append-only record of security and admin actions. Actor and target ids are not
+-- foreign keys so events outlive the rows they describe (and purges never touch them).
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,
+    actor_id INT,
+    target_type TEXT,
+    target_id INT,
+    ip_address TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    before_state JSONB NOT NULL DEFAULT 'null',
+    after_state JSONB NOT NULL DEFAULT 'null',
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_events_actor ON audit_events(actor_id, id);
+CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id, id);
+CREATE INDEX idx_audit_events_action ON audit_events(action, id);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+
+-- The triggers also bind the table owner, which REVOKE would not
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP FUNCTION audit_events_append_only();
@@ -0,0 +1,11 @@
+-- +goose Up
+-- Deleting a comment keeps its replies; they move up to the top level instead of
+-- disappearing with it
+ALTER TABLE comments
+    DROP CONSTRAINT comments_parent_id_fkey,
+    ADD CONSTRAINT comments_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE comments
+    DROP CONSTRAINT comments_parent_id_fkey,
+    ADD CONSTRAINT comments_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES comments(id) ON DELETE CASCADE;
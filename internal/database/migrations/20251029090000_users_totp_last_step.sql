@@ -0,0 +1,6 @@
+-- +goose Up
+-- Last TOTP time step accepted for the user; a code from the same or an earlier step is a replay
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN totp_last_step;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- One row per mfa token (keyed by its jti): the token works once and only for a few wrong codes
+CREATE TABLE mfa_challenges (
+    jti TEXT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+-- +goose Down
+DROP TABLE mfa_challenges;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Set when a signed-in user started the flow to link a provider to their own account
+ALTER TABLE oidc_auth_requests ADD COLUMN link_user_id INT REFERENCES users(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE oidc_auth_requests DROP COLUMN link_user_id;
@@ -0,0 +1,17 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (action, actor_id, target_type, target_id, ip_address, user_agent, request_id, before_state, after_state, metadata)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: ListAuditEvents :many
+-- Newest first; NULL filters match every event
+SELECT * FROM audit_events
+WHERE (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id)::bigint)
+  AND (sqlc.narg(actor_id)::int IS NULL OR actor_id = sqlc.narg(actor_id)::int)
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action)::text)
+  AND (sqlc.narg(target_type)::text IS NULL OR target_type = sqlc.narg(target_type)::text)
+  AND (sqlc.narg(target_id)::int IS NULL OR target_id = sqlc.narg(target_id)::int)
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since)::timestamptz)
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until)::timestamptz)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
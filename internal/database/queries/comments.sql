@@ -1,11 +1,80 @@
 -- name: CreateComment :one
-INSERT INTO comments (post_id, user_id, content)
-VALUES ($1, $2, $3)
+INSERT INTO comments (post_id, user_id, content, parent_id)
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
+-- name: GetCommentByID :one
+-- Comments on deleted posts or by deleted users are treated as missing
+SELECT c.* FROM comments c
+JOIN posts p ON p.id = c.post_id
+JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetCommentDepth :one
+-- Depth of a comment in its thread (0 for top-level comments)
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_id, 0 AS depth
+    FROM comments c
+    WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_id, a.depth + 1
+    FROM comments c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT MAX(ancestors.depth)::int AS depth FROM ancestors;
+
 -- name: ListCommentsByPost :many
-SELECT c.*, u.username
+SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, c.parent_id, u.username
 FROM comments c
 JOIN users u ON c.user_id = u.id
-WHERE c.post_id = $1
-ORDER BY c.created_at ASC;
+WHERE c.post_id = $1 AND u.deleted_at IS NULL
+ORDER BY c.created_at ASC, c.id ASC
+LIMIT $2 OFFSET $3;
+
+-- name: ListCommentThreadByPost :many
+-- Paginates top-level comments and walks their replies down to max_depth.
+-- Rows come back in thread order (depth-first by path). Comments of deleted users
+-- are left out together with their replies.
+WITH RECURSIVE thread AS (
+    SELECT root.id, root.post_id, root.user_id, root.parent_id, root.content, root.created_at, root.updated_at,
+        0 AS depth,
+        ARRAY[root.id] AS path
+    FROM (
+        SELECT comments.* FROM comments
+        JOIN users ru ON ru.id = comments.user_id
+        WHERE comments.post_id = sqlc.arg(post_id) AND comments.parent_id IS NULL AND ru.deleted_at IS NULL
+        ORDER BY comments.created_at ASC, comments.id ASC
+        LIMIT sqlc.arg(root_limit) OFFSET sqlc.arg(root_offset)
+    ) root
+    UNION ALL
+    SELECT c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at,
+        t.depth + 1,
+        t.path || c.id
+    FROM comments c
+    JOIN thread t ON c.parent_id = t.id
+    JOIN users cu ON cu.id = c.user_id
+    WHERE t.depth < sqlc.arg(max_depth)::int AND cu.deleted_at IS NULL
+)
+SELECT t.id, t.post_id, t.user_id, t.parent_id, t.content, t.created_at, t.updated_at,
+    t.depth::int AS depth,
+    t.path::int[] AS path,
+    u.username
+FROM thread t
+JOIN users u ON t.user_id = u.id
+ORDER BY t.path;
+
+-- name: UpdateComment :one
+UPDATE comments
+SET content = $2, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteComment :exec
+DELETE FROM comments WHERE id = $1;
+
+-- name: AnonymizeUserComments :exec
+-- Hands the user's comments to the "[deleted user]" placeholder before the user is purged
+UPDATE comments
+SET user_id = (SELECT g.id FROM users g WHERE g.username = '[deleted user]')
+WHERE user_id = $1;
@@ -0,0 +1,22 @@
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (user_id, token_hash, expires_at, new_email)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetLatestEmailVerificationToken :one
+SELECT * FROM email_verification_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ConsumeEmailVerificationToken :one
+-- Marks an unused, unexpired token as used; no row means the token cannot be used
+UPDATE email_verification_tokens
+SET used_at = now()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING user_id, new_email;
+
+-- name: InvalidateEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = now()
+WHERE user_id = $1 AND used_at IS NULL;
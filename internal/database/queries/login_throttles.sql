@@ -0,0 +1,40 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND key = $2;
+
+-- name: RecordLoginFailure :one
+-- Counts a failed attempt. The counter starts over when the previous failure is
+-- older than window_start or the last lockout has expired.
+INSERT INTO login_throttles (scope, key, failed_count, last_failed_at)
+VALUES (sqlc.arg(scope), sqlc.arg(key), 1, now())
+ON CONFLICT (scope, key) DO UPDATE
+SET failed_count = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(window_start)::timestamptz
+          OR login_throttles.locked_until <= now() THEN 1
+        ELSE login_throttles.failed_count + 1
+    END,
+    locked_until = CASE
+        WHEN login_throttles.locked_until <= now() THEN NULL
+        ELSE login_throttles.locked_until
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND key = $2;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE scope = $1 AND key = $2;
+
+-- name: CreateLoginLockout :one
+INSERT INTO login_lockouts (scope, key, user_id, ip_address, failed_count, locked_until)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: LiftLoginLockouts :exec
+UPDATE login_lockouts
+SET unlocked_at = now(), unlocked_by = $3
+WHERE scope = $1 AND key = $2 AND unlocked_at IS NULL AND locked_until > now();
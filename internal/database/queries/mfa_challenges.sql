@@ -0,0 +1,24 @@
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (jti, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetMFAChallenge :one
+-- Only challenges that are neither used nor expired
+SELECT * FROM mfa_challenges
+WHERE jti = $1 AND used_at IS NULL AND expires_at > now();
+
+-- name: RecordMFAChallengeFailure :one
+UPDATE mfa_challenges
+SET failed_attempts = failed_attempts + 1
+WHERE jti = $1
+RETURNING failed_attempts;
+
+-- name: UseMFAChallenge :execrows
+-- Marks the challenge used; no row is updated when another request used it first
+UPDATE mfa_challenges
+SET used_at = now()
+WHERE jti = $1 AND used_at IS NULL AND expires_at > now();
+
+-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at <= now();
@@ -0,0 +1,21 @@
+-- name: CreatePostRevision :one
+-- Must run in the same transaction that changed the post, which holds its row lock
+INSERT INTO post_revisions (post_id, revision, editor_id, title, content, restored_from)
+VALUES (
+    $1,
+    (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM post_revisions r WHERE r.post_id = $1),
+    $2, $3, $4, $5
+)
+RETURNING *;
+
+-- name: GetPostRevision :one
+SELECT * FROM post_revisions
+WHERE post_id = $1 AND revision = $2
+LIMIT 1;
+
+-- name: ListPostRevisions :many
+SELECT r.id, r.post_id, r.revision, r.editor_id, r.title, r.restored_from, r.created_at, u.username AS editor_username
+FROM post_revisions r
+LEFT JOIN users u ON r.editor_id = u.id AND u.deleted_at IS NULL
+WHERE r.post_id = $1
+ORDER BY r.revision DESC;
@@ -1,36 +1,111 @@
 -- name: CreatePost :one
 WITH new_post AS (
-    INSERT INTO posts (user_id, title, content)
-    VALUES ($1, $2, $3)
+    INSERT INTO posts (user_id, title, content, status, published_at, publish_at)
+    VALUES ($1, $2, $3, $4, CASE WHEN $4 = 'published' THEN now() END, $5)
     RETURNING *
 )
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username
 FROM new_post p
 JOIN users u ON p.user_id = u.id;
 
 -- name: GetPostByID :one
-SELECT * FROM posts WHERE id = $1 LIMIT 1;
+-- Posts of deleted users are hidden like deleted posts
+SELECT p.* FROM posts p
+JOIN users u ON u.id = p.user_id
+WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+LIMIT 1;
+
+-- name: GetPostDetail :one
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
+FROM posts p
+JOIN users u ON p.user_id = u.id
+WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL;
+
+-- name: LockPost :one
+-- Locks a live post until the transaction ends, so a snapshot read next is what gets changed
+SELECT id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;
 
 -- name: ListPosts :many
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+-- An empty tags array disables the filter; match_all requires every tag, otherwise any one is enough.
+-- Pages are keyed on (created_at, id): pass the last row of the previous page as after_*.
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
 FROM posts p
 JOIN users u ON p.user_id = u.id
-ORDER BY p.created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE p.status = 'published' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+  AND (cardinality(sqlc.arg(tags)::text[]) = 0 OR (
+    SELECT COUNT(*) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+    WHERE pt.post_id = p.id AND t.name = ANY(sqlc.arg(tags)::text[])
+  ) >= CASE WHEN sqlc.arg(match_all)::bool THEN cardinality(sqlc.arg(tags)::text[]) ELSE 1 END)
+  AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+       OR (p.created_at, p.id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::int))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg(page_limit);
 
 -- name: ListPostsByUser :many
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+-- Drafts and archived posts are only included when include_unpublished is set (author viewing own posts)
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
 FROM posts p
 JOIN users u ON p.user_id = u.id
-WHERE p.user_id = $1
-ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE p.user_id = sqlc.arg(user_id) AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+  AND (p.status = 'published' OR sqlc.arg(include_unpublished)::bool)
+  AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+       OR (p.created_at, p.id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::int))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg(page_limit);
 
 -- name: UpdatePost :one
+-- A NULL publish_at keeps the current schedule
 UPDATE posts
-SET title = $2, content = $3, updated_at = now()
-WHERE id = $1
+SET title = sqlc.arg(title),
+    content = sqlc.arg(content),
+    publish_at = COALESCE(sqlc.narg(publish_at), publish_at),
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeletePost :exec
-DELETE FROM posts WHERE id = $1;
+-- name: UpdatePostStatus :one
+UPDATE posts
+SET status = sqlc.arg(status),
+    published_at = CASE WHEN sqlc.arg(status) = 'published' THEN COALESCE(published_at, now()) ELSE published_at END,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: PublishDuePosts :many
+-- SKIP LOCKED lets several API replicas run the scheduler without publishing a post twice
+UPDATE posts
+SET status = 'published', published_at = publish_at, updated_at = now()
+WHERE id IN (
+    SELECT d.id FROM posts d
+    WHERE d.status = 'draft' AND d.publish_at IS NOT NULL AND d.publish_at <= now() AND d.deleted_at IS NULL
+    ORDER BY d.publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id;
+
+-- name: SoftDeletePost :execrows
+UPDATE posts
+SET deleted_at = now(), updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts WHERE deleted_at < $1;
+
+-- name: AnonymizeUserPosts :exec
+-- Hands the user's posts to the "[deleted user]" placeholder before the user is purged
+UPDATE posts
+SET user_id = (SELECT g.id FROM users g WHERE g.username = '[deleted user]')
+WHERE user_id = $1;
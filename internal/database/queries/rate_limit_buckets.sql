@@ -0,0 +1,18 @@
+-- name: TakeRateLimitToken :one
+-- Advances the bucket by one request when it still fits in the window; no row
+-- is returned when the request has to be refused
+INSERT INTO rate_limit_buckets AS b (key, tat)
+VALUES (sqlc.arg(key), now() + make_interval(secs => sqlc.arg(interval_secs)::float8))
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(b.tat, now()) + make_interval(secs => sqlc.arg(interval_secs)::float8)
+WHERE GREATEST(b.tat, now()) + make_interval(secs => sqlc.arg(interval_secs)::float8)
+   <= now() + make_interval(secs => sqlc.arg(window_secs)::float8)
+RETURNING tat, now()::timestamptz AS now;
+
+-- name: GetRateLimitBucket :one
+SELECT tat, now()::timestamptz AS now FROM rate_limit_buckets
+WHERE key = $1;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE tat <= now();
@@ -0,0 +1,49 @@
+-- name: SearchPosts :many
+-- Ranks and paginates first so ts_headline only runs on the returned page.
+-- Matches are delimited by chr(2)/chr(3) (stripped from the text first) and turned into <mark> after HTML-escaping in Go
+WITH query AS (
+    SELECT websearch_to_tsquery('simple', sqlc.arg(query)) AS q
+), hits AS (
+    SELECT p.id, p.user_id, p.title, p.content, p.created_at,
+        ts_rank(p.search_vector, query.q) AS rank
+    FROM posts p
+    JOIN users a ON a.id = p.user_id, query
+    WHERE p.status = 'published' AND p.deleted_at IS NULL AND a.deleted_at IS NULL
+      AND p.search_vector @@ query.q
+      AND (sqlc.narg(author_id)::int IS NULL OR p.user_id = sqlc.narg(author_id)::int)
+      AND (sqlc.narg(created_from)::timestamptz IS NULL OR p.created_at >= sqlc.narg(created_from)::timestamptz)
+      AND (sqlc.narg(created_to)::timestamptz IS NULL OR p.created_at < sqlc.narg(created_to)::timestamptz)
+    ORDER BY rank DESC, p.id DESC
+    LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset)
+)
+SELECT h.id, h.user_id, u.username, h.title, h.created_at, h.rank::real AS rank,
+    ts_headline('simple', translate(h.title, chr(2) || chr(3), ''), query.q, 'HighlightAll=true, StartSel=' || chr(2) || ', StopSel=' || chr(3)) AS title_highlight,
+    ts_headline('simple', translate(h.content, chr(2) || chr(3), ''), query.q, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=' || chr(2) || ', StopSel=' || chr(3)) AS snippet
+FROM hits h
+JOIN users u ON h.user_id = u.id, query
+ORDER BY h.rank DESC, h.id DESC;
+
+-- name: SearchComments :many
+-- Only comments on published posts are searchable
+WITH query AS (
+    SELECT websearch_to_tsquery('simple', sqlc.arg(query)) AS q
+), hits AS (
+    SELECT c.id, c.post_id, c.user_id, c.content, c.created_at,
+        ts_rank(c.search_vector, query.q) AS rank
+    FROM comments c
+    JOIN posts p ON c.post_id = p.id
+    JOIN users a ON a.id = c.user_id, query
+    WHERE p.status = 'published' AND p.deleted_at IS NULL AND a.deleted_at IS NULL
+      AND c.search_vector @@ query.q
+      AND (sqlc.narg(author_id)::int IS NULL OR c.user_id = sqlc.narg(author_id)::int)
+      AND (sqlc.narg(created_from)::timestamptz IS NULL OR c.created_at >= sqlc.narg(created_from)::timestamptz)
+      AND (sqlc.narg(created_to)::timestamptz IS NULL OR c.created_at < sqlc.narg(created_to)::timestamptz)
+    ORDER BY rank DESC, c.id DESC
+    LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset)
+)
+SELECT h.id, h.post_id, p.title AS post_title, h.user_id, u.username, h.created_at, h.rank::real AS rank,
+    ts_headline('simple', translate(h.content, chr(2) || chr(3), ''), query.q, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=' || chr(2) || ', StopSel=' || chr(3)) AS snippet
+FROM hits h
+JOIN posts p ON h.post_id = p.id
+JOIN users u ON h.user_id = u.id, query
+ORDER BY h.rank DESC, h.id DESC;
@@ -0,0 +1,22 @@
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, family_id, refresh_token_hash, device_name, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSessionByTokenHash :one
+SELECT * FROM sessions WHERE refresh_token_hash = $1 LIMIT 1;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeSessionFamily :exec
+UPDATE sessions
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = now()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,26 @@
+-- name: UpsertTags :many
+-- DO UPDATE (rather than DO NOTHING) so existing tags are returned as well
+INSERT INTO tags (name)
+SELECT unnest(sqlc.arg(names)::text[])
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id;
+
+-- name: DeletePostTags :exec
+DELETE FROM post_tags WHERE post_id = $1;
+
+-- name: AddPostTags :exec
+INSERT INTO post_tags (post_id, tag_id)
+SELECT sqlc.arg(post_id), unnest(sqlc.arg(tag_ids)::int[])
+ON CONFLICT DO NOTHING;
+
+-- name: ListTagsWithCounts :many
+-- Only published posts count, so tags used solely on drafts stay out of the cloud
+SELECT t.id, t.name, COUNT(*) AS post_count
+FROM tags t
+JOIN post_tags pt ON pt.tag_id = t.id
+JOIN posts p ON p.id = pt.post_id
+JOIN users u ON u.id = p.user_id
+WHERE p.status = 'published' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+GROUP BY t.id, t.name
+ORDER BY post_count DESC, t.name
+LIMIT $1;
@@ -0,0 +1,23 @@
+-- name: GetUserByIdentity :one
+SELECT u.* FROM users u
+JOIN user_identities ui ON ui.user_id = u.id
+WHERE ui.provider = $1 AND ui.subject = $2 AND u.deleted_at IS NULL;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateOIDCAuthRequest :exec
+INSERT INTO oidc_auth_requests (state_hash, provider, code_verifier, nonce, expires_at, link_user_id)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ConsumeOIDCAuthRequest :one
+-- Each state can complete one sign-in; no row means unknown, used or expired
+DELETE FROM oidc_auth_requests
+WHERE state_hash = $1 AND expires_at > now()
+RETURNING *;
+
+-- name: DeleteExpiredOIDCAuthRequests :execrows
+DELETE FROM oidc_auth_requests
+WHERE expires_at <= now();
@@ -4,14 +4,144 @@ VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: GetUserByEmail :one
-SELECT * FROM users WHERE email = $1 LIMIT 1;
+-- The "[deleted user]" placeholder is never found, so it cannot sign in or reset its password
+SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL AND username <> '[deleted user]' LIMIT 1;
 
 -- name: GetUserByID :one
-SELECT * FROM users WHERE id = $1 LIMIT 1;
+SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
 
 -- name: ListUsers :many
-SELECT * FROM users ORDER BY id;
+SELECT * FROM users
+WHERE id > sqlc.arg(after_id) AND deleted_at IS NULL AND username <> '[deleted user]'
+ORDER BY id
+LIMIT sqlc.arg(page_limit);
 
--- name: DeleteUser :exec
+-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+-- NULL arguments keep the current value; an empty string clears an optional field
+UPDATE users
+SET username = COALESCE(sqlc.narg(username), username),
+    display_name = CASE WHEN sqlc.narg(display_name)::text IS NULL THEN display_name ELSE NULLIF(sqlc.narg(display_name)::text, '') END,
+    bio = CASE WHEN sqlc.narg(bio)::text IS NULL THEN bio ELSE NULLIF(sqlc.narg(bio)::text, '') END,
+    avatar_url = CASE WHEN sqlc.narg(avatar_url)::text IS NULL THEN avatar_url ELSE NULLIF(sqlc.narg(avatar_url)::text, '') END,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: ChangeUserEmail :one
+-- The new address was confirmed through a verification link
+UPDATE users
+SET email = $2, email_verified_at = now(), updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = now(), updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: ListPurgeableUsers :many
+-- Users deleted before the cutoff, oldest first
+SELECT id FROM users
+WHERE deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2;
+
+-- name: DeleteUser :execrows
 DELETE FROM users WHERE id = $1;
 
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserTOTPSecret :exec
+-- Starts (or restarts) enrollment; 2FA stays off until EnableUserTOTP
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = now(), updated_at = now()
+WHERE id = $1 AND totp_secret IS NOT NULL;
+
+-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: UseUserTOTPStep :execrows
+-- Records an accepted TOTP step; no row is updated when the step was already used
+UPDATE users
+SET totp_last_step = sqlc.arg(step)
+WHERE id = sqlc.arg(id) AND (totp_last_step IS NULL OR totp_last_step < sqlc.arg(step));
+
+-- name: SearchUsers :many
+-- Admin listing. status is "active", "suspended" or "deleted" (deleted users are
+-- only listed with that status); sort is created_at, -created_at, username or
+-- -username, anything else orders by id
+SELECT * FROM users
+WHERE (sqlc.narg(query)::text IS NULL
+       OR username ILIKE '%' || sqlc.narg(query)::text || '%' ESCAPE '\'
+       OR email ILIKE '%' || sqlc.narg(query)::text || '%' ESCAPE '\'
+       OR display_name ILIKE '%' || sqlc.narg(query)::text || '%' ESCAPE '\')
+  AND username <> '[deleted user]'
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role)::text)
+  AND (deleted_at IS NOT NULL) = (sqlc.narg(status)::text IS NOT DISTINCT FROM 'deleted')
+  AND (sqlc.narg(status)::text IS NULL OR sqlc.narg(status)::text = 'deleted'
+       OR (sqlc.narg(status)::text = 'suspended') = (suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > now())))
+ORDER BY
+    CASE WHEN sqlc.arg(sort)::text = 'created_at' THEN created_at END ASC,
+    CASE WHEN sqlc.arg(sort)::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN sqlc.arg(sort)::text = 'username' THEN username END ASC,
+    CASE WHEN sqlc.arg(sort)::text = '-username' THEN username END DESC,
+    id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: AdminUpdateUser :one
+-- NULL arguments keep the current value; a changed email has to be verified again
+UPDATE users
+SET username = COALESCE(sqlc.narg(username), username),
+    email = COALESCE(sqlc.narg(email), email),
+    email_verified_at = CASE WHEN sqlc.narg(email)::text IS NULL OR sqlc.narg(email)::text = email THEN email_verified_at END,
+    role = COALESCE(sqlc.narg(role), role),
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = now(), suspended_until = $2, suspension_reason = $3, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RevokeUserTokens :exec
+-- Access tokens issued before tokens_revoked_at are rejected
+UPDATE users
+SET tokens_revoked_at = now()
+WHERE id = $1;
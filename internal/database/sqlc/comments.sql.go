@@ -8,22 +8,42 @@ package sqlc
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+const anonymizeUserComments = `-- name: AnonymizeUserComments :exec
+UPDATE comments
+SET user_id = (SELECT g.id FROM users g WHERE g.username = '[deleted user]')
+WHERE user_id = $1
+`
+
+// Hands the user's comments to the "[deleted user]" placeholder before the user is purged
+func (q *Queries) AnonymizeUserComments(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUserComments, userID)
+	return err
+}
+
 const createComment = `-- name: CreateComment :one
-INSERT INTO comments (post_id, user_id, content)
-VALUES ($1, $2, $3)
-RETURNING id, post_id, user_id, content, created_at
+INSERT INTO comments (post_id, user_id, content, parent_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, post_id, user_id, content, created_at, updated_at, parent_id, search_vector
 `
 
 type CreateCommentParams struct {
-	PostID  int32  `json:"post_id"`
-	UserID  int32  `json:"user_id"`
-	Content string `json:"content"`
+	PostID   int32         `json:"post_id"`
+	UserID   int32         `json:"user_id"`
+	Content  string        `json:"content"`
+	ParentID sql.NullInt32 `json:"parent_id"`
 }
 
 func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error) {
-	row := q.db.QueryRowContext(ctx, createComment, arg.PostID, arg.UserID, arg.Content)
+	row := q.db.QueryRowContext(ctx, createComment,
+		arg.PostID,
+		arg.UserID,
+		arg.Content,
+		arg.ParentID,
+	)
 	var i Comment
 	err := row.Scan(
 		&i.ID,
@@ -31,29 +51,188 @@ func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (C
 		&i.UserID,
 		&i.Content,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
+const deleteComment = `-- name: DeleteComment :exec
+DELETE FROM comments WHERE id = $1
+`
+
+func (q *Queries) DeleteComment(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteComment, id)
+	return err
+}
+
+const getCommentByID = `-- name: GetCommentByID :one
+SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, c.parent_id, c.search_vector FROM comments c
+JOIN posts p ON p.id = c.post_id
+JOIN users u ON u.id = c.user_id
+WHERE c.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+LIMIT 1
+`
+
+// Comments on deleted posts or by deleted users are treated as missing
+func (q *Queries) GetCommentByID(ctx context.Context, id int32) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, getCommentByID, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+		&i.SearchVector,
+	)
+	return i, err
+}
+
+const getCommentDepth = `-- name: GetCommentDepth :one
+WITH RECURSIVE ancestors AS (
+    SELECT c.id, c.parent_id, 0 AS depth
+    FROM comments c
+    WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_id, a.depth + 1
+    FROM comments c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT MAX(ancestors.depth)::int AS depth FROM ancestors
+`
+
+// Depth of a comment in its thread (0 for top-level comments)
+func (q *Queries) GetCommentDepth(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getCommentDepth, id)
+	var depth int32
+	err := row.Scan(&depth)
+	return depth, err
+}
+
+const listCommentThreadByPost = `-- name: ListCommentThreadByPost :many
+WITH RECURSIVE thread AS (
+    SELECT root.id, root.post_id, root.user_id, root.parent_id, root.content, root.created_at, root.updated_at,
+        0 AS depth,
+        ARRAY[root.id] AS path
+    FROM (
+        SELECT comments.id, comments.post_id, comments.user_id, comments.content, comments.created_at, comments.updated_at, comments.parent_id, comments.search_vector FROM comments
+        JOIN users ru ON ru.id = comments.user_id
+        WHERE comments.post_id = $1 AND comments.parent_id IS NULL AND ru.deleted_at IS NULL
+        ORDER BY comments.created_at ASC, comments.id ASC
+        LIMIT $2 OFFSET $3
+    ) root
+    UNION ALL
+    SELECT c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at,
+        t.depth + 1,
+        t.path || c.id
+    FROM comments c
+    JOIN thread t ON c.parent_id = t.id
+    JOIN users cu ON cu.id = c.user_id
+    WHERE t.depth < $4::int AND cu.deleted_at IS NULL
+)
+SELECT t.id, t.post_id, t.user_id, t.parent_id, t.content, t.created_at, t.updated_at,
+    t.depth::int AS depth,
+    t.path::int[] AS path,
+    u.username
+FROM thread t
+JOIN users u ON t.user_id = u.id
+ORDER BY t.path
+`
+
+type ListCommentThreadByPostParams struct {
+	PostID     int32 `json:"post_id"`
+	RootLimit  int32 `json:"root_limit"`
+	RootOffset int32 `json:"root_offset"`
+	MaxDepth   int32 `json:"max_depth"`
+}
+
+type ListCommentThreadByPostRow struct {
+	ID        int32         `json:"id"`
+	PostID    int32         `json:"post_id"`
+	UserID    int32         `json:"user_id"`
+	ParentID  sql.NullInt32 `json:"parent_id"`
+	Content   string        `json:"content"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	UpdatedAt sql.NullTime  `json:"updated_at"`
+	Depth     int32         `json:"depth"`
+	Path      []int32       `json:"path"`
+	Username  string        `json:"username"`
+}
+
+// Paginates top-level comments and walks their replies down to max_depth.
+// Rows come back in thread order (depth-first by path). Comments of deleted users
+// are left out together with their replies.
+func (q *Queries) ListCommentThreadByPost(ctx context.Context, arg ListCommentThreadByPostParams) ([]ListCommentThreadByPostRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCommentThreadByPost,
+		arg.PostID,
+		arg.RootLimit,
+		arg.RootOffset,
+		arg.MaxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCommentThreadByPostRow
+	for rows.Next() {
+		var i ListCommentThreadByPostRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.ParentID,
+			&i.Content,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Depth,
+			pq.Array(&i.Path),
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listCommentsByPost = `-- name: ListCommentsByPost :many
-SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, u.username
+SELECT c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, c.parent_id, u.username
 FROM comments c
 JOIN users u ON c.user_id = u.id
-WHERE c.post_id = $1
-ORDER BY c.created_at ASC
+WHERE c.post_id = $1 AND u.deleted_at IS NULL
+ORDER BY c.created_at ASC, c.id ASC
+LIMIT $2 OFFSET $3
 `
 
+type ListCommentsByPostParams struct {
+	PostID int32 `json:"post_id"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
 type ListCommentsByPostRow struct {
-	ID        int32        `json:"id"`
-	PostID    int32        `json:"post_id"`
-	UserID    int32        `json:"user_id"`
-	Content   string       `json:"content"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	Username  string       `json:"username"`
+	ID        int32         `json:"id"`
+	PostID    int32         `json:"post_id"`
+	UserID    int32         `json:"user_id"`
+	Content   string        `json:"content"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	UpdatedAt sql.NullTime  `json:"updated_at"`
+	ParentID  sql.NullInt32 `json:"parent_id"`
+	Username  string        `json:"username"`
 }
 
-func (q *Queries) ListCommentsByPost(ctx context.Context, postID int32) ([]ListCommentsByPostRow, error) {
-	rows, err := q.db.QueryContext(ctx, listCommentsByPost, postID)
+func (q *Queries) ListCommentsByPost(ctx context.Context, arg ListCommentsByPostParams) ([]ListCommentsByPostRow, error) {
+	rows, err := q.db.QueryContext(ctx, listCommentsByPost, arg.PostID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +246,8 @@ func (q *Queries) ListCommentsByPost(ctx context.Context, postID int32) ([]ListC
 			&i.UserID,
 			&i.Content,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ParentID,
 			&i.Username,
 		); err != nil {
 			return nil, err
@@ -81,3 +262,31 @@ func (q *Queries) ListCommentsByPost(ctx context.Context, postID int32) ([]ListC
 	}
 	return items, nil
 }
+
+const updateComment = `-- name: UpdateComment :one
+UPDATE comments
+SET content = $2, updated_at = now()
+WHERE id = $1
+RETURNING id, post_id, user_id, content, created_at, updated_at, parent_id, search_vector
+`
+
+type UpdateCommentParams struct {
+	ID      int32  `json:"id"`
+	Content string `json:"content"`
+}
+
+func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, updateComment, arg.ID, arg.Content)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+		&i.SearchVector,
+	)
+	return i, err
+}
@@ -6,31 +6,198 @@ package sqlc
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         int32        `json:"id"`
+	UserID     int32        `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	KeyHash    string       `json:"-"`
+	Scopes     []string     `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID          int64           `json:"id"`
+	Action      string          `json:"action"`
+	ActorID     sql.NullInt32   `json:"actor_id"`
+	TargetType  sql.NullString  `json:"target_type"`
+	TargetID    sql.NullInt32   `json:"target_id"`
+	IpAddress   string          `json:"ip_address"`
+	UserAgent   string          `json:"user_agent"`
+	RequestID   string          `json:"request_id"`
+	BeforeState json.RawMessage `json:"before_state"`
+	AfterState  json.RawMessage `json:"after_state"`
+	Metadata    json.RawMessage `json:"metadata"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
 type Comment struct {
+	ID           int32         `json:"id"`
+	PostID       int32         `json:"post_id"`
+	UserID       int32         `json:"user_id"`
+	Content      string        `json:"content"`
+	CreatedAt    sql.NullTime  `json:"created_at"`
+	UpdatedAt    sql.NullTime  `json:"updated_at"`
+	ParentID     sql.NullInt32 `json:"parent_id"`
+	SearchVector string        `json:"-"`
+}
+
+type EmailVerificationToken struct {
+	ID        int32          `json:"id"`
+	UserID    int32          `json:"user_id"`
+	TokenHash string         `json:"-"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	UsedAt    sql.NullTime   `json:"used_at"`
+	CreatedAt time.Time      `json:"created_at"`
+	NewEmail  sql.NullString `json:"new_email"`
+}
+
+type LoginLockout struct {
+	ID          int32         `json:"id"`
+	Scope       string        `json:"scope"`
+	Key         string        `json:"key"`
+	UserID      sql.NullInt32 `json:"user_id"`
+	IpAddress   string        `json:"ip_address"`
+	FailedCount int32         `json:"failed_count"`
+	LockedUntil time.Time     `json:"locked_until"`
+	UnlockedAt  sql.NullTime  `json:"unlocked_at"`
+	UnlockedBy  sql.NullInt32 `json:"unlocked_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Scope        string       `json:"scope"`
+	Key          string       `json:"key"`
+	FailedCount  int32        `json:"failed_count"`
+	LastFailedAt time.Time    `json:"last_failed_at"`
+	LockedUntil  sql.NullTime `json:"locked_until"`
+}
+
+type MfaChallenge struct {
+	Jti            string       `json:"jti"`
+	UserID         int32        `json:"user_id"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	UsedAt         sql.NullTime `json:"used_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type MfaRecoveryCode struct {
 	ID        int32        `json:"id"`
-	PostID    int32        `json:"post_id"`
 	UserID    int32        `json:"user_id"`
-	Content   string       `json:"content"`
+	CodeHash  string       `json:"-"`
+	UsedAt    sql.NullTime `json:"used_at"`
 	CreatedAt sql.NullTime `json:"created_at"`
 }
 
-type Post struct {
+type OidcAuthRequest struct {
+	StateHash    string        `json:"-"`
+	Provider     string        `json:"provider"`
+	CodeVerifier string        `json:"-"`
+	Nonce        string        `json:"-"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+	LinkUserID   sql.NullInt32 `json:"link_user_id"`
+}
+
+type PasswordResetToken struct {
 	ID        int32        `json:"id"`
 	UserID    int32        `json:"user_id"`
-	Title     string       `json:"title"`
-	Content   string       `json:"content"`
+	TokenHash string       `json:"-"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
 	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
 }
 
-type User struct {
+type Post struct {
 	ID           int32        `json:"id"`
-	Username     string       `json:"username"`
-	Email        string       `json:"email"`
-	PasswordHash string       `json:"password_hash"`
-	Role         string       `json:"role"`
+	UserID       int32        `json:"user_id"`
+	Title        string       `json:"title"`
+	Content      string       `json:"content"`
 	CreatedAt    sql.NullTime `json:"created_at"`
 	UpdatedAt    sql.NullTime `json:"updated_at"`
+	Status       string       `json:"status"`
+	PublishedAt  sql.NullTime `json:"published_at"`
+	PublishAt    sql.NullTime `json:"publish_at"`
+	SearchVector string       `json:"-"`
+	DeletedAt    sql.NullTime `json:"deleted_at"`
+}
+
+type PostRevision struct {
+	ID           int32         `json:"id"`
+	PostID       int32         `json:"post_id"`
+	Revision     int32         `json:"revision"`
+	EditorID     sql.NullInt32 `json:"editor_id"`
+	Title        string        `json:"title"`
+	Content      string        `json:"content"`
+	RestoredFrom sql.NullInt32 `json:"restored_from"`
+	CreatedAt    sql.NullTime  `json:"created_at"`
+}
+
+type PostTag struct {
+	PostID int32 `json:"post_id"`
+	TagID  int32 `json:"tag_id"`
+}
+
+type RateLimitBucket struct {
+	Key string    `json:"key"`
+	Tat time.Time `json:"tat"`
+}
+
+type Session struct {
+	ID               int32        `json:"id"`
+	UserID           int32        `json:"user_id"`
+	FamilyID         uuid.UUID    `json:"family_id"`
+	RefreshTokenHash string       `json:"-"`
+	DeviceName       string       `json:"device_name"`
+	UserAgent        string       `json:"user_agent"`
+	IpAddress        string       `json:"ip_address"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RevokedAt        sql.NullTime `json:"revoked_at"`
+	CreatedAt        sql.NullTime `json:"created_at"`
+}
+
+type Tag struct {
+	ID        int32        `json:"id"`
+	Name      string       `json:"name"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type User struct {
+	ID               int32          `json:"id"`
+	Username         string         `json:"username"`
+	Email            string         `json:"email"`
+	PasswordHash     string         `json:"-"`
+	Role             string         `json:"role"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	EmailVerifiedAt  sql.NullTime   `json:"email_verified_at"`
+	TotpSecret       sql.NullString `json:"-"`
+	TotpEnabledAt    sql.NullTime   `json:"totp_enabled_at"`
+	DisplayName      sql.NullString `json:"display_name"`
+	Bio              sql.NullString `json:"bio"`
+	AvatarUrl        sql.NullString `json:"avatar_url"`
+	SuspendedAt      sql.NullTime   `json:"suspended_at"`
+	SuspendedUntil   sql.NullTime   `json:"suspended_until"`
+	SuspensionReason sql.NullString `json:"suspension_reason"`
+	TokensRevokedAt  sql.NullTime   `json:"tokens_revoked_at"`
+	DeletedAt        sql.NullTime   `json:"deleted_at"`
+	TotpLastStep     sql.NullInt64  `json:"-"`
+}
+
+type UserIdentity struct {
+	ID        int32          `json:"id"`
+	UserID    int32          `json:"user_id"`
+	Provider  string         `json:"provider"`
+	Subject   string         `json:"subject"`
+	Email     sql.NullString `json:"email"`
+	CreatedAt time.Time      `json:"created_at"`
 }
@@ -8,43 +8,71 @@ package sqlc
 import (
 	"context"
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
+const anonymizeUserPosts = `-- name: AnonymizeUserPosts :exec
+UPDATE posts
+SET user_id = (SELECT g.id FROM users g WHERE g.username = '[deleted user]')
+WHERE user_id = $1
+`
+
+// Hands the user's posts to the "[deleted user]" placeholder before the user is purged
+func (q *Queries) AnonymizeUserPosts(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, anonymizeUserPosts, userID)
+	return err
+}
+
 const createPost = `-- name: CreatePost :one
 WITH new_post AS (
-    INSERT INTO posts (user_id, title, content)
-    VALUES ($1, $2, $3)
-    RETURNING id, user_id, title, content, created_at, updated_at
+    INSERT INTO posts (user_id, title, content, status, published_at, publish_at)
+    VALUES ($1, $2, $3, $4, CASE WHEN $4 = 'published' THEN now() END, $5)
+    RETURNING id, user_id, title, content, created_at, updated_at, status, published_at, publish_at, search_vector, deleted_at
 )
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username
 FROM new_post p
 JOIN users u ON p.user_id = u.id
 `
 
 type CreatePostParams struct {
-	UserID  int32  `json:"user_id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-}
-
-type CreatePostRow struct {
-	ID        int32        `json:"id"`
 	UserID    int32        `json:"user_id"`
 	Title     string       `json:"title"`
 	Content   string       `json:"content"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
-	Username  string       `json:"username"`
+	Status    string       `json:"status"`
+	PublishAt sql.NullTime `json:"publish_at"`
+}
+
+type CreatePostRow struct {
+	ID          int32        `json:"id"`
+	UserID      int32        `json:"user_id"`
+	Title       string       `json:"title"`
+	Content     string       `json:"content"`
+	Status      string       `json:"status"`
+	PublishedAt sql.NullTime `json:"published_at"`
+	PublishAt   sql.NullTime `json:"publish_at"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
+	Username    string       `json:"username"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreatePostRow, error) {
-	row := q.db.QueryRowContext(ctx, createPost, arg.UserID, arg.Title, arg.Content)
+	row := q.db.QueryRowContext(ctx, createPost,
+		arg.UserID,
+		arg.Title,
+		arg.Content,
+		arg.Status,
+		arg.PublishAt,
+	)
 	var i CreatePostRow
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.Title,
 		&i.Content,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Username,
@@ -52,19 +80,14 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreateP
 	return i, err
 }
 
-const deletePost = `-- name: DeletePost :exec
-DELETE FROM posts WHERE id = $1
-`
-
-func (q *Queries) DeletePost(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deletePost, id)
-	return err
-}
-
 const getPostByID = `-- name: GetPostByID :one
-SELECT id, user_id, title, content, created_at, updated_at FROM posts WHERE id = $1 LIMIT 1
+SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, p.status, p.published_at, p.publish_at, p.search_vector, p.deleted_at FROM posts p
+JOIN users u ON u.id = p.user_id
+WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+LIMIT 1
 `
 
+// Posts of deleted users are hidden like deleted posts
 func (q *Queries) GetPostByID(ctx context.Context, id int32) (Post, error) {
 	row := q.db.QueryRowContext(ctx, getPostByID, id)
 	var i Post
@@ -75,35 +98,108 @@ func (q *Queries) GetPostByID(ctx context.Context, id int32) (Post, error) {
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getPostDetail = `-- name: GetPostDetail :one
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
+FROM posts p
+JOIN users u ON p.user_id = u.id
+WHERE p.id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+`
+
+type GetPostDetailRow struct {
+	ID           int32        `json:"id"`
+	UserID       int32        `json:"user_id"`
+	Title        string       `json:"title"`
+	Content      string       `json:"content"`
+	Status       string       `json:"status"`
+	PublishedAt  sql.NullTime `json:"published_at"`
+	PublishAt    sql.NullTime `json:"publish_at"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+	UpdatedAt    sql.NullTime `json:"updated_at"`
+	Username     string       `json:"username"`
+	CommentCount int64        `json:"comment_count"`
+	Tags         []string     `json:"tags"`
+}
+
+func (q *Queries) GetPostDetail(ctx context.Context, id int32) (GetPostDetailRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostDetail, id)
+	var i GetPostDetailRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.CommentCount,
+		pq.Array(&i.Tags),
 	)
 	return i, err
 }
 
 const listPosts = `-- name: ListPosts :many
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
 FROM posts p
 JOIN users u ON p.user_id = u.id
-ORDER BY p.created_at DESC
-LIMIT $1 OFFSET $2
+WHERE p.status = 'published' AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+  AND (cardinality($1::text[]) = 0 OR (
+    SELECT COUNT(*) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+    WHERE pt.post_id = p.id AND t.name = ANY($1::text[])
+  ) >= CASE WHEN $2::bool THEN cardinality($1::text[]) ELSE 1 END)
+  AND ($3::timestamptz IS NULL
+       OR (p.created_at, p.id) < ($3::timestamptz, $4::int))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $5
 `
 
 type ListPostsParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	Tags           []string      `json:"tags"`
+	MatchAll       bool          `json:"match_all"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        sql.NullInt32 `json:"after_id"`
+	PageLimit      int32         `json:"page_limit"`
 }
 
 type ListPostsRow struct {
-	ID        int32        `json:"id"`
-	UserID    int32        `json:"user_id"`
-	Title     string       `json:"title"`
-	Content   string       `json:"content"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
-	Username  string       `json:"username"`
+	ID           int32        `json:"id"`
+	UserID       int32        `json:"user_id"`
+	Title        string       `json:"title"`
+	Content      string       `json:"content"`
+	Status       string       `json:"status"`
+	PublishedAt  sql.NullTime `json:"published_at"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+	UpdatedAt    sql.NullTime `json:"updated_at"`
+	Username     string       `json:"username"`
+	CommentCount int64        `json:"comment_count"`
+	Tags         []string     `json:"tags"`
 }
 
+// An empty tags array disables the filter; match_all requires every tag, otherwise any one is enough.
+// Pages are keyed on (created_at, id): pass the last row of the previous page as after_*.
 func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPostsRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPosts, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listPosts,
+		pq.Array(arg.Tags),
+		arg.MatchAll,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -116,9 +212,13 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 			&i.UserID,
 			&i.Title,
 			&i.Content,
+			&i.Status,
+			&i.PublishedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Username,
+			&i.CommentCount,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -134,32 +234,51 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 }
 
 const listPostsByUser = `-- name: ListPostsByUser :many
-SELECT p.id, p.user_id, p.title, p.content, p.created_at, p.updated_at, u.username
+SELECT p.id, p.user_id, p.title, p.content, p.status, p.published_at, p.publish_at, p.created_at, p.updated_at, u.username,
+    (SELECT COUNT(*) FROM comments c JOIN users cu ON cu.id = c.user_id WHERE c.post_id = p.id AND cu.deleted_at IS NULL) AS comment_count,
+    COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = p.id), '{}')::text[] AS tags
 FROM posts p
 JOIN users u ON p.user_id = u.id
-WHERE p.user_id = $1
-ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3
+WHERE p.user_id = $1 AND p.deleted_at IS NULL AND u.deleted_at IS NULL
+  AND (p.status = 'published' OR $2::bool)
+  AND ($3::timestamptz IS NULL
+       OR (p.created_at, p.id) < ($3::timestamptz, $4::int))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $5
 `
 
 type ListPostsByUserParams struct {
-	UserID int32 `json:"user_id"`
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	UserID             int32         `json:"user_id"`
+	IncludeUnpublished bool          `json:"include_unpublished"`
+	AfterCreatedAt     sql.NullTime  `json:"after_created_at"`
+	AfterID            sql.NullInt32 `json:"after_id"`
+	PageLimit          int32         `json:"page_limit"`
 }
 
 type ListPostsByUserRow struct {
-	ID        int32        `json:"id"`
-	UserID    int32        `json:"user_id"`
-	Title     string       `json:"title"`
-	Content   string       `json:"content"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
-	Username  string       `json:"username"`
+	ID           int32        `json:"id"`
+	UserID       int32        `json:"user_id"`
+	Title        string       `json:"title"`
+	Content      string       `json:"content"`
+	Status       string       `json:"status"`
+	PublishedAt  sql.NullTime `json:"published_at"`
+	PublishAt    sql.NullTime `json:"publish_at"`
+	CreatedAt    sql.NullTime `json:"created_at"`
+	UpdatedAt    sql.NullTime `json:"updated_at"`
+	Username     string       `json:"username"`
+	CommentCount int64        `json:"comment_count"`
+	Tags         []string     `json:"tags"`
 }
 
+// Drafts and archived posts are only included when include_unpublished is set (author viewing own posts)
 func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams) ([]ListPostsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPostsByUser, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listPostsByUser,
+		arg.UserID,
+		arg.IncludeUnpublished,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -172,9 +291,14 @@ func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams
 			&i.UserID,
 			&i.Title,
 			&i.Content,
+			&i.Status,
+			&i.PublishedAt,
+			&i.PublishAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Username,
+			&i.CommentCount,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -189,21 +313,164 @@ func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams
 	return items, nil
 }
 
+const lockPost = `-- name: LockPost :one
+SELECT id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+`
+
+// Locks a live post until the transaction ends, so a snapshot read next is what gets changed
+func (q *Queries) LockPost(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, lockPost, id)
+	err := row.Scan(&id)
+	return id, err
+}
+
+const publishDuePosts = `-- name: PublishDuePosts :many
+UPDATE posts
+SET status = 'published', published_at = publish_at, updated_at = now()
+WHERE id IN (
+    SELECT d.id FROM posts d
+    WHERE d.status = 'draft' AND d.publish_at IS NOT NULL AND d.publish_at <= now() AND d.deleted_at IS NULL
+    ORDER BY d.publish_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id
+`
+
+// SKIP LOCKED lets several API replicas run the scheduler without publishing a post twice
+func (q *Queries) PublishDuePosts(ctx context.Context, limit int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, publishDuePosts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeDeletedPosts = `-- name: PurgeDeletedPosts :execrows
+DELETE FROM posts WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedPosts(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedPosts, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restorePost = `-- name: RestorePost :one
+UPDATE posts
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, user_id, title, content, created_at, updated_at, status, published_at, publish_at, search_vector, deleted_at
+`
+
+func (q *Queries) RestorePost(ctx context.Context, id int32) (Post, error) {
+	row := q.db.QueryRowContext(ctx, restorePost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const softDeletePost = `-- name: SoftDeletePost :execrows
+UPDATE posts
+SET deleted_at = now(), updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeletePost(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeletePost, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
-SET title = $2, content = $3, updated_at = now()
-WHERE id = $1
-RETURNING id, user_id, title, content, created_at, updated_at
+SET title = $1,
+    content = $2,
+    publish_at = COALESCE($3, publish_at),
+    updated_at = now()
+WHERE id = $4 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, created_at, updated_at, status, published_at, publish_at, search_vector, deleted_at
 `
 
 type UpdatePostParams struct {
-	ID      int32  `json:"id"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
+	Title     string       `json:"title"`
+	Content   string       `json:"content"`
+	PublishAt sql.NullTime `json:"publish_at"`
+	ID        int32        `json:"id"`
 }
 
+// A NULL publish_at keeps the current schedule
 func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, updatePost, arg.ID, arg.Title, arg.Content)
+	row := q.db.QueryRowContext(ctx, updatePost,
+		arg.Title,
+		arg.Content,
+		arg.PublishAt,
+		arg.ID,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updatePostStatus = `-- name: UpdatePostStatus :one
+UPDATE posts
+SET status = $1,
+    published_at = CASE WHEN $1 = 'published' THEN COALESCE(published_at, now()) ELSE published_at END,
+    updated_at = now()
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, created_at, updated_at, status, published_at, publish_at, search_vector, deleted_at
+`
+
+type UpdatePostStatusParams struct {
+	Status string `json:"status"`
+	ID     int32  `json:"id"`
+}
+
+func (q *Queries) UpdatePostStatus(ctx context.Context, arg UpdatePostStatusParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updatePostStatus, arg.Status, arg.ID)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -212,6 +479,11 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.Content,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Status,
+		&i.PublishedAt,
+		&i.PublishAt,
+		&i.SearchVector,
+		&i.DeletedAt,
 	)
 	return i, err
 }
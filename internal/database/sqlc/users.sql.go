@@ -7,18 +7,110 @@ package sqlc
 
 import (
 	"context"
+	"database/sql"
 )
 
+const adminUpdateUser = `-- name: AdminUpdateUser :one
+UPDATE users
+SET username = COALESCE($1, username),
+    email = COALESCE($2, email),
+    email_verified_at = CASE WHEN $2::text IS NULL OR $2::text = email THEN email_verified_at END,
+    role = COALESCE($3, role),
+    updated_at = now()
+WHERE id = $4 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+type AdminUpdateUserParams struct {
+	Username sql.NullString `json:"username"`
+	Email    sql.NullString `json:"email"`
+	Role     sql.NullString `json:"role"`
+	ID       int32          `json:"id"`
+}
+
+// NULL arguments keep the current value; a changed email has to be verified again
+func (q *Queries) AdminUpdateUser(ctx context.Context, arg AdminUpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, adminUpdateUser,
+		arg.Username,
+		arg.Email,
+		arg.Role,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const changeUserEmail = `-- name: ChangeUserEmail :one
+UPDATE users
+SET email = $2, email_verified_at = now(), updated_at = now()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+type ChangeUserEmailParams struct {
+	ID    int32  `json:"id"`
+	Email string `json:"email"`
+}
+
+// The new address was confirmed through a verification link
+func (q *Queries) ChangeUserEmail(ctx context.Context, arg ChangeUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, changeUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, email, password_hash, role)
 VALUES ($1, $2, $3, $4)
-RETURNING id, username, email, password_hash, role, created_at, updated_at
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
 `
 
 type CreateUserParams struct {
 	Username     string `json:"username"`
 	Email        string `json:"email"`
-	PasswordHash string `json:"password_hash"`
+	PasswordHash string `json:"-"`
 	Role         string `json:"role"`
 }
 
@@ -38,23 +130,61 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
+const deleteUser = `-- name: DeleteUser :execrows
 DELETE FROM users WHERE id = $1
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
+func (q *Queries) DeleteUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :exec
+UPDATE users
+SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = now()
+WHERE id = $1
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, disableUserTOTP, id)
+	return err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :exec
+UPDATE users
+SET totp_enabled_at = now(), updated_at = now()
+WHERE id = $1 AND totp_secret IS NOT NULL
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, enableUserTOTP, id)
 	return err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users WHERE email = $1 LIMIT 1
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step FROM users WHERE email = $1 AND deleted_at IS NULL AND username <> '[deleted user]' LIMIT 1
 `
 
+// The "[deleted user]" placeholder is never found, so it cannot sign in or reset its password
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
 	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
 	var i User
@@ -66,12 +196,24 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users WHERE id = $1 LIMIT 1
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
@@ -85,16 +227,234 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (User, error) {
 		&i.Role,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
 	)
 	return i, err
 }
 
+const listPurgeableUsers = `-- name: ListPurgeableUsers :many
+SELECT id FROM users
+WHERE deleted_at < $1
+ORDER BY deleted_at
+LIMIT $2
+`
+
+type ListPurgeableUsersParams struct {
+	DeletedAt sql.NullTime `json:"deleted_at"`
+	Limit     int32        `json:"limit"`
+}
+
+// Users deleted before the cutoff, oldest first
+func (q *Queries) ListPurgeableUsers(ctx context.Context, arg ListPurgeableUsersParams) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listPurgeableUsers, arg.DeletedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT id, username, email, password_hash, role, created_at, updated_at FROM users ORDER BY id
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step FROM users
+WHERE id > $1 AND deleted_at IS NULL AND username <> '[deleted user]'
+ORDER BY id
+LIMIT $2
+`
+
+type ListUsersParams struct {
+	AfterID   int32 `json:"after_id"`
+	PageLimit int32 `json:"page_limit"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.AfterID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.PasswordHash,
+			&i.Role,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.SuspendedAt,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.TokensRevokedAt,
+			&i.DeletedAt,
+			&i.TotpLastStep,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, now()), updated_at = now()
+WHERE id = $1
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const restoreUser = `-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, restoreUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE users
+SET tokens_revoked_at = now()
+WHERE id = $1
+`
+
+// Access tokens issued before tokens_revoked_at are rejected
+func (q *Queries) RevokeUserTokens(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, id)
+	return err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step FROM users
+WHERE ($1::text IS NULL
+       OR username ILIKE '%' || $1::text || '%' ESCAPE '\'
+       OR email ILIKE '%' || $1::text || '%' ESCAPE '\'
+       OR display_name ILIKE '%' || $1::text || '%' ESCAPE '\')
+  AND username <> '[deleted user]'
+  AND ($2::text IS NULL OR role = $2::text)
+  AND (deleted_at IS NOT NULL) = ($3::text IS NOT DISTINCT FROM 'deleted')
+  AND ($3::text IS NULL OR $3::text = 'deleted'
+       OR ($3::text = 'suspended') = (suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > now())))
+ORDER BY
+    CASE WHEN $4::text = 'created_at' THEN created_at END ASC,
+    CASE WHEN $4::text = '-created_at' THEN created_at END DESC,
+    CASE WHEN $4::text = 'username' THEN username END ASC,
+    CASE WHEN $4::text = '-username' THEN username END DESC,
+    id
+LIMIT $5 OFFSET $6
 `
 
-func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers)
+type SearchUsersParams struct {
+	Query      sql.NullString `json:"query"`
+	Role       sql.NullString `json:"role"`
+	Status     sql.NullString `json:"status"`
+	Sort       string         `json:"sort"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+// Admin listing. status is "active", "suspended" or "deleted" (deleted users are
+// only listed with that status); sort is created_at, -created_at, username or
+// -username, anything else orders by id
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers,
+		arg.Query,
+		arg.Role,
+		arg.Status,
+		arg.Sort,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +470,18 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 			&i.Role,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.DisplayName,
+			&i.Bio,
+			&i.AvatarUrl,
+			&i.SuspendedAt,
+			&i.SuspendedUntil,
+			&i.SuspensionReason,
+			&i.TokensRevokedAt,
+			&i.DeletedAt,
+			&i.TotpLastStep,
 		); err != nil {
 			return nil, err
 		}
@@ -123,3 +495,236 @@ func (q *Queries) ListUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :exec
+UPDATE users
+SET totp_secret = $2, totp_enabled_at = NULL, updated_at = now()
+WHERE id = $1
+`
+
+type SetUserTOTPSecretParams struct {
+	ID         int32          `json:"id"`
+	TotpSecret sql.NullString `json:"-"`
+}
+
+// Starts (or restarts) enrollment; 2FA stays off until EnableUserTOTP
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) error {
+	_, err := q.db.ExecContext(ctx, setUserTOTPSecret, arg.ID, arg.TotpSecret)
+	return err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :execrows
+UPDATE users
+SET deleted_at = now(), updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, softDeleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = now(), suspended_until = $2, suspension_reason = $3, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+type SuspendUserParams struct {
+	ID               int32          `json:"id"`
+	SuspendedUntil   sql.NullTime   `json:"suspended_until"`
+	SuspensionReason sql.NullString `json:"suspension_reason"`
+}
+
+func (q *Queries) SuspendUser(ctx context.Context, arg SuspendUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, arg.ID, arg.SuspendedUntil, arg.SuspensionReason)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, suspended_until = NULL, suspension_reason = NULL, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           int32  `json:"id"`
+	PasswordHash string `json:"-"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET username = COALESCE($1, username),
+    display_name = CASE WHEN $2::text IS NULL THEN display_name ELSE NULLIF($2::text, '') END,
+    bio = CASE WHEN $3::text IS NULL THEN bio ELSE NULLIF($3::text, '') END,
+    avatar_url = CASE WHEN $4::text IS NULL THEN avatar_url ELSE NULLIF($4::text, '') END,
+    updated_at = now()
+WHERE id = $5 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+type UpdateUserProfileParams struct {
+	Username    sql.NullString `json:"username"`
+	DisplayName sql.NullString `json:"display_name"`
+	Bio         sql.NullString `json:"bio"`
+	AvatarUrl   sql.NullString `json:"avatar_url"`
+	ID          int32          `json:"id"`
+}
+
+// NULL arguments keep the current value; an empty string clears an optional field
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.Username,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role = $2, updated_at = now()
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, role, created_at, updated_at, email_verified_at, totp_secret, totp_enabled_at, display_name, bio, avatar_url, suspended_at, suspended_until, suspension_reason, tokens_revoked_at, deleted_at, totp_last_step
+`
+
+type UpdateUserRoleParams struct {
+	ID   int32  `json:"id"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Role,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.SuspendedAt,
+		&i.SuspendedUntil,
+		&i.SuspensionReason,
+		&i.TokensRevokedAt,
+		&i.DeletedAt,
+		&i.TotpLastStep,
+	)
+	return i, err
+}
+
+const useUserTOTPStep = `-- name: UseUserTOTPStep :execrows
+UPDATE users
+SET totp_last_step = $1
+WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+`
+
+type UseUserTOTPStepParams struct {
+	Step int64 `json:"step"`
+	ID   int32 `json:"id"`
+}
+
+// Records an accepted TOTP step; no row is updated when the step was already used
+func (q *Queries) UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useUserTOTPStep, arg.Step, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
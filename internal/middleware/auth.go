@@ -2,47 +2,179 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	apperrors "my_project/internal/app/errors"
 	"my_project/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/time/rate"
 )
 
-// AuthMiddleware verifies JWT and stores user context
+// APIKeyIdentity is who a request made with an API key acts as
+type APIKeyIdentity struct {
+	KeyID         int32
+	UserID        int32
+	Role          string
+	EmailVerified bool
+	Scopes        []string
+}
+
+// APIKeyAuthenticator resolves the key sent as "Authorization: ApiKey <key>"
+type APIKeyAuthenticator func(ctx context.Context, key string) (APIKeyIdentity, error)
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// SetAPIKeyAuthenticator enables API key authentication; until it is called only JWTs are accepted
+func SetAPIKeyAuthenticator(a APIKeyAuthenticator) {
+	apiKeyAuthenticator = a
+}
+
+// AccountState is the current state of a JWT's user; it overrides the role and
+// email_verified claims, which may be stale
+type AccountState struct {
+	Role          string
+	EmailVerified bool
+}
+
+// AccountChecker rejects access tokens of users that were suspended, deleted or
+// signed out everywhere after the token was issued
+type AccountChecker func(ctx context.Context, userID int32, issuedAt time.Time) (AccountState, error)
+
+var accountChecker AccountChecker
+
+// SetAccountChecker makes every JWT be checked against the current account state;
+// until it is called a token stays valid until it expires
+func SetAccountChecker(check AccountChecker) {
+	accountChecker = check
+}
+
+// AuthMiddleware verifies a JWT or an API key and stores user context
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		auth := c.GetHeader("Authorization")
-		if !strings.HasPrefix(auth, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing or invalid token"})
+		found, err := authenticateRequest(c)
+		if !found {
+			abortWithError(c, apperrors.NewUnauthorizedError("missing or invalid token"))
 			return
 		}
-
-		tokenStr := strings.TrimPrefix(auth, "Bearer ")
-		claims, err := utils.ParseToken(tokenStr)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			var appErr *apperrors.AppError
+			if !errors.As(err, &appErr) {
+				appErr = apperrors.NewUnauthorizedError(err.Error())
+			}
+			abortWithError(c, appErr)
 			return
 		}
+		c.Next()
+	}
+}
 
-		userID, err := extractUserID(claims)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token payload"})
-			return
+// OptionalAuthMiddleware sets the user context when valid credentials are sent,
+// but lets anonymous requests through (public routes that show more to owners)
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, _ = authenticateRequest(c)
+		c.Next()
+	}
+}
+
+// authenticateRequest checks the Authorization header; found is false when it
+// holds no credentials of a supported kind
+func authenticateRequest(c *gin.Context) (found bool, err error) {
+	auth := c.GetHeader("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		return true, authenticate(c, token)
+	}
+	if key, ok := strings.CutPrefix(auth, "ApiKey "); ok && apiKeyAuthenticator != nil {
+		return true, authenticateAPIKey(c, key)
+	}
+	return false, nil
+}
+
+// authenticate validates the token and stores userID, role and claims on the context
+func authenticate(c *gin.Context, tokenStr string) error {
+	claims, err := utils.ParseToken(tokenStr)
+	if err != nil {
+		return fmt.Errorf("invalid or expired token")
+	}
+
+	userID, err := extractUserID(claims)
+	if err != nil {
+		return fmt.Errorf("invalid token payload")
+	}
+
+	account, err := checkAccount(c, userID, claims)
+	if err != nil {
+		return err
+	}
+
+	c.Set("userID", userID)
+	c.Set("role", account.Role)
+	c.Set("emailVerified", account.EmailVerified)
+	c.Set("user", claims)
+	return nil
+}
+
+// checkAccount runs the AccountChecker for a parsed access token; without one
+// the token's own claims are trusted
+func checkAccount(c *gin.Context, userID int32, claims jwt.MapClaims) (AccountState, error) {
+	if accountChecker == nil {
+		role, _ := claims["role"].(string)
+		emailVerified, _ := claims["email_verified"].(bool)
+		return AccountState{Role: role, EmailVerified: emailVerified}, nil
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return AccountState{}, fmt.Errorf("invalid token payload")
+	}
+
+	account, err := accountChecker(c.Request.Context(), userID, issuedAt.Time)
+	var appErr *apperrors.AppError
+	if err != nil && !errors.As(err, &appErr) {
+		log.Printf("[RequestID=%s] account check failed: %v", c.GetString("RequestID"), err)
+		return AccountState{}, apperrors.NewInternalError("failed to verify account")
+	}
+	return account, err
+}
+
+// authenticateAPIKey stores the key owner's context, plus the key id and scopes
+// that RequirePermission and RequireSession look at
+func authenticateAPIKey(c *gin.Context, key string) error {
+	identity, err := resolveAPIKey(c, key)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) {
+			log.Printf("[RequestID=%s] api key lookup failed: %v", c.GetString("RequestID"), err)
 		}
+		return fmt.Errorf("invalid or expired api key")
+	}
 
-		c.Set("userID", userID)
-		c.Set("user", claims)
-		c.Next()
+	c.Set("userID", identity.UserID)
+	c.Set("role", identity.Role)
+	c.Set("emailVerified", identity.EmailVerified)
+	c.Set("apiKeyID", identity.KeyID)
+	c.Set("apiKeyScopes", identity.Scopes)
+	return nil
+}
+
+// resolveAPIKey looks the key up once per request; the rate limiter and
+// AuthMiddleware both need it
+func resolveAPIKey(c *gin.Context, key string) (APIKeyIdentity, error) {
+	if cached, ok := c.Get("apiKeyIdentity"); ok {
+		return cached.(APIKeyIdentity), nil
+	}
+	identity, err := apiKeyAuthenticator(c.Request.Context(), key)
+	if err != nil {
+		return APIKeyIdentity{}, err
 	}
+	c.Set("apiKeyIdentity", identity)
+	return identity, nil
 }
 
 func extractUserID(claims jwt.MapClaims) (int32, error) {
@@ -87,7 +219,7 @@ func TimeoutMiddleware(duration time.Duration) gin.HandlerFunc {
 
 		select {
 		case <-ctx.Done():
-			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timeout"})
+			abortWithError(c, apperrors.NewTimeoutError("request timeout"))
 		case <-done:
 		}
 	}
@@ -130,39 +262,11 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware: giá»›i háº¡n sá»‘ request
-func RateLimitMiddleware(rps int, per time.Duration) gin.HandlerFunc {
-	limiter := rate.NewLimiter(rate.Every(per/time.Duration(rps)), rps)
-
-	return func(c *gin.Context) {
-		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
-			return
-		}
-		c.Next()
-	}
-}
-
-// ErrorHandlerMiddleware: báº¯t lá»—i chung
-func ErrorHandlerMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("panic recovered: %v", err)
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
-					"error": "internal server error",
-				})
-			}
-		}()
-		c.Next()
-	}
-}
-
 // ValidationMiddleware: vĂ­ dá»¥ validate body rá»—ng
 func ValidationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.ContentLength == 0 {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "empty request body"})
+			abortWithError(c, apperrors.NewBadRequestError("empty request body"))
 			return
 		}
 		c.Next()
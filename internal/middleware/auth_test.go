@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveAdminRoute calls an admin-only route with token while checker stands in
+// for the user service
+func serveAdminRoute(t *testing.T, token string, checker AccountChecker) int {
+	t.Helper()
+	SetAccountChecker(checker)
+	t.Cleanup(func() { SetAccountChecker(nil) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandlerMiddleware())
+	router.GET("/admin", AuthMiddleware(), RequirePermission(permissions.UsersManage), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthMiddlewareUsesCurrentRole(t *testing.T) {
+	// Issued while the user was still an admin
+	token, err := utils.CreateToken(7, "demoted@example.com", permissions.RoleAdmin, true)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	withRole := func(role string) AccountChecker {
+		return func(ctx context.Context, userID int32, issuedAt time.Time) (AccountState, error) {
+			return AccountState{Role: role, EmailVerified: true}, nil
+		}
+	}
+
+	if got := serveAdminRoute(t, token, withRole(permissions.RoleAdmin)); got != http.StatusOK {
+		t.Errorf("admin: expected %d, got %d", http.StatusOK, got)
+	}
+	if got := serveAdminRoute(t, token, withRole(permissions.RoleUser)); got != http.StatusForbidden {
+		t.Errorf("demoted admin: expected %d, got %d", http.StatusForbidden, got)
+	}
+}
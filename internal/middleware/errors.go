@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	apperrors "my_project/internal/app/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ErrorHandlerMiddleware renders the last error recorded with c.Error, and any
+// recovered panic, as an ErrorResponse. Errors that are not AppErrors become a
+// generic 500 so internal details are never sent to clients.
+func ErrorHandlerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("[RequestID=%s] panic recovered: %v", c.GetString("RequestID"), err)
+				renderError(c, apperrors.NewInternalError("internal server error"))
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		renderError(c, c.Errors.Last().Err)
+	}
+}
+
+// NotFoundHandler answers unknown routes with the standard error body
+func NotFoundHandler(c *gin.Context) {
+	abortWithError(c, apperrors.NewNotFoundError("route not found"))
+}
+
+// abortWithError stops the chain and leaves the error for ErrorHandlerMiddleware
+func abortWithError(c *gin.Context, err *apperrors.AppError) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+func renderError(c *gin.Context, err error) {
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		appErr = apperrors.NewInternalError("internal server error").Wrap(err)
+	}
+	if appErr.Status >= http.StatusInternalServerError && appErr.Err != nil {
+		log.Printf("[RequestID=%s] %s: %v", c.GetString("RequestID"), appErr.Message, appErr.Err)
+	}
+
+	c.AbortWithStatusJSON(appErr.Status, ErrorResponse{
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		Details:   appErr.Details,
+		RequestID: c.GetString("RequestID"),
+	})
+}
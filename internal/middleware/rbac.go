@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission allows the request only if the caller's role grants every
+// listed permission and, for API keys, the key has the matching scope. It must
+// run after AuthMiddleware, which sets "role".
+func RequirePermission(perms ...permissions.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		scopes, isAPIKey := c.Get("apiKeyScopes")
+		write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead
+
+		for _, perm := range perms {
+			if !permissions.Has(role, perm) {
+				abortWithError(c, apperrors.NewForbiddenError("insufficient permissions").
+					WithDetails(gin.H{"permission": perm}))
+				return
+			}
+			if need := permissions.ScopeFor(perm, write); isAPIKey && !permissions.HasScope(scopes.([]string), need) {
+				abortWithError(c, apperrors.NewForbiddenError("api key is missing a scope").
+					WithDetails(gin.H{"scope": need}))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects API keys on routes that manage the account itself,
+// such as credentials and keys. It must run after AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			abortWithError(c, apperrors.NewForbiddenError("api keys cannot be used for this request"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmail rejects callers whose token says their email is not verified
+// yet. It is a no-op when required is false, and must run after AuthMiddleware.
+func RequireVerifiedEmail(required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if required && !c.GetBool("emailVerified") {
+			abortWithError(c, apperrors.NewForbiddenError("verify your email address first"))
+			return
+		}
+		c.Next()
+	}
+}
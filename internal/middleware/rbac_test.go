@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"my_project/internal/app/permissions"
+
+	"github.com/gin-gonic/gin"
+)
+
+// grants is the expected role × permission matrix, written out independently of
+// permissions.For so a change to either side shows up here
+var grants = map[permissions.Permission][]string{
+	permissions.UsersCreate:      {permissions.RoleAdmin},
+	permissions.UsersDelete:      {permissions.RoleAdmin},
+	permissions.UsersManageRoles: {permissions.RoleAdmin},
+	permissions.UsersUnlock:      {permissions.RoleAdmin},
+	permissions.UsersViewPrivate: {permissions.RoleAdmin},
+	permissions.UsersManage:      {permissions.RoleAdmin},
+	permissions.AuditRead:        {permissions.RoleAdmin},
+	permissions.PostsCreate:      {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.PostsUpdate:      {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.PostsDelete:      {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.PostsModerate:    {permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.CommentsCreate:   {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.CommentsUpdate:   {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.CommentsDelete:   {permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin},
+	permissions.CommentsModerate: {permissions.RoleModerator, permissions.RoleAdmin},
+}
+
+// serveWithRole runs RequirePermission(perm) for a caller whose context holds role
+func serveWithRole(role string, perm permissions.Permission) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandlerMiddleware())
+	router.POST("/", func(c *gin.Context) {
+		if role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	}, RequirePermission(perm), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	return w.Code
+}
+
+func TestRequirePermission(t *testing.T) {
+	roles := []string{permissions.RoleUser, permissions.RoleModerator, permissions.RoleAdmin, "", "unknown"}
+
+	for perm, granted := range grants {
+		for _, role := range roles {
+			want := http.StatusForbidden
+			for _, r := range granted {
+				if r == role {
+					want = http.StatusOK
+				}
+			}
+			t.Run(string(perm)+"/"+role, func(t *testing.T) {
+				if got := serveWithRole(role, perm); got != want {
+					t.Errorf("role %q, permission %s: expected %d, got %d", role, perm, want, got)
+				}
+			})
+		}
+	}
+
+	// Every permission a role has must be covered by the matrix above
+	for _, role := range roles[:3] {
+		for _, perm := range permissions.For(role) {
+			if _, ok := grants[perm]; !ok {
+				t.Errorf("permission %s of role %s is missing from the test matrix", perm, role)
+			}
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Package oidc is a small OpenID Connect relying party: authorization code flow
+// with PKCE, discovery, and ID token verification against the provider's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval limits how often an unknown key id makes us refetch the JWKS
+const jwksRefreshInterval = time.Minute
+
+// Config describes one identity provider
+type Config struct {
+	// Name is used in URLs (/auth/oidc/:provider) and stored with linked identities
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the ID token claims used to find or create the local account
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Provider talks to one identity provider. Discovery metadata and signing keys
+// are fetched on first use and cached.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *metadata
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a Provider; a nil client means http.DefaultClient
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Name returns the configured provider name
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// AuthCodeURL is where the user is sent to sign in. verifier is the PKCE code
+// verifier; only its S256 challenge leaves the server here.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Authenticate exchanges the authorization code and returns the verified ID token claims
+func (p *Provider) Authenticate(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	rawIDToken, err := p.exchange(ctx, code, verifier)
+	if err != nil {
+		return Claims{}, err
+	}
+	return p.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+func (p *Provider) exchange(ctx context.Context, code, verifier string) (string, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &body)
+	if err != nil {
+		return "", fmt.Errorf("oidc %s: token request: %w", p.cfg.Name, err)
+	}
+	if status != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc %s: token request failed (%d): %s %s", p.cfg.Name, status, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("oidc %s: token response has no id_token", p.cfg.Name)
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (Claims, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var claims idTokenClaims
+	_, err = jwt.ParseWithClaims(rawIDToken, &claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("oidc %s: invalid id token: %w", p.cfg.Name, err)
+	}
+	if claims.Nonce != nonce {
+		return Claims{}, fmt.Errorf("oidc %s: id token nonce mismatch", p.cfg.Name)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("oidc %s: id token has no subject", p.cfg.Name)
+	}
+
+	return Claims{
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     bool(claims.EmailVerified),
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string    `json:"nonce"`
+	Email             string    `json:"email"`
+	EmailVerified     flexibool `json:"email_verified"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+}
+
+// flexibool accepts true and "true"; some providers send email_verified as a string
+type flexibool bool
+
+func (b *flexibool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = flexibool(s == "true")
+	return nil
+}
+
+// metadata returns the discovery document, fetching it on first use
+func (p *Provider) metadata(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	status, err := p.doJSON(req, &meta)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("oidc %s: discovery failed (%d): %v", p.cfg.Name, status, err)
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc %s: discovery issuer %q does not match %q", p.cfg.Name, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc %s: discovery document is incomplete", p.cfg.Name)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the signing key with the given id, refetching the JWKS when the
+// provider may have rotated its keys
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	meta, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwkSet
+	status, err := p.doJSON(req, &set)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("fetching jwks failed (%d): %v", status, err)
+	}
+	p.keys = set.publicKeys()
+	p.keysFetched = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a key by id; tokens without a kid match a JWKS with a single key
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) doJSON(req *http.Request, v any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return resp.StatusCode, errors.New("response is not valid JSON")
+	}
+	return resp.StatusCode, nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
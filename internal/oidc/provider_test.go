@@ -0,0 +1,106 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"my_project/internal/oidc/oidctest"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+
+	server := oidctest.NewServer("client", "secret")
+	t.Cleanup(server.Close)
+
+	provider := NewProvider(Config{
+		Name:         "stub",
+		Issuer:       server.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+	}, server.Client())
+	return provider, server
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newTestProvider(t)
+	server.SetUser(oidctest.User{Subject: "user-1", Email: "a@example.com", EmailVerified: true, Name: "A"})
+
+	authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-that-is-long-enough-for-pkce-000000")
+	if err != nil {
+		t.Fatalf("failed to build auth url: %v", err)
+	}
+	parsed, _ := url.Parse(authURL)
+	if parsed.Query().Get("code_challenge") != CodeChallenge("verifier-that-is-long-enough-for-pkce-000000") {
+		t.Errorf("expected the S256 challenge in %s", authURL)
+	}
+
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize failed: %v", err)
+	}
+	if state != "state-1" {
+		t.Errorf("expected state to round-trip, got %q", state)
+	}
+
+	claims, err := provider.Authenticate(ctx, code, "verifier-that-is-long-enough-for-pkce-000000", "nonce-1")
+	if err != nil {
+		t.Fatalf("authenticate failed: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "a@example.com" || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// Codes are single use
+	if _, err := provider.Authenticate(ctx, code, "verifier-that-is-long-enough-for-pkce-000000", "nonce-1"); err == nil {
+		t.Errorf("expected a reused code to be rejected")
+	}
+}
+
+func TestAuthenticateRejectsWrongVerifierAndNonce(t *testing.T) {
+	ctx := context.Background()
+	provider, server := newTestProvider(t)
+	server.SetUser(oidctest.User{Subject: "user-1"})
+
+	authURL, err := provider.AuthCodeURL(ctx, "s", "nonce-1", "right-verifier")
+	if err != nil {
+		t.Fatalf("failed to build auth url: %v", err)
+	}
+	code, _, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("authorize failed: %v", err)
+	}
+	if _, err := provider.Authenticate(ctx, code, "wrong-verifier", "nonce-1"); err == nil {
+		t.Errorf("expected a wrong PKCE verifier to be rejected")
+	}
+
+	authURL, _ = provider.AuthCodeURL(ctx, "s", "nonce-1", "right-verifier")
+	code, _, _ = server.Authorize(authURL)
+	_, err = provider.Authenticate(ctx, code, "right-verifier", "nonce-2")
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("expected a nonce mismatch, got %v", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAudience(t *testing.T) {
+	ctx := context.Background()
+	_, server := newTestProvider(t)
+	server.SetUser(oidctest.User{Subject: "user-1"})
+
+	other := NewProvider(Config{Name: "other", Issuer: server.Issuer(), ClientID: "client", ClientSecret: "secret", RedirectURL: "http://localhost/callback"}, server.Client())
+	authURL, _ := other.AuthCodeURL(ctx, "s", "n", "v")
+	code, _, _ := server.Authorize(authURL)
+	rawIDToken, err := other.exchange(ctx, code, "v")
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+
+	mismatched := NewProvider(Config{Name: "mismatched", Issuer: server.Issuer(), ClientID: "someone-else"}, server.Client())
+	if _, err := mismatched.VerifyIDToken(ctx, rawIDToken, "n"); err == nil {
+		t.Errorf("expected a token for another client to be rejected")
+	}
+}
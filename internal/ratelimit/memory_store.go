@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory. Limits are per instance, so it
+// suits single-instance deployments and tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, p Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	tat := s.buckets[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(p.interval())
+	if next.Sub(now) > p.Window {
+		return p.result(false, tat, now), nil
+	}
+	s.buckets[key] = next
+	return p.result(true, next, now), nil
+}
+
+func (s *MemoryStore) Evict(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var evicted int64
+	for key, tat := range s.buckets {
+		if !tat.After(now) {
+			delete(s.buckets, key)
+			evicted++
+		}
+	}
+	return evicted, nil
+}
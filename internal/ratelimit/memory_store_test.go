@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreBurstAndRefill(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Name: "test", Limit: 3, Window: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		res, err := store.Take(ctx, "a", policy)
+		if err != nil {
+			t.Fatalf("take failed: %v", err)
+		}
+		if !res.Allowed || res.Remaining != i {
+			t.Fatalf("expected allowed with %d remaining, got %+v", i, res)
+		}
+	}
+
+	res, _ := store.Take(ctx, "a", policy)
+	if res.Allowed {
+		t.Fatalf("expected the fourth request in the burst to be refused")
+	}
+	if res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("expected retry after 1s and reset in 3s, got %+v", res)
+	}
+
+	// Other keys have their own bucket
+	if res, _ := store.Take(ctx, "b", policy); !res.Allowed {
+		t.Errorf("expected another key to be allowed")
+	}
+
+	// One request's worth refills per interval
+	now = now.Add(time.Second)
+	if res, _ := store.Take(ctx, "a", policy); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected one request after refill, got %+v", res)
+	}
+}
+
+func TestMemoryStoreEvict(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	_, _ = store.Take(ctx, "short", Policy{Limit: 10, Window: 10 * time.Second})
+	_, _ = store.Take(ctx, "long", Policy{Limit: 1, Window: time.Hour})
+
+	now = now.Add(time.Minute)
+	evicted, err := store.Evict(ctx)
+	if err != nil {
+		t.Fatalf("evict failed: %v", err)
+	}
+	if evicted != 1 {
+		t.Fatalf("expected 1 idle bucket evicted, got %d", evicted)
+	}
+	if _, ok := store.buckets["long"]; !ok {
+		t.Errorf("expected the bucket that is still draining to be kept")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("read", "100/1m")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if p != (Policy{Name: "read", Limit: 100, Window: time.Minute}) {
+		t.Errorf("unexpected policy %+v", p)
+	}
+
+	if p, err := ParsePolicy("read", "off"); err != nil || p.Enabled() {
+		t.Errorf("expected a disabled policy, got %+v, %v", p, err)
+	}
+
+	for _, spec := range []string{"100", "x/1m", "10/soon", "10/0s"} {
+		if _, err := ParsePolicy("read", spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"my_project/internal/database/sqlc"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every API
+// instance shares the same limits. Timing uses the database clock.
+type PostgresStore struct {
+	q *sqlc.Queries
+}
+
+// NewPostgresStore creates a PostgresStore
+func NewPostgresStore(q *sqlc.Queries) *PostgresStore {
+	return &PostgresStore{q: q}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	row, err := s.q.TakeRateLimitToken(ctx, sqlc.TakeRateLimitTokenParams{
+		Key:          key,
+		IntervalSecs: p.interval().Seconds(),
+		WindowSecs:   p.Window.Seconds(),
+	})
+	if err == nil {
+		return p.result(true, row.Tat, row.Now), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	bucket, err := s.q.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	return p.result(false, bucket.Tat, bucket.Now), nil
+}
+
+func (s *PostgresStore) Evict(ctx context.Context) (int64, error) {
+	return s.q.DeleteIdleRateLimitBuckets(ctx)
+}
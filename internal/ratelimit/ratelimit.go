@@ -0,0 +1,84 @@
+// Package ratelimit implements keyed rate limiting with the generic cell rate
+// algorithm (GCRA), a token bucket that only needs one timestamp per key.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests per Window for each key, all of which may come in a burst
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// Enabled reports whether the policy limits anything
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Window > 0
+}
+
+// interval is the time one request "costs"
+func (p Policy) interval() time.Duration {
+	return p.Window / time.Duration(p.Limit)
+}
+
+// Result describes the state of a key after a request was counted or refused
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the bucket is full again; RetryAfter when a refused request would fit
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps the bucket of every key. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take counts one request for key under p, unless the bucket is empty
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+	// Evict drops buckets that are full again, since they hold no information
+	Evict(ctx context.Context) (int64, error)
+}
+
+// result builds the Result from the key's theoretical arrival time (tat) after the request
+func (p Policy) result(allowed bool, tat, now time.Time) Result {
+	ahead := max(tat.Sub(now), 0)
+	r := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: min(max(int((p.Window-ahead)/p.interval()), 0), p.Limit),
+		Reset:     ahead,
+	}
+	if !allowed {
+		r.RetryAfter = max(ahead+p.interval()-p.Window, 0)
+	}
+	return r
+}
+
+// ParsePolicy reads a policy written as "<limit>/<window>", e.g. "100/1m".
+// "off" and "0" give a disabled policy.
+func ParsePolicy(name, spec string) (Policy, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" || spec == "0" {
+		return Policy{Name: name}, nil
+	}
+
+	limitStr, windowStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q: expected <limit>/<window>", spec)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid limit", spec)
+	}
+	window, err := time.ParseDuration(windowStr)
+	if err != nil || window <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: invalid window", spec)
+	}
+	return Policy{Name: name, Limit: limit, Window: window}, nil
+}
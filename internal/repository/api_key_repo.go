@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// APIKeyRepository defines the persistence operations for personal API keys
+type APIKeyRepository interface {
+	Create(ctx context.Context, arg sqlc.CreateAPIKeyParams) (sqlc.ApiKey, error)
+	ListByUser(ctx context.Context, userID int32) ([]sqlc.ApiKey, error)
+	GetByHash(ctx context.Context, keyHash string) (sqlc.ApiKey, error)
+	Touch(ctx context.Context, id int32) error
+	Delete(ctx context.Context, userID, id int32) error
+}
+
+type apiKeyRepo struct {
+	q *sqlc.Queries
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository implementation
+func NewAPIKeyRepository(q *sqlc.Queries) APIKeyRepository {
+	return &apiKeyRepo{q: q}
+}
+
+func (r *apiKeyRepo) Create(ctx context.Context, arg sqlc.CreateAPIKeyParams) (sqlc.ApiKey, error) {
+	return r.q.CreateAPIKey(ctx, arg)
+}
+
+func (r *apiKeyRepo) ListByUser(ctx context.Context, userID int32) ([]sqlc.ApiKey, error) {
+	return r.q.ListAPIKeysByUser(ctx, userID)
+}
+
+func (r *apiKeyRepo) GetByHash(ctx context.Context, keyHash string) (sqlc.ApiKey, error) {
+	return r.q.GetAPIKeyByHash(ctx, keyHash)
+}
+
+// Touch records that the key was just used
+func (r *apiKeyRepo) Touch(ctx context.Context, id int32) error {
+	return r.q.TouchAPIKey(ctx, id)
+}
+
+// Delete removes one of the user's keys; sql.ErrNoRows means the user has no such key
+func (r *apiKeyRepo) Delete(ctx context.Context, userID, id int32) error {
+	n, err := r.q.DeleteAPIKey(ctx, sqlc.DeleteAPIKeyParams{ID: id, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
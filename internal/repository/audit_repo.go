@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+
+	"my_project/internal/database/sqlc"
+)
+
+// AuditRepository defines the persistence operations for the audit log. Events can
+// only be added; the table rejects updates and deletes.
+type AuditRepository interface {
+	Create(ctx context.Context, arg sqlc.CreateAuditEventParams) (sqlc.AuditEvent, error)
+	List(ctx context.Context, arg sqlc.ListAuditEventsParams) ([]sqlc.AuditEvent, error)
+}
+
+type auditRepo struct {
+	q *sqlc.Queries
+}
+
+// NewAuditRepository creates a new AuditRepository implementation
+func NewAuditRepository(q *sqlc.Queries) AuditRepository {
+	return &auditRepo{q: q}
+}
+
+func (r *auditRepo) Create(ctx context.Context, arg sqlc.CreateAuditEventParams) (sqlc.AuditEvent, error) {
+	return r.q.CreateAuditEvent(ctx, arg)
+}
+
+func (r *auditRepo) List(ctx context.Context, arg sqlc.ListAuditEventsParams) ([]sqlc.AuditEvent, error) {
+	return r.q.ListAuditEvents(ctx, arg)
+}
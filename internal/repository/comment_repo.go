@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"my_project/internal/database/sqlc"
+)
+
+// CommentRepository defines the persistence operations for comments
+type CommentRepository interface {
+	Create(ctx context.Context, arg sqlc.CreateCommentParams) (sqlc.Comment, error)
+	GetByID(ctx context.Context, id int32) (sqlc.Comment, error)
+	GetDepth(ctx context.Context, id int32) (int32, error)
+	ListByPost(ctx context.Context, postID int32, limit, offset int32) ([]sqlc.ListCommentsByPostRow, error)
+	ListThreadByPost(ctx context.Context, arg sqlc.ListCommentThreadByPostParams) ([]sqlc.ListCommentThreadByPostRow, error)
+	Update(ctx context.Context, arg sqlc.UpdateCommentParams) (sqlc.Comment, error)
+	Delete(ctx context.Context, id int32) error
+}
+
+type commentRepo struct {
+	q *sqlc.Queries
+}
+
+// NewCommentRepository creates a new CommentRepository implementation
+func NewCommentRepository(q *sqlc.Queries) CommentRepository {
+	return &commentRepo{q: q}
+}
+
+func (r *commentRepo) Create(ctx context.Context, arg sqlc.CreateCommentParams) (sqlc.Comment, error) {
+	return r.q.CreateComment(ctx, arg)
+}
+
+func (r *commentRepo) GetByID(ctx context.Context, id int32) (sqlc.Comment, error) {
+	return r.q.GetCommentByID(ctx, id)
+}
+
+func (r *commentRepo) GetDepth(ctx context.Context, id int32) (int32, error) {
+	return r.q.GetCommentDepth(ctx, id)
+}
+
+func (r *commentRepo) ListByPost(ctx context.Context, postID int32, limit, offset int32) ([]sqlc.ListCommentsByPostRow, error) {
+	params := sqlc.ListCommentsByPostParams{
+		PostID: postID,
+		Limit:  limit,
+		Offset: offset,
+	}
+	return r.q.ListCommentsByPost(ctx, params)
+}
+
+func (r *commentRepo) ListThreadByPost(ctx context.Context, arg sqlc.ListCommentThreadByPostParams) ([]sqlc.ListCommentThreadByPostRow, error) {
+	return r.q.ListCommentThreadByPost(ctx, arg)
+}
+
+func (r *commentRepo) Update(ctx context.Context, arg sqlc.UpdateCommentParams) (sqlc.Comment, error) {
+	return r.q.UpdateComment(ctx, arg)
+}
+
+func (r *commentRepo) Delete(ctx context.Context, id int32) error {
+	return r.q.DeleteComment(ctx, id)
+}
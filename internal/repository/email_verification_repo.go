@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// EmailVerificationRepository defines the persistence operations for email verification tokens
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, arg sqlc.CreateEmailVerificationTokenParams) (sqlc.EmailVerificationToken, error)
+	Latest(ctx context.Context, userID int32) (sqlc.EmailVerificationToken, error)
+	Verify(ctx context.Context, tokenHash string) (sqlc.User, error)
+}
+
+type emailVerificationRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewEmailVerificationRepository creates a new EmailVerificationRepository implementation
+func NewEmailVerificationRepository(db *sql.DB, q *sqlc.Queries) EmailVerificationRepository {
+	return &emailVerificationRepo{db: db, q: q}
+}
+
+// Create stores a new token and invalidates the user's older unused ones,
+// so only the most recent verification email works
+func (r *emailVerificationRepo) Create(ctx context.Context, arg sqlc.CreateEmailVerificationTokenParams) (sqlc.EmailVerificationToken, error) {
+	var token sqlc.EmailVerificationToken
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.InvalidateEmailVerificationTokens(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = q.CreateEmailVerificationToken(ctx, arg)
+		return err
+	})
+	return token, err
+}
+
+func (r *emailVerificationRepo) Latest(ctx context.Context, userID int32) (sqlc.EmailVerificationToken, error) {
+	return r.q.GetLatestEmailVerificationToken(ctx, userID)
+}
+
+// Verify consumes the token and marks the user's email as verified atomically; a
+// token for a change of address also switches the user to the new email.
+// sql.ErrNoRows is returned for unknown, used or expired tokens.
+func (r *emailVerificationRepo) Verify(ctx context.Context, tokenHash string) (sqlc.User, error) {
+	var user sqlc.User
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		token, err := q.ConsumeEmailVerificationToken(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		if token.NewEmail.Valid {
+			user, err = q.ChangeUserEmail(ctx, sqlc.ChangeUserEmailParams{ID: token.UserID, Email: token.NewEmail.String})
+		} else {
+			user, err = q.MarkUserEmailVerified(ctx, token.UserID)
+		}
+		if err != nil {
+			return err
+		}
+		return q.InvalidateEmailVerificationTokens(ctx, token.UserID)
+	})
+	return user, err
+}
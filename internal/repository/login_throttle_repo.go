@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"my_project/internal/database/sqlc"
+)
+
+// LoginThrottleRepository defines the persistence operations for failed login tracking
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, scope, key string) (sqlc.LoginThrottle, error)
+	RecordFailure(ctx context.Context, scope, key string, windowStart time.Time) (sqlc.LoginThrottle, error)
+	Lock(ctx context.Context, arg sqlc.CreateLoginLockoutParams) (sqlc.LoginLockout, error)
+	Clear(ctx context.Context, scope, key string) error
+	Unlock(ctx context.Context, scope, key string, actorID int32) error
+}
+
+type loginThrottleRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewLoginThrottleRepository creates a new LoginThrottleRepository implementation
+func NewLoginThrottleRepository(db *sql.DB, q *sqlc.Queries) LoginThrottleRepository {
+	return &loginThrottleRepo{db: db, q: q}
+}
+
+func (r *loginThrottleRepo) Get(ctx context.Context, scope, key string) (sqlc.LoginThrottle, error) {
+	return r.q.GetLoginThrottle(ctx, sqlc.GetLoginThrottleParams{Scope: scope, Key: key})
+}
+
+// RecordFailure counts a failed attempt; failures before windowStart are forgotten
+func (r *loginThrottleRepo) RecordFailure(ctx context.Context, scope, key string, windowStart time.Time) (sqlc.LoginThrottle, error) {
+	return r.q.RecordLoginFailure(ctx, sqlc.RecordLoginFailureParams{Scope: scope, Key: key, WindowStart: windowStart})
+}
+
+// Lock blocks logins for the key until arg.LockedUntil and records the lockout
+func (r *loginThrottleRepo) Lock(ctx context.Context, arg sqlc.CreateLoginLockoutParams) (sqlc.LoginLockout, error) {
+	var lockout sqlc.LoginLockout
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		err := q.LockLogin(ctx, sqlc.LockLoginParams{
+			Scope:       arg.Scope,
+			Key:         arg.Key,
+			LockedUntil: sql.NullTime{Time: arg.LockedUntil, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		lockout, err = q.CreateLoginLockout(ctx, arg)
+		return err
+	})
+	return lockout, err
+}
+
+// Clear forgets the failed attempts of the key
+func (r *loginThrottleRepo) Clear(ctx context.Context, scope, key string) error {
+	return r.q.ClearLoginThrottle(ctx, sqlc.ClearLoginThrottleParams{Scope: scope, Key: key})
+}
+
+// Unlock clears the key and marks its active lockouts as lifted by actorID
+func (r *loginThrottleRepo) Unlock(ctx context.Context, scope, key string, actorID int32) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.ClearLoginThrottle(ctx, sqlc.ClearLoginThrottleParams{Scope: scope, Key: key}); err != nil {
+			return err
+		}
+		return q.LiftLoginLockouts(ctx, sqlc.LiftLoginLockoutsParams{
+			Scope:      scope,
+			Key:        key,
+			UnlockedBy: sql.NullInt32{Int32: actorID, Valid: true},
+		})
+	})
+}
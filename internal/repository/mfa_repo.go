@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// MFARepository defines the persistence operations for TOTP two-factor authentication
+type MFARepository interface {
+	SetSecret(ctx context.Context, userID int32, encryptedSecret string) error
+	Enable(ctx context.Context, userID int32, recoveryCodeHashes []string) error
+	Disable(ctx context.Context, userID int32) error
+	UseRecoveryCode(ctx context.Context, userID int32, codeHash string) (bool, error)
+	UseTOTPStep(ctx context.Context, userID int32, step int64) (bool, error)
+
+	CreateChallenge(ctx context.Context, arg sqlc.CreateMFAChallengeParams) error
+	GetChallenge(ctx context.Context, jti string) (sqlc.MfaChallenge, error)
+	RecordChallengeFailure(ctx context.Context, jti string) (int32, error)
+	UseChallenge(ctx context.Context, jti string) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context) (int64, error)
+}
+
+type mfaRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewMFARepository creates a new MFARepository implementation
+func NewMFARepository(db *sql.DB, q *sqlc.Queries) MFARepository {
+	return &mfaRepo{db: db, q: q}
+}
+
+func (r *mfaRepo) SetSecret(ctx context.Context, userID int32, encryptedSecret string) error {
+	return r.q.SetUserTOTPSecret(ctx, sqlc.SetUserTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: encryptedSecret, Valid: true},
+	})
+}
+
+// Enable turns 2FA on and replaces the user's recovery codes in one transaction
+func (r *mfaRepo) Enable(ctx context.Context, userID int32, recoveryCodeHashes []string) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.EnableUserTOTP(ctx, userID); err != nil {
+			return err
+		}
+		if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			if err := q.CreateRecoveryCode(ctx, sqlc.CreateRecoveryCodeParams{UserID: userID, CodeHash: hash}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Disable clears the secret and every recovery code
+func (r *mfaRepo) Disable(ctx context.Context, userID int32) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.DisableUserTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(ctx, userID)
+	})
+}
+
+// UseRecoveryCode marks a matching unused code as used and reports whether one was found
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID int32, codeHash string) (bool, error) {
+	affected, err := r.q.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{UserID: userID, CodeHash: codeHash})
+	return affected > 0, err
+}
+
+// UseTOTPStep records step as the last accepted one; it reports false when step is not newer
+func (r *mfaRepo) UseTOTPStep(ctx context.Context, userID int32, step int64) (bool, error) {
+	affected, err := r.q.UseUserTOTPStep(ctx, sqlc.UseUserTOTPStepParams{Step: step, ID: userID})
+	return affected > 0, err
+}
+
+func (r *mfaRepo) CreateChallenge(ctx context.Context, arg sqlc.CreateMFAChallengeParams) error {
+	return r.q.CreateMFAChallenge(ctx, arg)
+}
+
+// GetChallenge returns an unused, unexpired challenge
+func (r *mfaRepo) GetChallenge(ctx context.Context, jti string) (sqlc.MfaChallenge, error) {
+	return r.q.GetMFAChallenge(ctx, jti)
+}
+
+// RecordChallengeFailure counts a wrong code against the challenge and returns the new total
+func (r *mfaRepo) RecordChallengeFailure(ctx context.Context, jti string) (int32, error) {
+	return r.q.RecordMFAChallengeFailure(ctx, jti)
+}
+
+// UseChallenge marks the challenge used; it reports false when it was already used or expired
+func (r *mfaRepo) UseChallenge(ctx context.Context, jti string) (bool, error) {
+	affected, err := r.q.UseMFAChallenge(ctx, jti)
+	return affected > 0, err
+}
+
+func (r *mfaRepo) DeleteExpiredChallenges(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredMFAChallenges(ctx)
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// OIDCRepository defines the persistence operations for external sign-in
+type OIDCRepository interface {
+	CreateAuthRequest(ctx context.Context, arg sqlc.CreateOIDCAuthRequestParams) error
+	ConsumeAuthRequest(ctx context.Context, stateHash string) (sqlc.OidcAuthRequest, error)
+	DeleteExpiredAuthRequests(ctx context.Context) (int64, error)
+	GetUserByIdentity(ctx context.Context, provider, subject string) (sqlc.User, error)
+	Link(ctx context.Context, userID int32, provider, subject, email string) (sqlc.User, error)
+	CreateUser(ctx context.Context, arg sqlc.CreateUserParams, provider, subject string) (sqlc.User, error)
+}
+
+type oidcRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewOIDCRepository creates a new OIDCRepository implementation
+func NewOIDCRepository(db *sql.DB, q *sqlc.Queries) OIDCRepository {
+	return &oidcRepo{db: db, q: q}
+}
+
+func (r *oidcRepo) CreateAuthRequest(ctx context.Context, arg sqlc.CreateOIDCAuthRequestParams) error {
+	return r.q.CreateOIDCAuthRequest(ctx, arg)
+}
+
+// ConsumeAuthRequest returns and deletes a pending sign-in; sql.ErrNoRows means
+// the state is unknown, already used or expired
+func (r *oidcRepo) ConsumeAuthRequest(ctx context.Context, stateHash string) (sqlc.OidcAuthRequest, error) {
+	return r.q.ConsumeOIDCAuthRequest(ctx, stateHash)
+}
+
+func (r *oidcRepo) DeleteExpiredAuthRequests(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredOIDCAuthRequests(ctx)
+}
+
+func (r *oidcRepo) GetUserByIdentity(ctx context.Context, provider, subject string) (sqlc.User, error) {
+	return r.q.GetUserByIdentity(ctx, sqlc.GetUserByIdentityParams{Provider: provider, Subject: subject})
+}
+
+// Link attaches an identity to an existing user. The user's own email is left
+// as it is: the identity's email may differ from it.
+func (r *oidcRepo) Link(ctx context.Context, userID int32, provider, subject, email string) (sqlc.User, error) {
+	_, err := r.q.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  subject,
+		Email:    sql.NullString{String: email, Valid: email != ""},
+	})
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	return r.q.GetUserByID(ctx, userID)
+}
+
+// CreateUser creates a verified account together with its first identity
+func (r *oidcRepo) CreateUser(ctx context.Context, arg sqlc.CreateUserParams, provider, subject string) (sqlc.User, error) {
+	var user sqlc.User
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		created, err := q.CreateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+			UserID:   created.ID,
+			Provider: provider,
+			Subject:  subject,
+			Email:    sql.NullString{String: arg.Email, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.MarkUserEmailVerified(ctx, created.ID)
+		return err
+	})
+	return user, err
+}
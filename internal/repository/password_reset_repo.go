@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// PasswordResetRepository defines the persistence operations for password reset tokens
+type PasswordResetRepository interface {
+	Create(ctx context.Context, arg sqlc.CreatePasswordResetTokenParams) (sqlc.PasswordResetToken, error)
+	Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error)
+}
+
+type passwordResetRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewPasswordResetRepository creates a new PasswordResetRepository implementation
+func NewPasswordResetRepository(db *sql.DB, q *sqlc.Queries) PasswordResetRepository {
+	return &passwordResetRepo{db: db, q: q}
+}
+
+// Create stores a new token and invalidates the user's older unused ones,
+// so only the most recent reset email works
+func (r *passwordResetRepo) Create(ctx context.Context, arg sqlc.CreatePasswordResetTokenParams) (sqlc.PasswordResetToken, error) {
+	var token sqlc.PasswordResetToken
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.InvalidatePasswordResetTokens(ctx, arg.UserID); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = q.CreatePasswordResetToken(ctx, arg)
+		return err
+	})
+	return token, err
+}
+
+// Reset consumes the token, sets the new password hash and signs the user out
+// everywhere atomically. sql.ErrNoRows is returned for unknown, used or expired tokens.
+func (r *passwordResetRepo) Reset(ctx context.Context, tokenHash, passwordHash string) (int32, error) {
+	var userID int32
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		var err error
+		userID, err = q.ConsumePasswordResetToken(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		if err := q.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{ID: userID, PasswordHash: passwordHash}); err != nil {
+			return err
+		}
+		if err := q.InvalidatePasswordResetTokens(ctx, userID); err != nil {
+			return err
+		}
+		return revokeAccess(ctx, q, userID)
+	})
+	return userID, err
+}
@@ -2,58 +2,217 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"my_project/internal/database/sqlc"
 )
 
 // PostRepository defines the persistence operations for posts
 type PostRepository interface {
-	Create(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.CreatePostRow, error)
+	Create(ctx context.Context, arg sqlc.CreatePostParams, tags []string) (sqlc.GetPostDetailRow, error)
 	GetByID(ctx context.Context, id int32) (sqlc.Post, error)
-	List(ctx context.Context, limit, offset int32) ([]sqlc.ListPostsRow, error)
-	ListByUser(ctx context.Context, userID int32, limit, offset int32) ([]sqlc.ListPostsByUserRow, error)
-	Update(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error)
-	Delete(ctx context.Context, id int32) error
+	GetDetail(ctx context.Context, id int32) (sqlc.GetPostDetailRow, error)
+	List(ctx context.Context, arg sqlc.ListPostsParams) ([]sqlc.ListPostsRow, error)
+	ListByUser(ctx context.Context, arg sqlc.ListPostsByUserParams) ([]sqlc.ListPostsByUserRow, error)
+	Update(ctx context.Context, arg sqlc.UpdatePostParams, tags []string, editorID int32) (before, after sqlc.GetPostDetailRow, err error)
+	UpdateStatus(ctx context.Context, id int32, status string) (sqlc.Post, error)
+	PublishDue(ctx context.Context, batchSize int32) ([]int32, error)
+	Delete(ctx context.Context, id int32) (sqlc.GetPostDetailRow, error)
+	Restore(ctx context.Context, id int32) (sqlc.Post, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	ListRevisions(ctx context.Context, postID int32) ([]sqlc.ListPostRevisionsRow, error)
+	GetRevision(ctx context.Context, postID, revision int32) (sqlc.PostRevision, error)
+	RestoreRevision(ctx context.Context, postID, revision, editorID int32) (sqlc.Post, error)
+
+	ListTags(ctx context.Context, limit int32) ([]sqlc.ListTagsWithCountsRow, error)
 }
 
 type postRepo struct {
-	q *sqlc.Queries
+	db *sql.DB
+	q  *sqlc.Queries
 }
 
 // NewPostRepository creates a new PostRepository implementation
-func NewPostRepository(q *sqlc.Queries) PostRepository {
-	return &postRepo{q: q}
+func NewPostRepository(db *sql.DB, q *sqlc.Queries) PostRepository {
+	return &postRepo{db: db, q: q}
 }
 
-func (r *postRepo) Create(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.CreatePostRow, error) {
-	return r.q.CreatePost(ctx, arg)
+// Create inserts the post together with its first revision and tags
+func (r *postRepo) Create(ctx context.Context, arg sqlc.CreatePostParams, tags []string) (sqlc.GetPostDetailRow, error) {
+	var post sqlc.GetPostDetailRow
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		created, err := q.CreatePost(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreatePostRevision(ctx, sqlc.CreatePostRevisionParams{
+			PostID:   created.ID,
+			EditorID: sql.NullInt32{Int32: created.UserID, Valid: true},
+			Title:    created.Title,
+			Content:  created.Content,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := setTags(ctx, q, created.ID, tags); err != nil {
+			return err
+		}
+
+		post, err = q.GetPostDetail(ctx, created.ID)
+		return err
+	})
+	return post, err
 }
 
 func (r *postRepo) GetByID(ctx context.Context, id int32) (sqlc.Post, error) {
 	return r.q.GetPostByID(ctx, id)
 }
 
-func (r *postRepo) List(ctx context.Context, limit, offset int32) ([]sqlc.ListPostsRow, error) {
-	params := sqlc.ListPostsParams{
-		Limit:  limit,
-		Offset: offset,
+func (r *postRepo) GetDetail(ctx context.Context, id int32) (sqlc.GetPostDetailRow, error) {
+	return r.q.GetPostDetail(ctx, id)
+}
+
+func (r *postRepo) List(ctx context.Context, arg sqlc.ListPostsParams) ([]sqlc.ListPostsRow, error) {
+	if arg.Tags == nil {
+		// A NULL array would not match the "no filter" branch of the query
+		arg.Tags = []string{}
 	}
-	return r.q.ListPosts(ctx, params)
+	return r.q.ListPosts(ctx, arg)
+}
+
+func (r *postRepo) ListByUser(ctx context.Context, arg sqlc.ListPostsByUserParams) ([]sqlc.ListPostsByUserRow, error) {
+	return r.q.ListPostsByUser(ctx, arg)
+}
+
+// Update changes the post and records the new state as a revision; nil tags leave
+// the tags untouched. before is the post as it was right before this update.
+func (r *postRepo) Update(ctx context.Context, arg sqlc.UpdatePostParams, tags []string, editorID int32) (before, after sqlc.GetPostDetailRow, err error) {
+	err = runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		var err error
+		if before, err = lockPostDetail(ctx, q, arg.ID); err != nil {
+			return err
+		}
+		updated, err := q.UpdatePost(ctx, arg)
+		if err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, q, updated, editorID, sql.NullInt32{}); err != nil {
+			return err
+		}
+
+		if tags != nil {
+			if err := q.DeletePostTags(ctx, updated.ID); err != nil {
+				return err
+			}
+			if err := setTags(ctx, q, updated.ID, tags); err != nil {
+				return err
+			}
+		}
+
+		after, err = q.GetPostDetail(ctx, updated.ID)
+		return err
+	})
+	return before, after, err
+}
+
+func (r *postRepo) UpdateStatus(ctx context.Context, id int32, status string) (sqlc.Post, error) {
+	return r.q.UpdatePostStatus(ctx, sqlc.UpdatePostStatusParams{Status: status, ID: id})
+}
+
+func (r *postRepo) PublishDue(ctx context.Context, batchSize int32) ([]int32, error) {
+	return r.q.PublishDuePosts(ctx, batchSize)
 }
 
-func (r *postRepo) ListByUser(ctx context.Context, userID int32, limit, offset int32) ([]sqlc.ListPostsByUserRow, error) {
-	params := sqlc.ListPostsByUserParams{
-		UserID: userID,
-		Limit:  limit,
-		Offset: offset,
+// Delete soft-deletes a post and returns it as it was deleted; sql.ErrNoRows is
+// returned when no live post has the id
+func (r *postRepo) Delete(ctx context.Context, id int32) (sqlc.GetPostDetailRow, error) {
+	var post sqlc.GetPostDetailRow
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		var err error
+		if post, err = lockPostDetail(ctx, q, id); err != nil {
+			return err
+		}
+		_, err = q.SoftDeletePost(ctx, id)
+		return err
+	})
+	return post, err
+}
+
+// Restore undoes Delete; sql.ErrNoRows is returned when no deleted post has the id
+func (r *postRepo) Restore(ctx context.Context, id int32) (sqlc.Post, error) {
+	return r.q.RestorePost(ctx, id)
+}
+
+// PurgeDeleted hard-deletes posts that were deleted before deletedBefore
+func (r *postRepo) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return r.q.PurgeDeletedPosts(ctx, sql.NullTime{Time: deletedBefore, Valid: true})
+}
+
+func (r *postRepo) ListRevisions(ctx context.Context, postID int32) ([]sqlc.ListPostRevisionsRow, error) {
+	return r.q.ListPostRevisions(ctx, postID)
+}
+
+func (r *postRepo) GetRevision(ctx context.Context, postID, revision int32) (sqlc.PostRevision, error) {
+	return r.q.GetPostRevision(ctx, sqlc.GetPostRevisionParams{PostID: postID, Revision: revision})
+}
+
+// RestoreRevision copies an old revision back onto the post as a new revision
+func (r *postRepo) RestoreRevision(ctx context.Context, postID, revision, editorID int32) (sqlc.Post, error) {
+	var post sqlc.Post
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		old, err := q.GetPostRevision(ctx, sqlc.GetPostRevisionParams{PostID: postID, Revision: revision})
+		if err != nil {
+			return err
+		}
+
+		post, err = q.UpdatePost(ctx, sqlc.UpdatePostParams{
+			ID:      postID,
+			Title:   old.Title,
+			Content: old.Content,
+		})
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, q, post, editorID, sql.NullInt32{Int32: revision, Valid: true})
+	})
+	return post, err
+}
+
+func (r *postRepo) ListTags(ctx context.Context, limit int32) ([]sqlc.ListTagsWithCountsRow, error) {
+	return r.q.ListTagsWithCounts(ctx, limit)
+}
+
+// lockPostDetail locks a live post for the rest of the transaction and reads it
+func lockPostDetail(ctx context.Context, q *sqlc.Queries, id int32) (sqlc.GetPostDetailRow, error) {
+	if _, err := q.LockPost(ctx, id); err != nil {
+		return sqlc.GetPostDetailRow{}, err
 	}
-	return r.q.ListPostsByUser(ctx, params)
+	return q.GetPostDetail(ctx, id)
 }
 
-func (r *postRepo) Update(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error) {
-	return r.q.UpdatePost(ctx, arg)
+// setTags attaches tags to a post, creating any that do not exist yet
+func setTags(ctx context.Context, q *sqlc.Queries, postID int32, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	tagIDs, err := q.UpsertTags(ctx, tags)
+	if err != nil {
+		return err
+	}
+	return q.AddPostTags(ctx, sqlc.AddPostTagsParams{PostID: postID, TagIds: tagIDs})
 }
 
-func (r *postRepo) Delete(ctx context.Context, id int32) error {
-	return r.q.DeletePost(ctx, id)
+func recordRevision(ctx context.Context, q *sqlc.Queries, post sqlc.Post, editorID int32, restoredFrom sql.NullInt32) error {
+	_, err := q.CreatePostRevision(ctx, sqlc.CreatePostRevisionParams{
+		PostID:       post.ID,
+		EditorID:     sql.NullInt32{Int32: editorID, Valid: true},
+		Title:        post.Title,
+		Content:      post.Content,
+		RestoredFrom: restoredFrom,
+	})
+	return err
 }
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"my_project/internal/database/sqlc"
+)
+
+// SearchRepository runs full-text queries against posts and comments
+type SearchRepository interface {
+	SearchPosts(ctx context.Context, arg sqlc.SearchPostsParams) ([]sqlc.SearchPostsRow, error)
+	SearchComments(ctx context.Context, arg sqlc.SearchCommentsParams) ([]sqlc.SearchCommentsRow, error)
+}
+
+type searchRepo struct {
+	q *sqlc.Queries
+}
+
+// NewSearchRepository creates a new SearchRepository implementation
+func NewSearchRepository(q *sqlc.Queries) SearchRepository {
+	return &searchRepo{q: q}
+}
+
+func (r *searchRepo) SearchPosts(ctx context.Context, arg sqlc.SearchPostsParams) ([]sqlc.SearchPostsRow, error) {
+	return r.q.SearchPosts(ctx, arg)
+}
+
+func (r *searchRepo) SearchComments(ctx context.Context, arg sqlc.SearchCommentsParams) ([]sqlc.SearchCommentsRow, error) {
+	return r.q.SearchComments(ctx, arg)
+}
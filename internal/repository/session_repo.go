@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"my_project/internal/database/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionRevoked is returned when a session was already revoked by a concurrent request
+var ErrSessionRevoked = errors.New("session already revoked")
+
+// SessionRepository defines the persistence operations for refresh-token sessions
+type SessionRepository interface {
+	Create(ctx context.Context, arg sqlc.CreateSessionParams) (sqlc.Session, error)
+	GetByTokenHash(ctx context.Context, hash string) (sqlc.Session, error)
+	Rotate(ctx context.Context, oldID int32, next sqlc.CreateSessionParams) (sqlc.Session, error)
+	Revoke(ctx context.Context, id int32) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAllForUser(ctx context.Context, userID int32) error
+}
+
+type sessionRepo struct {
+	db *sql.DB
+	q  *sqlc.Queries
+}
+
+// NewSessionRepository creates a new SessionRepository implementation
+func NewSessionRepository(db *sql.DB, q *sqlc.Queries) SessionRepository {
+	return &sessionRepo{db: db, q: q}
+}
+
+func (r *sessionRepo) Create(ctx context.Context, arg sqlc.CreateSessionParams) (sqlc.Session, error) {
+	return r.q.CreateSession(ctx, arg)
+}
+
+func (r *sessionRepo) GetByTokenHash(ctx context.Context, hash string) (sqlc.Session, error) {
+	return r.q.GetSessionByTokenHash(ctx, hash)
+}
+
+// Rotate revokes the old session and creates its successor atomically.
+// If the old session was already revoked, ErrSessionRevoked is returned and nothing is created.
+func (r *sessionRepo) Rotate(ctx context.Context, oldID int32, next sqlc.CreateSessionParams) (sqlc.Session, error) {
+	var session sqlc.Session
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		affected, err := q.RevokeSession(ctx, oldID)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrSessionRevoked
+		}
+
+		session, err = q.CreateSession(ctx, next)
+		return err
+	})
+	return session, err
+}
+
+func (r *sessionRepo) Revoke(ctx context.Context, id int32) error {
+	_, err := r.q.RevokeSession(ctx, id)
+	return err
+}
+
+func (r *sessionRepo) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	return r.q.RevokeSessionFamily(ctx, familyID)
+}
+
+func (r *sessionRepo) RevokeAllForUser(ctx context.Context, userID int32) error {
+	return r.q.RevokeUserSessions(ctx, userID)
+}
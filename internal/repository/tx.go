@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"my_project/internal/database/sqlc"
+)
+
+// runInTx executes fn inside a single transaction, rolling back if fn fails
+func runInTx(ctx context.Context, db *sql.DB, fn func(q *sqlc.Queries) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(sqlc.New(tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
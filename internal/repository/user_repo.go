@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
 	"my_project/internal/database/sqlc"
 )
 
@@ -9,16 +12,28 @@ type UserRepository interface {
 	Create(ctx context.Context, arg sqlc.CreateUserParams) (sqlc.User, error)
 	GetByID(ctx context.Context, id int32) (sqlc.User, error)
 	GetByEmail(ctx context.Context, email string) (sqlc.User, error)
-	List(ctx context.Context) ([]sqlc.User, error)
+	List(ctx context.Context, afterID, limit int32) ([]sqlc.User, error)
+	Search(ctx context.Context, arg sqlc.SearchUsersParams) ([]sqlc.User, error)
+	UpdateRole(ctx context.Context, id int32, role string) (sqlc.User, error)
+	UpdateProfile(ctx context.Context, arg sqlc.UpdateUserProfileParams) (sqlc.User, error)
+	UpdatePassword(ctx context.Context, id int32, passwordHash string) error
+	AdminUpdate(ctx context.Context, arg sqlc.AdminUpdateUserParams) (sqlc.User, error)
+	Suspend(ctx context.Context, arg sqlc.SuspendUserParams) (sqlc.User, error)
+	Unsuspend(ctx context.Context, id int32) (sqlc.User, error)
+	RevokeAccess(ctx context.Context, id int32) error
 	Delete(ctx context.Context, id int32) error
+	Restore(ctx context.Context, id int32) (sqlc.User, error)
+	ListPurgeable(ctx context.Context, deletedBefore time.Time, limit int32) ([]int32, error)
+	Purge(ctx context.Context, id int32, anonymize bool) error
 }
 
 type userRepo struct {
-	q *sqlc.Queries
+	db *sql.DB
+	q  *sqlc.Queries
 }
 
-func NewUserRepository(q *sqlc.Queries) UserRepository {
-	return &userRepo{q}
+func NewUserRepository(db *sql.DB, q *sqlc.Queries) UserRepository {
+	return &userRepo{db: db, q: q}
 }
 
 func (r *userRepo) Create(ctx context.Context, arg sqlc.CreateUserParams) (sqlc.User, error) {
@@ -33,10 +48,139 @@ func (r *userRepo) GetByEmail(ctx context.Context, email string) (sqlc.User, err
 	return r.q.GetUserByEmail(ctx, email)
 }
 
-func (r *userRepo) List(ctx context.Context) ([]sqlc.User, error) {
-	return r.q.ListUsers(ctx)
+func (r *userRepo) List(ctx context.Context, afterID, limit int32) ([]sqlc.User, error) {
+	return r.q.ListUsers(ctx, sqlc.ListUsersParams{AfterID: afterID, PageLimit: limit})
+}
+
+func (r *userRepo) Search(ctx context.Context, arg sqlc.SearchUsersParams) ([]sqlc.User, error) {
+	return r.q.SearchUsers(ctx, arg)
+}
+
+// UpdateRole changes the user's role and signs them out everywhere, so no token
+// carrying the old role stays in use
+func (r *userRepo) UpdateRole(ctx context.Context, id int32, role string) (sqlc.User, error) {
+	var user sqlc.User
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		var err error
+		if user, err = q.UpdateUserRole(ctx, sqlc.UpdateUserRoleParams{ID: id, Role: role}); err != nil {
+			return err
+		}
+		return revokeAccess(ctx, q, id)
+	})
+	return user, err
+}
+
+func (r *userRepo) UpdateProfile(ctx context.Context, arg sqlc.UpdateUserProfileParams) (sqlc.User, error) {
+	return r.q.UpdateUserProfile(ctx, arg)
+}
+
+// UpdatePassword sets a new password hash and signs the user out everywhere atomically
+func (r *userRepo) UpdatePassword(ctx context.Context, id int32, passwordHash string) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if err := q.UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{ID: id, PasswordHash: passwordHash}); err != nil {
+			return err
+		}
+		return revokeAccess(ctx, q, id)
+	})
+}
+
+// AdminUpdate applies an admin's edit. A changed email voids the verification
+// links sent so far; a changed role signs the user out everywhere, like UpdateRole.
+func (r *userRepo) AdminUpdate(ctx context.Context, arg sqlc.AdminUpdateUserParams) (sqlc.User, error) {
+	var user sqlc.User
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		before, err := q.GetUserByID(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if user, err = q.AdminUpdateUser(ctx, arg); err != nil {
+			return err
+		}
+		if user.Email != before.Email {
+			if err := q.InvalidateEmailVerificationTokens(ctx, arg.ID); err != nil {
+				return err
+			}
+		}
+		if user.Role == before.Role {
+			return nil
+		}
+		return revokeAccess(ctx, q, arg.ID)
+	})
+	return user, err
+}
+
+// Suspend marks the user suspended and signs them out everywhere in one transaction
+func (r *userRepo) Suspend(ctx context.Context, arg sqlc.SuspendUserParams) (sqlc.User, error) {
+	var user sqlc.User
+	err := runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		var err error
+		if user, err = q.SuspendUser(ctx, arg); err != nil {
+			return err
+		}
+		return revokeAccess(ctx, q, arg.ID)
+	})
+	return user, err
 }
 
+func (r *userRepo) Unsuspend(ctx context.Context, id int32) (sqlc.User, error) {
+	return r.q.UnsuspendUser(ctx, id)
+}
+
+// RevokeAccess revokes every session of the user and rejects the access tokens already issued
+func (r *userRepo) RevokeAccess(ctx context.Context, id int32) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		return revokeAccess(ctx, q, id)
+	})
+}
+
+func revokeAccess(ctx context.Context, q *sqlc.Queries, userID int32) error {
+	if err := q.RevokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return q.RevokeUserTokens(ctx, userID)
+}
+
+// Delete soft-deletes a user and signs them out everywhere; sql.ErrNoRows is
+// returned when no live user has the id
 func (r *userRepo) Delete(ctx context.Context, id int32) error {
-	return r.q.DeleteUser(ctx, id)
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		affected, err := q.SoftDeleteUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return sql.ErrNoRows
+		}
+		return revokeAccess(ctx, q, id)
+	})
+}
+
+// Restore undoes Delete; sql.ErrNoRows is returned when no deleted user has the id
+func (r *userRepo) Restore(ctx context.Context, id int32) (sqlc.User, error) {
+	return r.q.RestoreUser(ctx, id)
+}
+
+// ListPurgeable returns up to limit users deleted before deletedBefore
+func (r *userRepo) ListPurgeable(ctx context.Context, deletedBefore time.Time, limit int32) ([]int32, error) {
+	return r.q.ListPurgeableUsers(ctx, sqlc.ListPurgeableUsersParams{
+		DeletedAt: sql.NullTime{Time: deletedBefore, Valid: true},
+		Limit:     limit,
+	})
+}
+
+// Purge hard-deletes a user. Their posts and comments are deleted with them, or
+// handed to the "[deleted user]" placeholder when anonymize is set.
+func (r *userRepo) Purge(ctx context.Context, id int32, anonymize bool) error {
+	return runInTx(ctx, r.db, func(q *sqlc.Queries) error {
+		if anonymize {
+			if err := q.AnonymizeUserPosts(ctx, id); err != nil {
+				return err
+			}
+			if err := q.AnonymizeUserComments(ctx, id); err != nil {
+				return err
+			}
+		}
+		_, err := q.DeleteUser(ctx, id)
+		return err
+	})
 }
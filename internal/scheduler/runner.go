@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work run periodically by the Runner
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Runner runs registered jobs on their own tickers until stopped.
+// Jobs must be safe to run concurrently from several API replicas.
+type Runner struct {
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates an empty job runner
+func NewRunner() *Runner {
+	return &Runner{}
+}
+
+// Add registers a job; it must be called before Start
+func (r *Runner) Add(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// Start launches one goroutine per job
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(ctx, job)
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, job Job) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job.Run(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[job=%s] failed: %v", job.Name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	var runs, failures atomic.Int32
+	done := make(chan struct{})
+
+	r := NewRunner()
+	r.Add(Job{
+		Name:     "count",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			if runs.Add(1) == 3 {
+				close(done)
+			}
+			return nil
+		},
+	})
+	r.Add(Job{
+		Name:     "fail",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			failures.Add(1)
+			return errors.New("boom")
+		},
+	})
+
+	r.Start()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to run repeatedly after Start")
+	}
+	r.Stop()
+
+	// Nothing runs once Stop has returned, and a failing job does not stop the others
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("expected no runs after Stop, got %d more", runs.Load()-stopped)
+	}
+	if failures.Load() == 0 {
+		t.Error("expected the failing job to keep being scheduled")
+	}
+}
+
+func TestRunnerStopWithoutStart(t *testing.T) {
+	NewRunner().Stop()
+}
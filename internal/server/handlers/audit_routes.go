@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"my_project/internal/app/permissions"
+	"my_project/internal/controller"
+	"my_project/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRoutes struct {
+	auditController *controller.AuditController
+}
+
+func NewAuditRoutes(ac *controller.AuditController) *AuditRoutes {
+	return &AuditRoutes{auditController: ac}
+}
+
+func (ar *AuditRoutes) RegisterRoutes(router *gin.RouterGroup) {
+	admin := router.Group("/admin/audit")
+	admin.Use(middleware.AuthMiddleware())
+	{
+		admin.GET("", middleware.RequirePermission(permissions.AuditRead), ar.auditController.ListAuditEventsHandler)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"my_project/internal/controller"
+	"my_project/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +18,25 @@ func NewAuthRoutes(authController *controller.AuthController) *AuthRoutes {
 func (ar *AuthRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	auth := router.Group("/auth")
 	{
+		// public
 		auth.POST("/register", ar.authController.RegisterHandler)
 		auth.POST("/login", ar.authController.LoginHandler)
+		auth.POST("/refresh", ar.authController.RefreshHandler)
+		auth.POST("/logout", ar.authController.LogoutHandler)
+		auth.POST("/password/forgot", ar.authController.ForgotPasswordHandler)
+		auth.POST("/password/reset", ar.authController.ResetPasswordHandler)
+		auth.GET("/verify", ar.authController.VerifyEmailHandler)
+		auth.POST("/2fa/verify", ar.authController.VerifyMFAHandler)
+		auth.GET("/oidc", ar.authController.ListOIDCProvidersHandler)
+		auth.GET("/oidc/:provider/start", ar.authController.StartOIDCHandler)
+		auth.GET("/oidc/:provider/callback", ar.authController.OIDCCallbackHandler)
 	}
+
+	protected := auth.Group("")
+	protected.Use(middleware.AuthMiddleware(), middleware.RequireSession())
+	protected.POST("/verify/resend", ar.authController.ResendVerificationHandler)
+	protected.POST("/2fa/setup", ar.authController.SetupTOTPHandler)
+	protected.POST("/2fa/enable", ar.authController.EnableTOTPHandler)
+	protected.POST("/2fa/disable", ar.authController.DisableTOTPHandler)
+	protected.POST("/oidc/:provider/link", ar.authController.LinkOIDCHandler)
 }
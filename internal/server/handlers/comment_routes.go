@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"my_project/internal/app/permissions"
+	"my_project/internal/controller"
+	"my_project/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentRoutes struct {
+	commentController    *controller.CommentController
+	requireVerifiedEmail bool
+}
+
+func NewCommentRoutes(cc *controller.CommentController, requireVerifiedEmail bool) *CommentRoutes {
+	return &CommentRoutes{commentController: cc, requireVerifiedEmail: requireVerifiedEmail}
+}
+
+func (cr *CommentRoutes) RegisterRoutes(api *gin.RouterGroup) {
+	// public
+	api.GET("/posts/:id/comments", cr.commentController.ListCommentsHandler)
+
+	protected := api.Group("")
+	protected.Use(middleware.AuthMiddleware())
+	protected.POST("/posts/:id/comments", middleware.RequirePermission(permissions.CommentsCreate), middleware.RequireVerifiedEmail(cr.requireVerifiedEmail), cr.commentController.CreateCommentHandler)
+	protected.PUT("/comments/:id", middleware.RequirePermission(permissions.CommentsUpdate), cr.commentController.UpdateCommentHandler)
+	protected.DELETE("/comments/:id", middleware.RequirePermission(permissions.CommentsDelete), cr.commentController.DeleteCommentHandler)
+}
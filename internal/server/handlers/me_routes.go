@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"my_project/internal/controller"
+	"my_project/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MeRoutes are the signed-in user's own account endpoints
+type MeRoutes struct {
+	meController     *controller.MeController
+	apiKeyController *controller.APIKeyController
+}
+
+func NewMeRoutes(meController *controller.MeController, apiKeyController *controller.APIKeyController) *MeRoutes {
+	return &MeRoutes{meController: meController, apiKeyController: apiKeyController}
+}
+
+func (mr *MeRoutes) RegisterRoutes(api *gin.RouterGroup) {
+	me := api.Group("/me")
+	me.Use(middleware.AuthMiddleware(), middleware.RequireSession())
+
+	me.GET("", mr.meController.GetMeHandler)
+	me.PATCH("", mr.meController.UpdateMeHandler)
+	me.DELETE("", mr.meController.DeleteMeHandler)
+	me.POST("/password", mr.meController.ChangePasswordHandler)
+	me.POST("/email", mr.meController.ChangeEmailHandler)
+
+	me.POST("/api-keys", mr.apiKeyController.CreateAPIKeyHandler)
+	me.GET("/api-keys", mr.apiKeyController.ListAPIKeysHandler)
+	me.DELETE("/api-keys/:id", mr.apiKeyController.DeleteAPIKeyHandler)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"my_project/internal/app/permissions"
 	"my_project/internal/controller"
 	"my_project/internal/middleware"
 
@@ -8,22 +9,38 @@ import (
 )
 
 type PostRoutes struct {
-	postController *controller.PostController
+	postController       *controller.PostController
+	requireVerifiedEmail bool
 }
 
-func NewPostRoutes(pc *controller.PostController) *PostRoutes {
-	return &PostRoutes{postController: pc}
+func NewPostRoutes(pc *controller.PostController, requireVerifiedEmail bool) *PostRoutes {
+	return &PostRoutes{postController: pc, requireVerifiedEmail: requireVerifiedEmail}
 }
 
 func (pr *PostRoutes) RegisterRoutes(api *gin.RouterGroup) {
 	posts := api.Group("/posts")
-	posts.GET("", pr.postController.ListPostsHandler)
-	posts.GET("/user/:userID", pr.postController.ListPostsByUserHandler)
-	posts.GET("/:id", pr.postController.GetPostHandler)
+
+	// public; a valid token additionally reveals the caller's own drafts
+	public := posts.Group("")
+	public.Use(middleware.OptionalAuthMiddleware())
+	public.GET("", pr.postController.ListPostsHandler)
+	public.GET("/user/:userID", pr.postController.ListPostsByUserHandler)
+	public.GET("/:id", pr.postController.GetPostHandler)
+
+	api.GET("/tags", pr.postController.ListTagsHandler)
 
 	protected := posts.Group("")
 	protected.Use(middleware.AuthMiddleware())
-	protected.POST("", pr.postController.CreatePostHandler)
-	protected.PUT("/:id", pr.postController.UpdatePostHandler)
-	protected.DELETE("/:id", pr.postController.DeletePostHandler)
+	protected.POST("", middleware.RequirePermission(permissions.PostsCreate), middleware.RequireVerifiedEmail(pr.requireVerifiedEmail), pr.postController.CreatePostHandler)
+	protected.PUT("/:id", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.UpdatePostHandler)
+	protected.POST("/:id/publish", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.PublishPostHandler)
+	protected.POST("/:id/archive", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.ArchivePostHandler)
+	protected.GET("/:id/revisions", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.ListRevisionsHandler)
+	protected.GET("/:id/revisions/:rev/diff", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.RevisionDiffHandler)
+	protected.POST("/:id/revisions/:rev/restore", middleware.RequirePermission(permissions.PostsUpdate), pr.postController.RestoreRevisionHandler)
+	protected.DELETE("/:id", middleware.RequirePermission(permissions.PostsDelete), pr.postController.DeletePostHandler)
+
+	admin := api.Group("/admin/posts")
+	admin.Use(middleware.AuthMiddleware())
+	admin.POST("/:id/restore", middleware.RequirePermission(permissions.PostsModerate), pr.postController.RestorePostHandler)
 }
@@ -2,31 +2,68 @@ package handlers
 
 import (
 	"my_project/internal/controller"
+	"my_project/internal/middleware"
+	"my_project/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
+// RouteConfig holds the access policies applied while registering routes
+type RouteConfig struct {
+	// RequireVerifiedEmail blocks users with an unverified email from creating posts and comments
+	RequireVerifiedEmail bool
+	RateLimits           RateLimitConfig
+}
+
+// RateLimitConfig holds the per-client policy of each route group; a nil Store disables limiting
+type RateLimitConfig struct {
+	Store ratelimit.Store
+	Auth  ratelimit.Policy
+	Read  ratelimit.Policy
+	Write ratelimit.Policy
+}
+
 type RouteHandler struct {
-	UserRoutes *UserRoutes
-	AuthRoutes *AuthRoutes
-	PostRoutes *PostRoutes
+	UserRoutes    *UserRoutes
+	AuthRoutes    *AuthRoutes
+	PostRoutes    *PostRoutes
+	CommentRoutes *CommentRoutes
+	SearchRoutes  *SearchRoutes
+	MeRoutes      *MeRoutes
+	AuditRoutes   *AuditRoutes
+
+	rateLimits RateLimitConfig
 }
 
-func NewRouteHandler(userController *controller.UserController, authController *controller.AuthController, postController *controller.PostController) *RouteHandler {
+func NewRouteHandler(cfg RouteConfig, userController *controller.UserController, authController *controller.AuthController, postController *controller.PostController, commentController *controller.CommentController, searchController *controller.SearchController, meController *controller.MeController, apiKeyController *controller.APIKeyController, auditController *controller.AuditController) *RouteHandler {
 	return &RouteHandler{
-		UserRoutes: NewUserRoutes(userController),
-		AuthRoutes: NewAuthRoutes(authController),
-		PostRoutes: NewPostRoutes(postController),
+		UserRoutes:    NewUserRoutes(userController),
+		AuthRoutes:    NewAuthRoutes(authController),
+		PostRoutes:    NewPostRoutes(postController, cfg.RequireVerifiedEmail),
+		CommentRoutes: NewCommentRoutes(commentController, cfg.RequireVerifiedEmail),
+		SearchRoutes:  NewSearchRoutes(searchController),
+		MeRoutes:      NewMeRoutes(meController, apiKeyController),
+		AuditRoutes:   NewAuditRoutes(auditController),
+		rateLimits:    cfg.RateLimits,
 	}
 }
 
 func (rh *RouteHandler) RegisterAllRoutes(api *gin.RouterGroup) {
-	rh.AuthRoutes.RegisterRoutes(api)
-	rh.UserRoutes.RegisterRoutes(api)
-	rh.PostRoutes.RegisterRoutes(api)
+	limits := rh.rateLimits
+
+	// Auth endpoints get their own, stricter budget
+	rh.AuthRoutes.RegisterRoutes(api.Group("", middleware.RateLimit(limits.Store, limits.Auth, middleware.ClientKey)))
+
+	rest := api.Group("", middleware.RateLimitByMethod(limits.Store, limits.Read, limits.Write, middleware.ClientKey))
+	rh.UserRoutes.RegisterRoutes(rest)
+	rh.PostRoutes.RegisterRoutes(rest)
+	rh.CommentRoutes.RegisterRoutes(rest)
+	rh.SearchRoutes.RegisterRoutes(rest)
+	rh.MeRoutes.RegisterRoutes(rest)
+	rh.AuditRoutes.RegisterRoutes(rest)
 }
 
-func RegisterAPIRoutes(api *gin.RouterGroup, userController *controller.UserController, authController *controller.AuthController, postController *controller.PostController) {
-	routeHandler := NewRouteHandler(userController, authController, postController)
+func RegisterAPIRoutes(api *gin.RouterGroup, cfg RouteConfig, userController *controller.UserController, authController *controller.AuthController, postController *controller.PostController, commentController *controller.CommentController, searchController *controller.SearchController, meController *controller.MeController, apiKeyController *controller.APIKeyController, auditController *controller.AuditController) {
+	routeHandler := NewRouteHandler(cfg, userController, authController, postController, commentController, searchController, meController, apiKeyController, auditController)
 	routeHandler.RegisterAllRoutes(api)
 }
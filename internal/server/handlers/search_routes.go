@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"my_project/internal/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchRoutes struct {
+	searchController *controller.SearchController
+}
+
+func NewSearchRoutes(sc *controller.SearchController) *SearchRoutes {
+	return &SearchRoutes{searchController: sc}
+}
+
+func (sr *SearchRoutes) RegisterRoutes(api *gin.RouterGroup) {
+	// public; only published content is searchable
+	api.GET("/search", sr.searchController.SearchHandler)
+}
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"my_project/internal/app/permissions"
 	"my_project/internal/controller"
+	"my_project/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,10 +18,32 @@ func NewUserRoutes(userController *controller.UserController) *UserRoutes {
 
 func (ur *UserRoutes) RegisterRoutes(router *gin.RouterGroup) {
 	users := router.Group("/users")
+
+	// public; a valid token reveals private fields of the caller's own account, or of anyone to admins
+	public := users.Group("")
+	public.Use(middleware.OptionalAuthMiddleware())
+	{
+		public.GET("", ur.userController.ListUsersHandler)
+		public.GET("/:id", ur.userController.GetUserHandler)
+	}
+
+	protected := users.Group("")
+	protected.Use(middleware.AuthMiddleware())
+	{
+		protected.POST("", middleware.RequirePermission(permissions.UsersCreate), ur.userController.CreateUserHandler) // tạo user mới (admin)
+		protected.DELETE("/:id", middleware.RequirePermission(permissions.UsersDelete), ur.userController.DeleteUserHandler)
+		protected.PUT("/:id/role", middleware.RequirePermission(permissions.UsersManageRoles), ur.userController.ChangeRoleHandler)
+		protected.POST("/:id/unlock", middleware.RequirePermission(permissions.UsersUnlock), ur.userController.UnlockLoginHandler)
+	}
+
+	admin := router.Group("/admin/users")
+	admin.Use(middleware.AuthMiddleware())
 	{
-		users.GET("", ur.userController.ListUsersHandler)
-		users.GET("/:id", ur.userController.GetUserHandler)
-		users.POST("", ur.userController.CreateUserHandler) // tạo user mới (admin)
-		users.DELETE("/:id", ur.userController.DeleteUserHandler)
+		admin.GET("", middleware.RequirePermission(permissions.UsersViewPrivate), ur.userController.AdminListUsersHandler)
+		admin.PATCH("/:id", middleware.RequirePermission(permissions.UsersManage), ur.userController.AdminUpdateUserHandler)
+		admin.POST("/:id/suspend", middleware.RequirePermission(permissions.UsersManage), ur.userController.SuspendUserHandler)
+		admin.POST("/:id/unsuspend", middleware.RequirePermission(permissions.UsersManage), ur.userController.UnsuspendUserHandler)
+		admin.POST("/:id/logout", middleware.RequirePermission(permissions.UsersManage), ur.userController.RevokeAccessHandler)
+		admin.POST("/:id/restore", middleware.RequirePermission(permissions.UsersManage), ur.userController.RestoreUserHandler)
 	}
 }
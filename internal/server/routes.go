@@ -14,6 +14,8 @@ import (
 func (s *Server) RegisterRoutes() http.Handler {
 	router := gin.New()
 	router.Use(gin.Recovery())
+	// Client IPs from X-Forwarded-For only count behind TRUSTED_PROXIES; validated in NewServer
+	_ = router.SetTrustedProxies(s.trustedProxies)
 
 	router.Use(cors.New(newCORSConfig()))
 	router.Use(
@@ -22,8 +24,12 @@ func (s *Server) RegisterRoutes() http.Handler {
 		middleware.ErrorHandlerMiddleware(),
 	)
 
+	router.NoRoute(middleware.NotFoundHandler)
+
+	router.GET("/.well-known/jwks.json", s.AuthController.JWKSHandler)
+
 	api := router.Group("/api/v1")
-	routeHandler := handlers.NewRouteHandler(s.UserController, s.AuthController, s.PostController)
+	routeHandler := handlers.NewRouteHandler(s.routeConfig, s.UserController, s.AuthController, s.PostController, s.CommentController, s.SearchController, s.MeController, s.APIKeyController, s.AuditController)
 	routeHandler.RegisterAllRoutes(api)
 
 	return router
@@ -34,7 +40,7 @@ func newCORSConfig() cors.Config {
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowHeaders:     []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Request-ID"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
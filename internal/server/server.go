@@ -4,29 +4,80 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"my_project/internal/controller"
 	"my_project/internal/database"
+	"my_project/internal/mailer"
+	"my_project/internal/middleware"
+	"my_project/internal/oidc"
+	"my_project/internal/ratelimit"
 	"my_project/internal/repository"
+	"my_project/internal/scheduler"
+	"my_project/internal/server/handlers"
 	"my_project/internal/service"
+	"my_project/utils"
+)
+
+// scheduledPublishInterval is how often due scheduled posts are published
+const scheduledPublishInterval = 30 * time.Second
+
+// defaultPasswordResetURL is the frontend page that completes a password reset
+const defaultPasswordResetURL = "http://localhost:5173/reset-password"
+
+// defaultEmailVerifyURL is the endpoint linked from verification emails
+const defaultEmailVerifyURL = "http://localhost:8080/api/v1/auth/verify"
+
+// mfaCleanupInterval is how often expired mfa challenges are deleted
+const mfaCleanupInterval = 10 * time.Minute
+
+// oidcCleanupInterval is how often abandoned external sign-ins are deleted
+const oidcCleanupInterval = 10 * time.Minute
+
+// defaultOIDCRedirectBase is where providers send users back, followed by /<provider>/callback
+const defaultOIDCRedirectBase = "http://localhost:8080/api/v1/auth/oidc"
+
+// rateLimitEvictInterval is how often full rate limit buckets are dropped
+const rateLimitEvictInterval = time.Minute
+
+// purgeInterval is how often soft-deleted users and posts past retention are hard-deleted
+const purgeInterval = time.Hour
+
+// defaultSoftDeleteRetention is how long deleted users and posts can be restored, overridable with SOFT_DELETE_RETENTION
+const defaultSoftDeleteRetention = 30 * 24 * time.Hour
+
+// Default per-client rate limits, overridable with RATE_LIMIT_AUTH, RATE_LIMIT_READ and RATE_LIMIT_WRITE
+const (
+	defaultAuthRateLimit  = "20/1m"
+	defaultReadRateLimit  = "300/1m"
+	defaultWriteRateLimit = "60/1m"
 )
 
 type Server struct {
-	port int
-	db   database.Service
+	port           int
+	db             database.Service
+	jobs           *scheduler.Runner
+	routeConfig    handlers.RouteConfig
+	trustedProxies []string
 
 	// Dependencies
-	UserRepository repository.UserRepository
-	UserService    service.UserService
-	UserController *controller.UserController
-	PostController *controller.PostController
-	AuthController *controller.AuthController
+	UserRepository    repository.UserRepository
+	UserService       service.UserService
+	UserController    *controller.UserController
+	PostController    *controller.PostController
+	CommentController *controller.CommentController
+	SearchController  *controller.SearchController
+	AuthController    *controller.AuthController
+	MeController      *controller.MeController
+	APIKeyController  *controller.APIKeyController
+	AuditController   *controller.AuditController
 }
 
 func NewServer() (*Server, error) {
@@ -35,6 +86,19 @@ func NewServer() (*Server, error) {
 		port = 8080
 	}
 
+	jwtConfig, err := utils.LoadJWTConfig(os.Getenv("APP_ENV") == "production")
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.ConfigureJWT(jwtConfig); err != nil {
+		return nil, err
+	}
+	secretKey, err := utils.LoadSecretKey()
+	if err != nil {
+		return nil, err
+	}
+	utils.ConfigureSecretKey(secretKey)
+
 	// Initialize database
 	db := database.New()
 
@@ -45,30 +109,307 @@ func NewServer() (*Server, error) {
 	}
 
 	// Initialize dependencies with Clean Architecture
-	userRepo := repository.NewUserRepository(db.GetQueries())
-	userService := service.NewUserService(userRepo)
-	userController := controller.NewUserController(userService)
+	auditRepo := repository.NewAuditRepository(db.GetQueries())
+	auditLogger := service.NewAuditLogger(auditRepo)
+	auditController := controller.NewAuditController(auditLogger)
+
+	userRepo := repository.NewUserRepository(db.GetDB(), db.GetQueries())
+	loginThrottleRepo := repository.NewLoginThrottleRepository(db.GetDB(), db.GetQueries())
+	loginThrottle := service.NewLoginThrottleService(loginThrottleRepo, service.DefaultAccountLoginPolicy, service.DefaultIPLoginPolicy)
+	userService := service.NewUserService(userRepo, loginThrottle)
+	middleware.SetAccountChecker(func(ctx context.Context, userID int32, issuedAt time.Time) (middleware.AccountState, error) {
+		user, err := userService.CheckAccess(ctx, userID, issuedAt)
+		if err != nil {
+			return middleware.AccountState{}, err
+		}
+		return middleware.AccountState{Role: user.Role, EmailVerified: user.EmailVerifiedAt.Valid}, nil
+	})
+
+	postRepo := repository.NewPostRepository(db.GetDB(), db.GetQueries())
+	postService := service.NewPostService(postRepo, auditLogger)
+	postController := controller.NewPostController(postService, auditLogger)
+
+	commentRepo := repository.NewCommentRepository(db.GetQueries())
+	commentService := service.NewCommentService(commentRepo, postRepo)
+	commentController := controller.NewCommentController(commentService)
+
+	searchRepo := repository.NewSearchRepository(db.GetQueries())
+	searchService := service.NewSearchService(searchRepo)
+	searchController := controller.NewSearchController(searchService)
+
+	sessionRepo := repository.NewSessionRepository(db.GetDB(), db.GetQueries())
+	sessionService := service.NewSessionService(sessionRepo, userRepo)
+
+	mail, err := newMailer()
+	if err != nil {
+		return nil, err
+	}
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = defaultPasswordResetURL
+	}
+	passwordResetRepo := repository.NewPasswordResetRepository(db.GetDB(), db.GetQueries())
+	passwordResetService := service.NewPasswordResetService(passwordResetRepo, userRepo, mail, resetURL)
+
+	verifyURL := os.Getenv("EMAIL_VERIFY_URL")
+	if verifyURL == "" {
+		verifyURL = defaultEmailVerifyURL
+	}
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db.GetDB(), db.GetQueries())
+	emailVerificationService := service.NewEmailVerificationService(emailVerificationRepo, userRepo, mail, verifyURL)
+	userController := controller.NewUserController(userService, emailVerificationService, auditLogger)
+
+	mfaRepo := repository.NewMFARepository(db.GetDB(), db.GetQueries())
+	mfaService := service.NewMFAService(mfaRepo, userRepo, loginThrottle)
+
+	oidcProviders, err := newOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
+	oidcRepo := repository.NewOIDCRepository(db.GetDB(), db.GetQueries())
+	oidcService := service.NewOIDCService(oidcRepo, userRepo, oidcProviders)
+
+	authController := controller.NewAuthController(userService, sessionService, passwordResetService, emailVerificationService, mfaService, oidcService, auditLogger)
+	meController := controller.NewMeController(userService, sessionService, emailVerificationService)
+
+	apiKeyRepo := repository.NewAPIKeyRepository(db.GetQueries())
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	middleware.SetAPIKeyAuthenticator(func(ctx context.Context, key string) (middleware.APIKeyIdentity, error) {
+		apiKey, user, err := apiKeyService.Authenticate(ctx, key)
+		if err != nil {
+			return middleware.APIKeyIdentity{}, err
+		}
+		return middleware.APIKeyIdentity{
+			KeyID:         apiKey.ID,
+			UserID:        user.ID,
+			Role:          user.Role,
+			EmailVerified: user.EmailVerifiedAt.Valid,
+			Scopes:        apiKey.Scopes,
+		}, nil
+	})
+
+	// Background jobs
+	jobs := scheduler.NewRunner()
+	jobs.Add(scheduler.Job{
+		Name:     "publish-scheduled-posts",
+		Interval: scheduledPublishInterval,
+		Run: func(ctx context.Context) error {
+			published, err := postService.PublishScheduled(ctx)
+			if published > 0 {
+				log.Printf("📅 Published %d scheduled post(s)", published)
+			}
+			return err
+		},
+	})
+
+	jobs.Add(scheduler.Job{
+		Name:     "delete-expired-mfa-challenges",
+		Interval: mfaCleanupInterval,
+		Run: func(ctx context.Context) error {
+			_, err := mfaService.DeleteExpired(ctx)
+			return err
+		},
+	})
+
+	jobs.Add(scheduler.Job{
+		Name:     "delete-expired-oidc-requests",
+		Interval: oidcCleanupInterval,
+		Run: func(ctx context.Context) error {
+			_, err := oidcService.DeleteExpired(ctx)
+			return err
+		},
+	})
+
+	retention, err := softDeleteRetention()
+	if err != nil {
+		return nil, err
+	}
+	// Bật ANONYMIZE_DELETED_USERS=true để giữ bài viết/bình luận của user bị purge dưới tên "[deleted user]"
+	anonymizeDeletedUsers := os.Getenv("ANONYMIZE_DELETED_USERS") == "true"
+	jobs.Add(scheduler.Job{
+		Name:     "purge-deleted",
+		Interval: purgeInterval,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().Add(-retention)
+			posts, err := postService.PurgeDeleted(ctx, cutoff)
+			if err != nil {
+				return err
+			}
+			users, err := userService.PurgeDeleted(ctx, cutoff, anonymizeDeletedUsers)
+			if posts > 0 || users > 0 {
+				log.Printf("🗑️ Purged %d deleted post(s) and %d deleted user(s)", posts, users)
+			}
+			return err
+		},
+	})
+
+	rateLimits, err := newRateLimitConfig(db)
+	if err != nil {
+		return nil, err
+	}
+	jobs.Add(scheduler.Job{
+		Name:     "evict-rate-limit-buckets",
+		Interval: rateLimitEvictInterval,
+		Run: func(ctx context.Context) error {
+			_, err := rateLimits.Store.Evict(ctx)
+			return err
+		},
+	})
 
-	postRepo := repository.NewPostRepository(db.GetQueries())
-	postService := service.NewPostService(postRepo)
-	postController := controller.NewPostController(postService)
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
 
-	authController := controller.NewAuthController(userService)
+	routeConfig := handlers.RouteConfig{
+		// Bật mặc định; đặt EMAIL_VERIFICATION_REQUIRED=false để tắt
+		RequireVerifiedEmail: os.Getenv("EMAIL_VERIFICATION_REQUIRED") != "false",
+		RateLimits:           rateLimits,
+	}
 
 	fmt.Printf("✅ Database connected successfully\n")
 	fmt.Printf("✅ All dependencies initialized\n")
 
 	return &Server{
-		port:           port,
-		db:             db,
-		UserRepository: userRepo,
-		UserService:    userService,
-		UserController: userController,
-		PostController: postController,
-		AuthController: authController,
+		port:              port,
+		db:                db,
+		jobs:              jobs,
+		routeConfig:       routeConfig,
+		trustedProxies:    trustedProxies,
+		UserRepository:    userRepo,
+		UserService:       userService,
+		UserController:    userController,
+		PostController:    postController,
+		CommentController: commentController,
+		SearchController:  searchController,
+		AuthController:    authController,
+		MeController:      meController,
+		APIKeyController:  apiKeyController,
+		AuditController:   auditController,
 	}, nil
 }
 
+// newRateLimitConfig builds the per-client rate limits. RATE_LIMIT_STORE=postgres
+// shares them between instances; the default keeps them in memory.
+func newRateLimitConfig(db database.Service) (handlers.RateLimitConfig, error) {
+	var cfg handlers.RateLimitConfig
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		cfg.Store = ratelimit.NewMemoryStore()
+	case "postgres":
+		cfg.Store = ratelimit.NewPostgresStore(db.GetQueries())
+	default:
+		return cfg, fmt.Errorf("unknown RATE_LIMIT_STORE %q", store)
+	}
+
+	var err error
+	if cfg.Auth, err = rateLimitPolicy("auth", "RATE_LIMIT_AUTH", defaultAuthRateLimit); err != nil {
+		return cfg, err
+	}
+	if cfg.Read, err = rateLimitPolicy("read", "RATE_LIMIT_READ", defaultReadRateLimit); err != nil {
+		return cfg, err
+	}
+	if cfg.Write, err = rateLimitPolicy("write", "RATE_LIMIT_WRITE", defaultWriteRateLimit); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func rateLimitPolicy(name, env, fallback string) (ratelimit.Policy, error) {
+	spec := os.Getenv(env)
+	if spec == "" {
+		spec = fallback
+	}
+	policy, err := ratelimit.ParsePolicy(name, spec)
+	if err != nil {
+		return policy, fmt.Errorf("%s: %w", env, err)
+	}
+	return policy, nil
+}
+
+// softDeleteRetention reads SOFT_DELETE_RETENTION as a Go duration, e.g. "720h"
+func softDeleteRetention() (time.Duration, error) {
+	value := os.Getenv("SOFT_DELETE_RETENTION")
+	if value == "" {
+		return defaultSoftDeleteRetention, nil
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("SOFT_DELETE_RETENTION: invalid duration %q", value)
+	}
+	return retention, nil
+}
+
+// parseTrustedProxies reads a comma-separated list of proxy IPs or CIDRs whose
+// X-Forwarded-For header is believed. Without it no proxy is trusted.
+func parseTrustedProxies(value string) ([]string, error) {
+	var proxies []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: invalid address %q", p)
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, nil
+}
+
+// newOIDCProviders reads the external identity providers. OIDC_PROVIDERS lists
+// their names, e.g. "google,gitlab"; each name needs OIDC_<NAME>_ISSUER,
+// OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET, and may set
+// OIDC_<NAME>_REDIRECT_URL and OIDC_<NAME>_SCOPES (space separated).
+func newOIDCProviders() ([]*oidc.Provider, error) {
+	redirectBase := os.Getenv("OIDC_REDIRECT_BASE_URL")
+	if redirectBase == "" {
+		redirectBase = defaultOIDCRedirectBase
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	var providers []*oidc.Provider
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		cfg := oidc.Config{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("oidc provider %q: %sISSUER and %sCLIENT_ID are required", name, prefix, prefix)
+		}
+		if cfg.RedirectURL == "" {
+			cfg.RedirectURL = fmt.Sprintf("%s/%s/callback", strings.TrimSuffix(redirectBase, "/"), name)
+		}
+		providers = append(providers, oidc.NewProvider(cfg, client))
+	}
+	return providers, nil
+}
+
+// newMailer logs outgoing mail to MAIL_LOG_FILE when set, otherwise to stdout
+func newMailer() (mailer.Mailer, error) {
+	path := os.Getenv("MAIL_LOG_FILE")
+	if path == "" {
+		return mailer.NewLogMailer(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mail log: %w", err)
+	}
+	return mailer.NewLogMailer(f), nil
+}
+
 func (s *Server) Start() error {
 	router := s.RegisterRoutes()
 
@@ -80,6 +421,8 @@ func (s *Server) Start() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	s.jobs.Start()
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -97,6 +440,9 @@ func (s *Server) Start() error {
 			log.Printf("❌ Server forced to shutdown: %v", err)
 		}
 
+		// Stop background jobs before the database goes away
+		s.jobs.Stop()
+
 		// Close database connection
 		if err := s.db.Close(); err != nil {
 			log.Printf("❌ Database close error: %v", err)
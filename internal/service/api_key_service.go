@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// APIKeyPrefix starts every API key so leaked keys are easy to recognise
+const APIKeyPrefix = "mpk_"
+
+const (
+	apiKeyTokenBytes = 32
+	// apiKeyVisibleLen is how much of the key is stored in clear, prefix included
+	apiKeyVisibleLen = 12
+	// apiKeyTouchInterval limits how often last_used_at is written for a busy key
+	apiKeyTouchInterval = time.Minute
+)
+
+// ErrInvalidAPIKey covers unknown and expired API keys alike
+var ErrInvalidAPIKey = apperrors.NewUnauthorizedError("invalid or expired api key")
+
+// CreatedAPIKey is a new key together with its secret, which is only shown once
+type CreatedAPIKey struct {
+	sqlc.ApiKey
+	Key string `json:"key"`
+}
+
+// APIKeyService manages personal API keys and authenticates requests made with them
+type APIKeyService interface {
+	Create(ctx context.Context, userID int32, name string, scopes []string, expiresAt *time.Time) (CreatedAPIKey, error)
+	List(ctx context.Context, userID int32) ([]sqlc.ApiKey, error)
+	Delete(ctx context.Context, userID, id int32) error
+	Authenticate(ctx context.Context, key string) (sqlc.ApiKey, sqlc.User, error)
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	userRepo   repository.UserRepository
+}
+
+// NewAPIKeyService creates a new APIKeyService instance
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, userRepo repository.UserRepository) APIKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo, userRepo: userRepo}
+}
+
+// Create issues a key limited to scopes, valid until expiresAt when given
+func (s *apiKeyService) Create(ctx context.Context, userID int32, name string, scopes []string, expiresAt *time.Time) (CreatedAPIKey, error) {
+	if len(scopes) == 0 {
+		return CreatedAPIKey{}, apperrors.NewValidationError("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !permissions.IsValidScope(scope) {
+			return CreatedAPIKey{}, apperrors.NewValidationError("unknown scope: " + scope)
+		}
+	}
+	var expires sql.NullTime
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return CreatedAPIKey{}, apperrors.NewValidationError("expires_at must be in the future")
+		}
+		expires = sql.NullTime{Time: *expiresAt, Valid: true}
+	}
+
+	token, err := utils.GenerateOpaqueToken(apiKeyTokenBytes)
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	key := APIKeyPrefix + token
+
+	apiKey, err := s.apiKeyRepo.Create(ctx, sqlc.CreateAPIKeyParams{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyVisibleLen],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return CreatedAPIKey{}, apperrors.FromDB(err, "api key")
+	}
+	return CreatedAPIKey{ApiKey: apiKey, Key: key}, nil
+}
+
+// List returns the user's keys, newest first
+func (s *apiKeyService) List(ctx context.Context, userID int32) ([]sqlc.ApiKey, error) {
+	return s.apiKeyRepo.ListByUser(ctx, userID)
+}
+
+// Delete revokes one of the user's keys
+func (s *apiKeyService) Delete(ctx context.Context, userID, id int32) error {
+	return apperrors.FromDB(s.apiKeyRepo.Delete(ctx, userID, id), "api key")
+}
+
+// Authenticate resolves a key to its owner and records the use
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (sqlc.ApiKey, sqlc.User, error) {
+	apiKey, err := s.apiKeyRepo.GetByHash(ctx, utils.HashToken(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.ApiKey{}, sqlc.User{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return sqlc.ApiKey{}, sqlc.User{}, err
+	}
+	if apiKey.ExpiresAt.Valid && !apiKey.ExpiresAt.Time.After(time.Now()) {
+		return sqlc.ApiKey{}, sqlc.User{}, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The owner was deleted
+		return sqlc.ApiKey{}, sqlc.User{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return sqlc.ApiKey{}, sqlc.User{}, err
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return sqlc.ApiKey{}, sqlc.User{}, err
+	}
+
+	if !apiKey.LastUsedAt.Valid || time.Since(apiKey.LastUsedAt.Time) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.Touch(ctx, apiKey.ID); err != nil {
+			return sqlc.ApiKey{}, sqlc.User{}, err
+		}
+	}
+	return apiKey, user, nil
+}
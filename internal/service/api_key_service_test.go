@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/repository"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAPIKeyService(repository.NewAPIKeyRepository(testDB.GetQueries()), repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries()))
+
+	owner := createTestUser(t, "keyowner", permissions.RoleUser)
+	other := createTestUser(t, "keyother", permissions.RoleUser)
+
+	_, err := svc.Create(ctx, owner.ID, "ci", []string{"posts:everything"}, nil)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	created, err := svc.Create(ctx, owner.ID, "ci", []string{permissions.ScopePostsWrite}, nil)
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, APIKeyPrefix) || !strings.HasPrefix(created.Key, created.Prefix) {
+		t.Errorf("expected key %q to start with its prefix %q", created.Key, created.Prefix)
+	}
+
+	apiKey, user, err := svc.Authenticate(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("failed to authenticate: %v", err)
+	}
+	if user.ID != owner.ID || !apiKey.LastUsedAt.Valid {
+		t.Errorf("expected the owner and last_used_at to be set, got user %d, %+v", user.ID, apiKey.LastUsedAt)
+	}
+
+	_, _, err = svc.Authenticate(ctx, created.Key+"x")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	// Only the owner can revoke the key
+	assertStatus(t, svc.Delete(ctx, other.ID, created.ID), http.StatusNotFound)
+	if err := svc.Delete(ctx, owner.ID, created.ID); err != nil {
+		t.Fatalf("failed to delete api key: %v", err)
+	}
+	_, _, err = svc.Authenticate(ctx, created.Key)
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = svc.Create(ctx, owner.ID, "old", []string{permissions.ScopePostsRead}, &past)
+	assertStatus(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+// Audited actions
+const (
+	AuditLogin          = "auth.login"
+	AuditLoginFailed    = "auth.login_failed"
+	AuditRegister       = "auth.register"
+	AuditLogout         = "auth.logout"
+	AuditUserCreate     = "user.create"
+	AuditUserUpdate     = "user.update"
+	AuditUserRoleChange = "user.role_change"
+	AuditUserDelete     = "user.delete"
+	AuditUserRestore    = "user.restore"
+	AuditUserSuspend    = "user.suspend"
+	AuditUserUnsuspend  = "user.unsuspend"
+	AuditUserLogout     = "user.logout"
+	AuditUserUnlock     = "user.unlock"
+	AuditPostUpdate     = "post.update"
+	AuditPostDelete     = "post.delete"
+	AuditPostRestore    = "post.restore"
+)
+
+// Kinds of audit targets
+const (
+	AuditTargetUser = "user"
+	AuditTargetPost = "post"
+)
+
+// AuditEvent is one entry to add to the audit log
+type AuditEvent struct {
+	Action string
+	// ActorID is 0 when nobody was signed in, e.g. a failed login
+	ActorID    int32
+	TargetType string
+	TargetID   int32
+	IPAddress  string
+	UserAgent  string
+	RequestID  string
+	// Before and After are snapshots of the target, stored as JSON; nil is stored as null
+	Before   any
+	After    any
+	Metadata map[string]any
+}
+
+// AuditFilter selects audit events, newest first; zero fields match everything
+type AuditFilter struct {
+	ActorID    int32
+	Action     string
+	TargetType string
+	TargetID   int32
+	From       time.Time
+	To         time.Time
+	// BeforeID continues a listing below the last event seen
+	BeforeID int64
+	Limit    int32
+}
+
+// AuditLogger writes and reads the append-only audit log
+type AuditLogger interface {
+	Record(ctx context.Context, event AuditEvent)
+	List(ctx context.Context, filter AuditFilter) ([]sqlc.AuditEvent, error)
+}
+
+type auditLogger struct {
+	repo repository.AuditRepository
+}
+
+// NewAuditLogger creates a new AuditLogger backed by the audit_events table
+func NewAuditLogger(repo repository.AuditRepository) AuditLogger {
+	return &auditLogger{repo: repo}
+}
+
+// Record adds an event to the audit log. The audited action has already happened
+// by then, so a failure is logged instead of failing the request.
+func (s *auditLogger) Record(ctx context.Context, event AuditEvent) {
+	arg := sqlc.CreateAuditEventParams{
+		Action:     event.Action,
+		ActorID:    sql.NullInt32{Int32: event.ActorID, Valid: event.ActorID != 0},
+		TargetType: sql.NullString{String: event.TargetType, Valid: event.TargetType != ""},
+		TargetID:   sql.NullInt32{Int32: event.TargetID, Valid: event.TargetID != 0},
+		IpAddress:  event.IPAddress,
+		UserAgent:  event.UserAgent,
+		RequestID:  event.RequestID,
+	}
+
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	var err error
+	if arg.BeforeState, err = json.Marshal(event.Before); err == nil {
+		if arg.AfterState, err = json.Marshal(event.After); err == nil {
+			arg.Metadata, err = json.Marshal(metadata)
+		}
+	}
+	if err == nil {
+		// Chạy cả khi client đã ngắt kết nối: hành động đã xảy ra nên vẫn phải ghi lại
+		_, err = s.repo.Create(context.WithoutCancel(ctx), arg)
+	}
+	if err != nil {
+		log.Printf("[RequestID=%s] failed to record audit event %s by %d: %v", event.RequestID, event.Action, event.ActorID, err)
+	}
+}
+
+// List returns audit events matching the filter
+func (s *auditLogger) List(ctx context.Context, filter AuditFilter) ([]sqlc.AuditEvent, error) {
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, apperrors.NewValidationError("from must be before to")
+	}
+
+	arg := sqlc.ListAuditEventsParams{
+		BeforeID:   sql.NullInt64{Int64: filter.BeforeID, Valid: filter.BeforeID != 0},
+		ActorID:    sql.NullInt32{Int32: filter.ActorID, Valid: filter.ActorID != 0},
+		Action:     sql.NullString{String: filter.Action, Valid: filter.Action != ""},
+		TargetType: sql.NullString{String: filter.TargetType, Valid: filter.TargetType != ""},
+		TargetID:   sql.NullInt32{Int32: filter.TargetID, Valid: filter.TargetID != 0},
+		Since:      sql.NullTime{Time: filter.From, Valid: !filter.From.IsZero()},
+		Until:      sql.NullTime{Time: filter.To, Valid: !filter.To.IsZero()},
+		PageLimit:  filter.Limit,
+	}
+	return s.repo.List(ctx, arg)
+}
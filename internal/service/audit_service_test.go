@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/repository"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	audit := NewAuditLogger(repository.NewAuditRepository(testDB.GetQueries()))
+	admin := createTestUser(t, "auditor", permissions.RoleAdmin)
+	target := createTestUser(t, "audited", permissions.RoleUser)
+
+	audit.Record(ctx, AuditEvent{
+		Action:     AuditUserRoleChange,
+		ActorID:    admin.ID,
+		TargetType: AuditTargetUser,
+		TargetID:   target.ID,
+		IPAddress:  "203.0.113.9",
+		UserAgent:  "test-agent",
+		RequestID:  "req-1",
+		Before:     map[string]string{"role": permissions.RoleUser},
+		After:      map[string]string{"role": permissions.RoleModerator},
+	})
+	audit.Record(ctx, AuditEvent{
+		Action:     AuditUserDelete,
+		ActorID:    admin.ID,
+		TargetType: AuditTargetUser,
+		TargetID:   target.ID,
+		RequestID:  "req-2",
+	})
+	audit.Record(ctx, AuditEvent{
+		Action:    AuditLoginFailed,
+		IPAddress: "203.0.113.9",
+		Metadata:  map[string]any{"email": target.Email},
+	})
+
+	events, err := audit.List(ctx, AuditFilter{ActorID: admin.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != AuditUserDelete || events[1].Action != AuditUserRoleChange {
+		t.Fatalf("expected the admin's two events newest first, got %+v", events)
+	}
+
+	change := events[1]
+	var before, after map[string]string
+	if err := json.Unmarshal(change.BeforeState, &before); err != nil || before["role"] != permissions.RoleUser {
+		t.Errorf("unexpected before state %s (%v)", change.BeforeState, err)
+	}
+	if err := json.Unmarshal(change.AfterState, &after); err != nil || after["role"] != permissions.RoleModerator {
+		t.Errorf("unexpected after state %s (%v)", change.AfterState, err)
+	}
+	if change.IpAddress != "203.0.113.9" || change.UserAgent != "test-agent" || change.RequestID != "req-1" {
+		t.Errorf("request context not recorded: %+v", change)
+	}
+	if string(events[0].BeforeState) != "null" || string(events[0].Metadata) != "{}" {
+		t.Errorf("expected empty snapshots as null and {}, got %s and %s", events[0].BeforeState, events[0].Metadata)
+	}
+
+	// Paging continues below the last id seen
+	page, err := audit.List(ctx, AuditFilter{ActorID: admin.ID, BeforeID: events[0].ID, Limit: 10})
+	if err != nil || len(page) != 1 || page[0].ID != change.ID {
+		t.Errorf("expected only the older event after the cursor, got %+v (%v)", page, err)
+	}
+
+	byTarget, err := audit.List(ctx, AuditFilter{Action: AuditUserDelete, TargetType: AuditTargetUser, TargetID: target.ID, Limit: 10})
+	if err != nil || len(byTarget) != 1 || byTarget[0].ID != events[0].ID {
+		t.Errorf("expected the delete event by target, got %+v (%v)", byTarget, err)
+	}
+
+	future, err := audit.List(ctx, AuditFilter{ActorID: admin.ID, From: time.Now().Add(time.Hour), Limit: 10})
+	if err != nil || len(future) != 0 {
+		t.Errorf("expected no events after from, got %+v (%v)", future, err)
+	}
+	_, err = audit.List(ctx, AuditFilter{From: time.Now(), To: time.Now().Add(-time.Hour), Limit: 10})
+	assertStatus(t, err, http.StatusBadRequest)
+
+	// Rows are append-only, even for the table owner
+	if _, err := testDB.GetDB().ExecContext(ctx, `UPDATE audit_events SET action = 'tampered' WHERE id = $1`, change.ID); err == nil {
+		t.Error("expected updating an audit event to fail")
+	}
+	if _, err := testDB.GetDB().ExecContext(ctx, `DELETE FROM audit_events WHERE id = $1`, change.ID); err == nil {
+		t.Error("expected deleting an audit event to fail")
+	}
+	if _, err := testDB.GetDB().ExecContext(ctx, `TRUNCATE audit_events`); err == nil {
+		t.Error("expected truncating the audit log to fail")
+	}
+
+	// Purging a user keeps the events that mention them
+	if err := newTestUserService().DeleteUser(ctx, int64(target.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if _, err := testDB.GetQueries().DeleteUser(ctx, target.ID); err != nil {
+		t.Fatalf("failed to purge user: %v", err)
+	}
+	kept, err := audit.List(ctx, AuditFilter{TargetType: AuditTargetUser, TargetID: target.ID, Limit: 10})
+	if err != nil || len(kept) != 2 {
+		t.Errorf("expected both events about the purged user to stay, got %+v (%v)", kept, err)
+	}
+}
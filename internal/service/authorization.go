@@ -0,0 +1,26 @@
+package service
+
+import (
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+)
+
+// Actor is the authenticated user performing an operation
+type Actor struct {
+	UserID int32
+	Role   string
+}
+
+// Can reports whether the actor's role grants the permission
+func (a Actor) Can(perm permissions.Permission) bool {
+	return permissions.Has(a.Role, perm)
+}
+
+// authorizeOwner allows the owner of a resource, or anyone holding the
+// moderate permission for that resource type, to mutate it.
+func authorizeOwner(actor Actor, ownerID int32, moderate permissions.Permission, resource string) error {
+	if actor.UserID == ownerID || actor.Can(moderate) {
+		return nil
+	}
+	return apperrors.NewForbiddenError("You are not allowed to modify this " + resource)
+}
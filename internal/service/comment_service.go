@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+// MaxCommentDepth is the deepest reply level allowed; top-level comments have depth 0
+const MaxCommentDepth = 5
+
+// CommentNode is a comment with its replies nested beneath it
+type CommentNode struct {
+	sqlc.ListCommentThreadByPostRow
+	Replies []*CommentNode `json:"replies"`
+}
+
+// CommentService defines the business logic for comments
+type CommentService interface {
+	CreateComment(ctx context.Context, arg sqlc.CreateCommentParams) (sqlc.Comment, error)
+	ListComments(ctx context.Context, postID int32, limit, offset int32) ([]sqlc.ListCommentsByPostRow, error)
+	ListCommentTree(ctx context.Context, postID int32, limit, offset int32) ([]*CommentNode, error)
+	UpdateComment(ctx context.Context, actor Actor, arg sqlc.UpdateCommentParams) (sqlc.Comment, error)
+	DeleteComment(ctx context.Context, actor Actor, id int32) error
+}
+
+type commentService struct {
+	commentRepo repository.CommentRepository
+	postRepo    repository.PostRepository
+}
+
+// NewCommentService creates a new CommentService instance
+func NewCommentService(commentRepo repository.CommentRepository, postRepo repository.PostRepository) CommentService {
+	return &commentService{commentRepo: commentRepo, postRepo: postRepo}
+}
+
+func (s *commentService) CreateComment(ctx context.Context, arg sqlc.CreateCommentParams) (sqlc.Comment, error) {
+	if err := s.ensurePostExists(ctx, arg.PostID); err != nil {
+		return sqlc.Comment{}, err
+	}
+	if arg.ParentID.Valid {
+		if err := s.validateParent(ctx, arg.PostID, arg.ParentID.Int32); err != nil {
+			return sqlc.Comment{}, err
+		}
+	}
+	comment, err := s.commentRepo.Create(ctx, arg)
+	return comment, apperrors.FromDB(err, "comment")
+}
+
+func (s *commentService) ListComments(ctx context.Context, postID int32, limit, offset int32) ([]sqlc.ListCommentsByPostRow, error) {
+	if err := s.ensurePostExists(ctx, postID); err != nil {
+		return nil, err
+	}
+	return s.commentRepo.ListByPost(ctx, postID, limit, offset)
+}
+
+// ListCommentTree paginates top-level comments and nests their replies
+func (s *commentService) ListCommentTree(ctx context.Context, postID int32, limit, offset int32) ([]*CommentNode, error) {
+	if err := s.ensurePostExists(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.commentRepo.ListThreadByPost(ctx, sqlc.ListCommentThreadByPostParams{
+		PostID:     postID,
+		RootLimit:  limit,
+		RootOffset: offset,
+		MaxDepth:   MaxCommentDepth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildCommentTree(rows), nil
+}
+
+func (s *commentService) UpdateComment(ctx context.Context, actor Actor, arg sqlc.UpdateCommentParams) (sqlc.Comment, error) {
+	if _, err := s.authorizedComment(ctx, actor, arg.ID); err != nil {
+		return sqlc.Comment{}, err
+	}
+	comment, err := s.commentRepo.Update(ctx, arg)
+	return comment, apperrors.FromDB(err, "comment")
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, actor Actor, id int32) error {
+	if _, err := s.authorizedComment(ctx, actor, id); err != nil {
+		return err
+	}
+	return apperrors.FromDB(s.commentRepo.Delete(ctx, id), "comment")
+}
+
+// validateParent checks that a reply targets a comment on the same post and
+// would not exceed MaxCommentDepth
+func (s *commentService) validateParent(ctx context.Context, postID, parentID int32) error {
+	parent, err := s.commentRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return apperrors.FromDB(err, "parent comment")
+	}
+	if parent.PostID != postID {
+		return apperrors.NewValidationError("parent comment belongs to a different post")
+	}
+
+	depth, err := s.commentRepo.GetDepth(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if depth+1 > MaxCommentDepth {
+		return apperrors.NewValidationError(fmt.Sprintf("replies cannot be nested deeper than %d levels", MaxCommentDepth))
+	}
+	return nil
+}
+
+// ensurePostExists checks that comments can be read or written on the post;
+// drafts and archived posts are treated as missing
+func (s *commentService) ensurePostExists(ctx context.Context, postID int32) error {
+	post, err := s.postRepo.GetByID(ctx, postID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && post.Status != PostStatusPublished) {
+		return apperrors.NewNotFoundError("post not found")
+	}
+	return err
+}
+
+// authorizedComment loads a comment and checks that the actor may mutate it
+func (s *commentService) authorizedComment(ctx context.Context, actor Actor, id int32) (sqlc.Comment, error) {
+	comment, err := s.commentRepo.GetByID(ctx, id)
+	if err != nil {
+		return sqlc.Comment{}, apperrors.FromDB(err, "comment")
+	}
+
+	if err := authorizeOwner(actor, comment.UserID, permissions.CommentsModerate, "comment"); err != nil {
+		return sqlc.Comment{}, err
+	}
+	return comment, nil
+}
+
+// buildCommentTree nests rows that arrive in path order under their parents
+func buildCommentTree(rows []sqlc.ListCommentThreadByPostRow) []*CommentNode {
+	roots := []*CommentNode{}
+	nodes := make(map[int32]*CommentNode, len(rows))
+
+	for _, row := range rows {
+		node := &CommentNode{ListCommentThreadByPostRow: row, Replies: []*CommentNode{}}
+		nodes[row.ID] = node
+
+		if parent, ok := nodes[row.ParentID.Int32]; row.ParentID.Valid && ok {
+			parent.Replies = append(parent.Replies, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
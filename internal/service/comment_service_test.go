@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+func newTestCommentService() (CommentService, PostService) {
+	postRepo := repository.NewPostRepository(testDB.GetDB(), testDB.GetQueries())
+	return NewCommentService(repository.NewCommentRepository(testDB.GetQueries()), postRepo), NewPostService(postRepo, NewAuditLogger(repository.NewAuditRepository(testDB.GetQueries())))
+}
+
+func TestCommentOwnership(t *testing.T) {
+	ctx := context.Background()
+	comments, posts := newTestCommentService()
+	author := createTestUser(t, "commenter", permissions.RoleUser)
+	other := createTestUser(t, "bystander", permissions.RoleUser)
+	moderator := createTestUser(t, "commentmod", permissions.RoleModerator)
+
+	post, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "t", Content: "c"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	comment, err := comments.CreateComment(ctx, sqlc.CreateCommentParams{PostID: post.ID, UserID: author.ID, Content: "first"})
+	if err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+
+	authorActor := Actor{UserID: author.ID, Role: author.Role}
+	otherActor := Actor{UserID: other.ID, Role: other.Role}
+	modActor := Actor{UserID: moderator.ID, Role: moderator.Role}
+
+	_, err = comments.UpdateComment(ctx, otherActor, sqlc.UpdateCommentParams{ID: comment.ID, Content: "hijacked"})
+	assertStatus(t, err, http.StatusForbidden)
+	assertStatus(t, comments.DeleteComment(ctx, otherActor, comment.ID), http.StatusForbidden)
+
+	updated, err := comments.UpdateComment(ctx, authorActor, sqlc.UpdateCommentParams{ID: comment.ID, Content: "edited"})
+	if err != nil || updated.Content != "edited" || !updated.UpdatedAt.Valid {
+		t.Fatalf("expected the author to edit the comment, got %+v (%v)", updated, err)
+	}
+	if _, err := comments.UpdateComment(ctx, modActor, sqlc.UpdateCommentParams{ID: comment.ID, Content: "moderated"}); err != nil {
+		t.Errorf("expected a moderator to edit any comment: %v", err)
+	}
+
+	if err := comments.DeleteComment(ctx, modActor, comment.ID); err != nil {
+		t.Fatalf("expected a moderator to delete any comment: %v", err)
+	}
+	assertStatus(t, comments.DeleteComment(ctx, authorActor, comment.ID), http.StatusNotFound)
+}
+
+func TestCommentReplies(t *testing.T) {
+	ctx := context.Background()
+	comments, posts := newTestCommentService()
+	user := createTestUser(t, "replier", permissions.RoleUser)
+
+	post, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: user.ID, Title: "thread", Content: "c"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	otherPost, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: user.ID, Title: "elsewhere", Content: "c"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	reply := func(postID, parentID int32, content string) (sqlc.Comment, error) {
+		arg := sqlc.CreateCommentParams{PostID: postID, UserID: user.ID, Content: content}
+		if parentID != 0 {
+			arg.ParentID = sql.NullInt32{Int32: parentID, Valid: true}
+		}
+		return comments.CreateComment(ctx, arg)
+	}
+
+	root, err := reply(post.ID, 0, "root")
+	if err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+
+	_, err = reply(otherPost.ID, root.ID, "cross-post")
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = reply(post.ID, 999999, "orphan")
+	assertStatus(t, err, http.StatusNotFound)
+
+	// A chain root -> 1 -> ... -> MaxCommentDepth is allowed, one more level is not
+	parent := root
+	chain := []sqlc.Comment{root}
+	for depth := 1; depth <= MaxCommentDepth; depth++ {
+		if parent, err = reply(post.ID, parent.ID, "nested"); err != nil {
+			t.Fatalf("failed to reply at depth %d: %v", depth, err)
+		}
+		chain = append(chain, parent)
+	}
+	_, err = reply(post.ID, parent.ID, "too deep")
+	assertStatus(t, err, http.StatusBadRequest)
+
+	sibling, err := reply(post.ID, root.ID, "second reply")
+	if err != nil {
+		t.Fatalf("failed to reply: %v", err)
+	}
+
+	tree, err := comments.ListCommentTree(ctx, post.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list tree: %v", err)
+	}
+	if len(tree) != 1 || tree[0].ID != root.ID {
+		t.Fatalf("expected a single root, got %+v", tree)
+	}
+	if replies := tree[0].Replies; len(replies) != 2 || replies[0].ID != chain[1].ID || replies[1].ID != sibling.ID {
+		t.Fatalf("expected replies in creation order, got %+v", replies)
+	}
+
+	// Deleting a comment keeps its replies, which move up to the top level
+	owner := Actor{UserID: user.ID, Role: user.Role}
+	if err := comments.DeleteComment(ctx, owner, root.ID); err != nil {
+		t.Fatalf("failed to delete comment: %v", err)
+	}
+	tree, err = comments.ListCommentTree(ctx, post.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list tree: %v", err)
+	}
+	if len(tree) != 2 || tree[0].ID != chain[1].ID || tree[1].ID != sibling.ID {
+		t.Fatalf("expected the orphaned replies as roots, got %+v", tree)
+	}
+	if len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != chain[2].ID {
+		t.Errorf("expected the rest of the chain to stay nested, got %+v", tree[0].Replies)
+	}
+}
+
+func TestBuildCommentTree(t *testing.T) {
+	row := func(id, parentID int32) sqlc.ListCommentThreadByPostRow {
+		return sqlc.ListCommentThreadByPostRow{ID: id, ParentID: sql.NullInt32{Int32: parentID, Valid: parentID != 0}}
+	}
+	// Path order: 1, 1/2, 1/2/4, 1/3, 5; 6 replies to a comment outside the page
+	tree := buildCommentTree([]sqlc.ListCommentThreadByPostRow{
+		row(1, 0), row(2, 1), row(4, 2), row(3, 1), row(5, 0), row(6, 99),
+	})
+
+	if len(tree) != 3 || tree[0].ID != 1 || tree[1].ID != 5 || tree[2].ID != 6 {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+	first := tree[0]
+	if len(first.Replies) != 2 || first.Replies[0].ID != 2 || first.Replies[1].ID != 3 {
+		t.Fatalf("unexpected replies of 1: %+v", first.Replies)
+	}
+	if len(first.Replies[0].Replies) != 1 || first.Replies[0].Replies[0].ID != 4 {
+		t.Errorf("unexpected replies of 2: %+v", first.Replies[0].Replies)
+	}
+	if tree[1].Replies == nil || len(tree[1].Replies) != 0 {
+		t.Errorf("expected an empty, non-nil reply list, got %#v", tree[1].Replies)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/mailer"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// EmailVerificationTokenTTL is how long a verification link stays valid
+const EmailVerificationTokenTTL = 24 * time.Hour
+
+// VerificationResendInterval is the minimum wait between two verification emails to one user
+const VerificationResendInterval = time.Minute
+
+const emailVerificationTokenBytes = 32
+
+// ErrInvalidVerificationToken covers unknown, already used and expired verification tokens alike
+var ErrInvalidVerificationToken = apperrors.NewBadRequestError("invalid or expired verification token")
+
+// EmailVerificationService confirms that users own the email they registered with, or change to
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user sqlc.User) error
+	RequestEmailChange(ctx context.Context, user sqlc.User, newEmail string) error
+	Verify(ctx context.Context, token string) (sqlc.User, error)
+	Resend(ctx context.Context, userID int32) error
+}
+
+type emailVerificationService struct {
+	verificationRepo repository.EmailVerificationRepository
+	userRepo         repository.UserRepository
+	mailer           mailer.Mailer
+	verifyURL        string
+}
+
+// NewEmailVerificationService creates a new EmailVerificationService; verifyURL is
+// the page that receives the token as ?token=
+func NewEmailVerificationService(verificationRepo repository.EmailVerificationRepository, userRepo repository.UserRepository, m mailer.Mailer, verifyURL string) EmailVerificationService {
+	return &emailVerificationService{verificationRepo: verificationRepo, userRepo: userRepo, mailer: m, verifyURL: verifyURL}
+}
+
+// SendVerification emails a fresh verification link; older links stop working.
+// Delivery failures are logged rather than returned, the user can ask for a resend.
+func (s *emailVerificationService) SendVerification(ctx context.Context, user sqlc.User) error {
+	token, err := s.createToken(ctx, user.ID, sql.NullString{})
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, user.ID, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %d hours.\n\n%s?token=%s",
+			user.Username, int(EmailVerificationTokenTTL.Hours()), s.verifyURL, token),
+	})
+	return nil
+}
+
+// RequestEmailChange emails a link to newEmail; the account switches to it once
+// the link is opened. The current address is told about the request.
+func (s *emailVerificationService) RequestEmailChange(ctx context.Context, user sqlc.User, newEmail string) error {
+	token, err := s.createToken(ctx, user.ID, sql.NullString{String: newEmail, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	s.send(ctx, user.ID, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your account to %s. Nothing changes until the new address is confirmed.\n\nIf this was not you, change your password.",
+			user.Username, newEmail),
+	})
+	s.send(ctx, user.ID, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to use this address for your account. It expires in %d hours.\n\n%s?token=%s",
+			user.Username, int(EmailVerificationTokenTTL.Hours()), s.verifyURL, token),
+	})
+	return nil
+}
+
+func (s *emailVerificationService) createToken(ctx context.Context, userID int32, newEmail sql.NullString) (string, error) {
+	token, err := utils.GenerateOpaqueToken(emailVerificationTokenBytes)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.verificationRepo.Create(ctx, sqlc.CreateEmailVerificationTokenParams{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(EmailVerificationTokenTTL),
+		NewEmail:  newEmail,
+	})
+	return token, err
+}
+
+func (s *emailVerificationService) send(ctx context.Context, userID int32, msg mailer.Message) {
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", userID, err)
+	}
+}
+
+// Verify marks the email of the token's owner as verified, or completes a change of address
+func (s *emailVerificationService) Verify(ctx context.Context, token string) (sqlc.User, error) {
+	user, err := s.verificationRepo.Verify(ctx, utils.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, ErrInvalidVerificationToken
+	}
+	if _, taken := apperrors.UniqueViolation(err); taken {
+		// Another account registered the new address after the change was requested
+		return sqlc.User{}, apperrors.NewConflictError("this email address is already in use").Wrap(err)
+	}
+	return user, err
+}
+
+// Resend sends a new verification email, at most once per VerificationResendInterval
+func (s *emailVerificationService) Resend(ctx context.Context, userID int32) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return apperrors.FromDB(err, "user")
+	}
+	if user.EmailVerifiedAt.Valid {
+		return apperrors.NewConflictError("email is already verified")
+	}
+
+	latest, err := s.verificationRepo.Latest(ctx, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		if wait := time.Until(latest.CreatedAt.Add(VerificationResendInterval)); wait > 0 {
+			return apperrors.NewTooManyRequestsError("a verification email was sent recently, try again later").
+				WithDetails(map[string]int{"retry_after": int(wait.Seconds()) + 1})
+		}
+	}
+
+	return s.SendVerification(ctx, user)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/repository"
+)
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	mail := &recordingMailer{}
+	svc := NewEmailVerificationService(
+		repository.NewEmailVerificationRepository(testDB.GetDB(), testDB.GetQueries()),
+		userRepo, mail, "http://localhost/verify",
+	)
+
+	user := createTestUser(t, "unverified", permissions.RoleUser)
+	if user.EmailVerifiedAt.Valid {
+		t.Fatalf("expected new users to start unverified")
+	}
+
+	if err := svc.SendVerification(ctx, user); err != nil {
+		t.Fatalf("failed to send verification: %v", err)
+	}
+	token := mail.lastToken(t)
+
+	// A resend right after the first email is throttled
+	assertStatus(t, svc.Resend(ctx, user.ID), http.StatusTooManyRequests)
+
+	verified, err := svc.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if !verified.EmailVerifiedAt.Valid {
+		t.Errorf("expected email_verified_at to be set")
+	}
+
+	_, err = svc.Verify(ctx, token)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	assertStatus(t, svc.Resend(ctx, user.ID), http.StatusConflict)
+}
+
+func TestEmailChange(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	mail := &recordingMailer{}
+	svc := NewEmailVerificationService(
+		repository.NewEmailVerificationRepository(testDB.GetDB(), testDB.GetQueries()),
+		userRepo, mail, "http://localhost/verify",
+	)
+	users := newTestUserService()
+
+	user := createTestUserWithPassword(t, "mover")
+	createTestUser(t, "occupant", permissions.RoleUser)
+
+	_, err := users.CheckEmailChange(ctx, user.ID, "wrong", "moved@example.com")
+	assertStatus(t, err, http.StatusForbidden)
+	_, err = users.CheckEmailChange(ctx, user.ID, "password", "occupant@example.com")
+	assertStatus(t, err, http.StatusConflict)
+
+	if _, err := users.CheckEmailChange(ctx, user.ID, "password", "moved@example.com"); err != nil {
+		t.Fatalf("expected the change to be allowed: %v", err)
+	}
+	if err := svc.RequestEmailChange(ctx, user, "moved@example.com"); err != nil {
+		t.Fatalf("failed to request email change: %v", err)
+	}
+	if len(mail.sent) != 2 || mail.sent[0].To != user.Email || mail.sent[1].To != "moved@example.com" {
+		t.Fatalf("expected a notice to the old address and a link to the new one, got %+v", mail.sent)
+	}
+
+	// Nothing changes until the link is opened
+	unchanged, _ := userRepo.GetByID(ctx, user.ID)
+	if unchanged.Email != user.Email {
+		t.Errorf("expected the email to stay %s until confirmed, got %s", user.Email, unchanged.Email)
+	}
+
+	changed, err := svc.Verify(ctx, mail.lastToken(t))
+	if err != nil {
+		t.Fatalf("failed to confirm email change: %v", err)
+	}
+	if changed.Email != "moved@example.com" || !changed.EmailVerifiedAt.Valid {
+		t.Errorf("expected the new email to be set and verified, got %+v", changed)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+// Scopes of the failed login counters
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+// LoginThrottlePolicy decides how failed logins for one key are slowed down and locked out
+type LoginThrottlePolicy struct {
+	// FreeAttempts failures are allowed before any delay applies
+	FreeAttempts int
+	// BaseDelay is the wait after the first delayed failure, doubling up to MaxDelay; zero disables delays
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxFailures failures within Window lock the key for LockoutDuration
+	MaxFailures     int
+	LockoutDuration time.Duration
+	Window          time.Duration
+}
+
+// DefaultAccountLoginPolicy applies to each email address
+var DefaultAccountLoginPolicy = LoginThrottlePolicy{
+	FreeAttempts:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        30 * time.Second,
+	MaxFailures:     10,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// DefaultIPLoginPolicy applies to each client IP. It has no delays and a high
+// limit because many users can share one address.
+var DefaultIPLoginPolicy = LoginThrottlePolicy{
+	MaxFailures:     100,
+	LockoutDuration: 15 * time.Minute,
+	Window:          time.Hour,
+}
+
+// LoginThrottleService tracks failed logins per account and per IP
+type LoginThrottleService interface {
+	Check(ctx context.Context, email, ipAddress string) error
+	RecordFailure(ctx context.Context, email, ipAddress string, userID int32) error
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, actorID int32, email string) error
+}
+
+type loginThrottleService struct {
+	repo     repository.LoginThrottleRepository
+	policies map[string]LoginThrottlePolicy
+	now      func() time.Time
+}
+
+// NewLoginThrottleService creates a new LoginThrottleService with the given policies
+func NewLoginThrottleService(repo repository.LoginThrottleRepository, account, ip LoginThrottlePolicy) LoginThrottleService {
+	return &loginThrottleService{
+		repo:     repo,
+		policies: map[string]LoginThrottlePolicy{LoginScopeAccount: account, LoginScopeIP: ip},
+		now:      time.Now,
+	}
+}
+
+// Check rejects the attempt while the account or IP is locked or still waiting out a delay
+func (s *loginThrottleService) Check(ctx context.Context, email, ipAddress string) error {
+	for _, k := range throttleKeys(email, ipAddress) {
+		throttle, err := s.repo.Get(ctx, k.scope, k.key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(now) {
+			return tooManyLoginAttempts("too many failed login attempts, try again later", throttle.LockedUntil.Time.Sub(now))
+		}
+		policy := s.policies[k.scope]
+		if now.Sub(throttle.LastFailedAt) > policy.Window {
+			continue
+		}
+		if wait := throttle.LastFailedAt.Add(policy.delay(int(throttle.FailedCount))).Sub(now); wait > 0 {
+			return tooManyLoginAttempts("please wait before trying to log in again", wait)
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt and locks the account or IP once it
+// reaches the policy limit. userID is 0 when no account has the email.
+func (s *loginThrottleService) RecordFailure(ctx context.Context, email, ipAddress string, userID int32) error {
+	for _, k := range throttleKeys(email, ipAddress) {
+		policy := s.policies[k.scope]
+		now := s.now()
+
+		throttle, err := s.repo.RecordFailure(ctx, k.scope, k.key, now.Add(-policy.Window))
+		if err != nil {
+			return err
+		}
+		if policy.MaxFailures <= 0 || int(throttle.FailedCount) < policy.MaxFailures || throttle.LockedUntil.Valid {
+			continue
+		}
+
+		lockout, err := s.repo.Lock(ctx, sqlc.CreateLoginLockoutParams{
+			Scope:       k.scope,
+			Key:         k.key,
+			UserID:      sql.NullInt32{Int32: userID, Valid: userID != 0 && k.scope == LoginScopeAccount},
+			IpAddress:   ipAddress,
+			FailedCount: throttle.FailedCount,
+			LockedUntil: now.Add(policy.LockoutDuration),
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("login locked: %s %q after %d failed attempts (last from %s) until %s",
+			k.scope, k.key, lockout.FailedCount, ipAddress, lockout.LockedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's failures. The IP counter is left to expire,
+// otherwise logging into one's own account would reset it.
+func (s *loginThrottleService) RecordSuccess(ctx context.Context, email string) error {
+	return s.repo.Clear(ctx, LoginScopeAccount, normalizeLoginEmail(email))
+}
+
+// Unlock lifts an account lockout before it expires
+func (s *loginThrottleService) Unlock(ctx context.Context, actorID int32, email string) error {
+	return s.repo.Unlock(ctx, LoginScopeAccount, normalizeLoginEmail(email), actorID)
+}
+
+// delay is how long to wait after the given number of consecutive failures
+func (p LoginThrottlePolicy) delay(failures int) time.Duration {
+	if p.BaseDelay <= 0 || failures < p.FreeAttempts {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+type throttleKey struct {
+	scope string
+	key   string
+}
+
+func throttleKeys(email, ipAddress string) []throttleKey {
+	keys := []throttleKey{{LoginScopeAccount, normalizeLoginEmail(email)}}
+	if ipAddress != "" {
+		keys = append(keys, throttleKey{LoginScopeIP, ipAddress})
+	}
+	return keys
+}
+
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func tooManyLoginAttempts(message string, wait time.Duration) error {
+	return apperrors.NewTooManyRequestsError(message).
+		WithDetails(map[string]int{"retry_after": int(wait.Seconds()) + 1})
+}
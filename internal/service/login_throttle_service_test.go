@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	throttle := NewLoginThrottleService(
+		repository.NewLoginThrottleRepository(testDB.GetDB(), testDB.GetQueries()),
+		LoginThrottlePolicy{FreeAttempts: 2, BaseDelay: time.Second, MaxDelay: 4 * time.Second, MaxFailures: 4, LockoutDuration: time.Minute, Window: time.Hour},
+		DefaultIPLoginPolicy,
+	).(*loginThrottleService)
+	throttle.now = func() time.Time { return now }
+	svc := NewUserService(repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries()), throttle)
+
+	hash, err := utils.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := createTestUser(t, "lockout", permissions.RoleUser)
+	if err := testDB.GetQueries().UpdateUserPassword(ctx, sqlc.UpdateUserPasswordParams{ID: user.ID, PasswordHash: hash}); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+	admin := createTestUser(t, "lockout-admin", permissions.RoleAdmin)
+
+	login := func(password string) error {
+		_, err := svc.Login(ctx, user.Email, password, "203.0.113.7")
+		return err
+	}
+
+	// Two free attempts, then each failure needs a growing wait
+	assertStatus(t, login("wrong"), http.StatusUnauthorized)
+	assertStatus(t, login("wrong"), http.StatusUnauthorized)
+	assertStatus(t, login("correct-horse"), http.StatusTooManyRequests)
+
+	now = now.Add(2 * time.Second)
+	assertStatus(t, login("wrong"), http.StatusUnauthorized)
+	now = now.Add(3 * time.Second)
+	assertStatus(t, login("wrong"), http.StatusUnauthorized)
+
+	// Locked: even the right password is refused until an admin unlocks
+	now = now.Add(10 * time.Second)
+	assertStatus(t, login("correct-horse"), http.StatusTooManyRequests)
+
+	if err := svc.UnlockLogin(ctx, admin.ID, int64(user.ID)); err != nil {
+		t.Fatalf("failed to unlock: %v", err)
+	}
+	if err := login("correct-horse"); err != nil {
+		t.Fatalf("expected login after unlock, got %v", err)
+	}
+
+	// Unknown emails are throttled exactly like real ones
+	for i := 0; i < 2; i++ {
+		_, err := svc.Login(ctx, "nobody@example.com", "wrong", "203.0.113.8")
+		assertStatus(t, err, http.StatusUnauthorized)
+	}
+	_, err = svc.Login(ctx, "nobody@example.com", "wrong", "203.0.113.8")
+	assertStatus(t, err, http.StatusTooManyRequests)
+}
+
+func TestLoginThrottlePolicyDelay(t *testing.T) {
+	p := DefaultAccountLoginPolicy
+	cases := map[int]time.Duration{
+		0:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		6:  8 * time.Second,
+		9:  30 * time.Second,
+		50: 30 * time.Second,
+	}
+	for failures, want := range cases {
+		if got := p.delay(failures); got != want {
+			t.Errorf("delay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// TOTPIssuer is the account label shown in authenticator apps
+const TOTPIssuer = "Manager User"
+
+// recoveryCodeCount is how many one-time recovery codes are issued when 2FA is enabled
+const recoveryCodeCount = 10
+
+// maxMFAAttempts is how many wrong codes one mfa token survives before the user must log in again
+const maxMFAAttempts = 5
+
+// mfaChallengeIDBytes is the size of the random jti identifying an mfa token
+const mfaChallengeIDBytes = 16
+
+var (
+	ErrInvalidMFACode  = apperrors.NewUnauthorizedError("invalid authentication code")
+	ErrInvalidMFAToken = apperrors.NewUnauthorizedError("invalid or expired mfa token")
+)
+
+// TOTPSetup is what an authenticator app needs to enroll the account
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFAService manages TOTP two-factor authentication and the second login step
+type MFAService interface {
+	Setup(ctx context.Context, userID int32) (TOTPSetup, error)
+	Enable(ctx context.Context, userID int32, code string) ([]string, error)
+	Disable(ctx context.Context, userID int32, code string) error
+	Challenge(ctx context.Context, user sqlc.User) (string, error)
+	CompleteLogin(ctx context.Context, mfaToken, code, ipAddress string) (sqlc.User, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type mfaService struct {
+	mfaRepo  repository.MFARepository
+	userRepo repository.UserRepository
+	throttle LoginThrottleService
+}
+
+// NewMFAService creates a new MFAService instance; wrong codes at login count
+// against the same throttle as wrong passwords
+func NewMFAService(mfaRepo repository.MFARepository, userRepo repository.UserRepository, throttle LoginThrottleService) MFAService {
+	return &mfaService{mfaRepo: mfaRepo, userRepo: userRepo, throttle: throttle}
+}
+
+// Setup generates a new secret; 2FA stays off until Enable confirms a code from it
+func (s *mfaService) Setup(ctx context.Context, userID int32) (TOTPSetup, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return TOTPSetup{}, apperrors.FromDB(err, "user")
+	}
+	if user.TotpEnabledAt.Valid {
+		return TOTPSetup{}, apperrors.NewConflictError("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return TOTPSetup{}, err
+	}
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return TOTPSetup{}, err
+	}
+	if err := s.mfaRepo.SetSecret(ctx, userID, encrypted); err != nil {
+		return TOTPSetup{}, err
+	}
+
+	return TOTPSetup{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// Enable turns 2FA on after the user proves their app produces valid codes,
+// and returns recovery codes that are shown only this once
+func (s *mfaService) Enable(ctx context.Context, userID int32, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, apperrors.FromDB(err, "user")
+	}
+	if user.TotpEnabledAt.Valid {
+		return nil, apperrors.NewConflictError("two-factor authentication is already enabled")
+	}
+	if !user.TotpSecret.Valid {
+		return nil, apperrors.NewBadRequestError("start two-factor setup first")
+	}
+
+	ok, err := s.checkTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.mfaRepo.Enable(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns 2FA off; it requires a current TOTP or recovery code
+func (s *mfaService) Disable(ctx context.Context, userID int32, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return apperrors.FromDB(err, "user")
+	}
+	if !user.TotpEnabledAt.Valid {
+		return apperrors.NewConflictError("two-factor authentication is not enabled")
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.Disable(ctx, userID)
+}
+
+// Challenge issues the short-lived token that stands in for a session until the
+// second factor is checked; its jti is stored so the token can be used only once
+func (s *mfaService) Challenge(ctx context.Context, user sqlc.User) (string, error) {
+	jti, err := utils.GenerateOpaqueToken(mfaChallengeIDBytes)
+	if err != nil {
+		return "", err
+	}
+	if err := s.mfaRepo.CreateChallenge(ctx, sqlc.CreateMFAChallengeParams{
+		Jti:       jti,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(utils.MFATokenTTL),
+	}); err != nil {
+		return "", err
+	}
+	return utils.CreateMFAToken(user.ID, jti)
+}
+
+// CompleteLogin exchanges an mfa token and a TOTP or recovery code for the user.
+// Wrong codes count towards the login throttle and, after maxMFAAttempts, void
+// the token; the throttle is only cleared here, once both factors succeeded.
+func (s *mfaService) CompleteLogin(ctx context.Context, mfaToken, code, ipAddress string) (sqlc.User, error) {
+	userID, jti, err := utils.ParseMFAToken(mfaToken)
+	if err != nil {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+
+	challenge, err := s.mfaRepo.GetChallenge(ctx, jti)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if challenge.UserID != userID || challenge.FailedAttempts >= maxMFAAttempts {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || !user.TotpEnabledAt.Valid {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+	if err := s.throttle.Check(ctx, user.Email, ipAddress); err != nil {
+		return sqlc.User{}, err
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if _, err := s.mfaRepo.RecordChallengeFailure(ctx, jti); err != nil {
+				return sqlc.User{}, err
+			}
+			if err := s.throttle.RecordFailure(ctx, user.Email, ipAddress, user.ID); err != nil {
+				return sqlc.User{}, err
+			}
+		}
+		return sqlc.User{}, err
+	}
+
+	used, err := s.mfaRepo.UseChallenge(ctx, jti)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if !used {
+		return sqlc.User{}, ErrInvalidMFAToken
+	}
+	if err := s.throttle.RecordSuccess(ctx, user.Email); err != nil {
+		return sqlc.User{}, err
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+// DeleteExpired drops mfa challenges whose token has expired
+func (s *mfaService) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.mfaRepo.DeleteExpiredChallenges(ctx)
+}
+
+// verifyCode accepts a 6-digit TOTP code or, failing that, an unused recovery code
+func (s *mfaService) verifyCode(ctx context.Context, user sqlc.User, code string) error {
+	ok, err := s.checkTOTP(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// checkTOTP validates a TOTP code and consumes its time step, so each code works only once
+func (s *mfaService) checkTOTP(ctx context.Context, user sqlc.User, code string) (bool, error) {
+	secret, err := utils.DecryptSecret(user.TotpSecret.String)
+	if err != nil {
+		return false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.mfaRepo.UseTOTPStep(ctx, user.ID, step)
+}
+
+// generateRecoveryCode returns a code such as "k7q2m-xw4pd"
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 7)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+	return raw[:5] + "-" + raw[5:], nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed loosely
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(code)))
+	return utils.HashToken(normalized)
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+func TestTOTPLogin(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	// No delays or lockouts here; TestMFALoginLimits covers the throttle
+	throttle := NewLoginThrottleService(repository.NewLoginThrottleRepository(testDB.GetDB(), testDB.GetQueries()), LoginThrottlePolicy{}, LoginThrottlePolicy{})
+	svc := NewMFAService(repository.NewMFARepository(testDB.GetDB(), testDB.GetQueries()), userRepo, throttle)
+
+	user := createTestUser(t, "totp", permissions.RoleUser)
+
+	setup, err := svc.Setup(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to set up totp: %v", err)
+	}
+	if !strings.HasPrefix(setup.OTPAuthURI, "otpauth://totp/") {
+		t.Errorf("unexpected otpauth uri %q", setup.OTPAuthURI)
+	}
+
+	_, err = svc.Enable(ctx, user.ID, "000000")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	code, err := utils.TOTPCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	recoveryCodes, err := svc.Enable(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("failed to enable totp: %v", err)
+	}
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(recoveryCodes))
+	}
+
+	stored, err := userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	if stored.TotpSecret.String == setup.Secret {
+		t.Errorf("expected the secret to be stored encrypted")
+	}
+
+	challenge := func() string {
+		t.Helper()
+		mfaToken, err := svc.Challenge(ctx, stored)
+		if err != nil {
+			t.Fatalf("failed to create mfa token: %v", err)
+		}
+		return mfaToken
+	}
+	mfaToken := challenge()
+	if _, err := utils.ParseToken(mfaToken); err == nil {
+		t.Errorf("mfa token must not be accepted as an access token")
+	}
+
+	_, err = svc.CompleteLogin(ctx, mfaToken, "000000", "")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	// The code used to enable 2FA was consumed; a code from the next step still falls within the skew
+	_, err = svc.CompleteLogin(ctx, mfaToken, code, "")
+	assertStatus(t, err, http.StatusUnauthorized)
+	next, err := utils.TOTPCode(setup.Secret, time.Now().Add(utils.TOTPPeriod))
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	if _, err := svc.CompleteLogin(ctx, mfaToken, next, ""); err != nil {
+		t.Fatalf("expected totp code to complete login: %v", err)
+	}
+	_, err = svc.CompleteLogin(ctx, challenge(), next, "")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	// Recovery codes work once, regardless of case
+	recovery := strings.ToUpper(recoveryCodes[0])
+	if _, err := svc.CompleteLogin(ctx, challenge(), recovery, ""); err != nil {
+		t.Fatalf("expected recovery code to complete login: %v", err)
+	}
+	_, err = svc.CompleteLogin(ctx, challenge(), recovery, "")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	// An mfa token works only once, even with a fresh code
+	_, err = svc.CompleteLogin(ctx, mfaToken, recoveryCodes[2], "")
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	pending := challenge()
+	if err := svc.Disable(ctx, user.ID, recoveryCodes[1]); err != nil {
+		t.Fatalf("failed to disable totp: %v", err)
+	}
+	_, err = svc.CompleteLogin(ctx, pending, recoveryCodes[3], "")
+	assertStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestMFALoginLimits(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	throttle := NewLoginThrottleService(
+		repository.NewLoginThrottleRepository(testDB.GetDB(), testDB.GetQueries()),
+		LoginThrottlePolicy{MaxFailures: 3, LockoutDuration: time.Minute, Window: time.Hour},
+		LoginThrottlePolicy{},
+	)
+	users := NewUserService(userRepo, throttle)
+	svc := NewMFAService(repository.NewMFARepository(testDB.GetDB(), testDB.GetQueries()), userRepo, throttle)
+
+	enroll := func(user sqlc.User) ([]string, sqlc.User) {
+		t.Helper()
+		setup, err := svc.Setup(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to set up totp: %v", err)
+		}
+		code, _ := utils.TOTPCode(setup.Secret, time.Now())
+		recoveryCodes, err := svc.Enable(ctx, user.ID, code)
+		if err != nil {
+			t.Fatalf("failed to enable totp: %v", err)
+		}
+		stored, err := userRepo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to load user: %v", err)
+		}
+		return recoveryCodes, stored
+	}
+
+	t.Run("token voided after too many wrong codes", func(t *testing.T) {
+		recoveryCodes, user := enroll(createTestUser(t, "mfa-attempts", permissions.RoleUser))
+		// Unthrottled, so only the per-token limit applies
+		unthrottled := NewMFAService(repository.NewMFARepository(testDB.GetDB(), testDB.GetQueries()), userRepo,
+			NewLoginThrottleService(repository.NewLoginThrottleRepository(testDB.GetDB(), testDB.GetQueries()), LoginThrottlePolicy{}, LoginThrottlePolicy{}))
+
+		mfaToken, err := unthrottled.Challenge(ctx, user)
+		if err != nil {
+			t.Fatalf("failed to create mfa token: %v", err)
+		}
+		for range maxMFAAttempts {
+			_, err := unthrottled.CompleteLogin(ctx, mfaToken, "000000", "")
+			assertStatus(t, err, http.StatusUnauthorized)
+		}
+		if _, err := unthrottled.CompleteLogin(ctx, mfaToken, recoveryCodes[0], ""); !errors.Is(err, ErrInvalidMFAToken) {
+			t.Fatalf("expected the token to be void after %d wrong codes, got %v", maxMFAAttempts, err)
+		}
+	})
+
+	t.Run("second factor failures lock the account", func(t *testing.T) {
+		user := createTestUserWithPassword(t, "mfa-throttled")
+		recoveryCodes, _ := enroll(user)
+
+		// Two wrong passwords, then the right one: with 2FA on, this must not reset the counter
+		for range 2 {
+			_, err := users.Login(ctx, user.Email, "wrong", "")
+			assertStatus(t, err, http.StatusUnauthorized)
+		}
+		stored, err := users.Login(ctx, user.Email, "password", "")
+		if err != nil {
+			t.Fatalf("expected the password step to pass: %v", err)
+		}
+		mfaToken, err := svc.Challenge(ctx, stored)
+		if err != nil {
+			t.Fatalf("failed to create mfa token: %v", err)
+		}
+
+		_, err = svc.CompleteLogin(ctx, mfaToken, "000000", "")
+		assertStatus(t, err, http.StatusUnauthorized)
+		_, err = svc.CompleteLogin(ctx, mfaToken, recoveryCodes[0], "")
+		assertStatus(t, err, http.StatusTooManyRequests)
+	})
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/oidc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// OIDCAuthRequestTTL is how long a user has to finish signing in at the provider
+const OIDCAuthRequestTTL = 10 * time.Minute
+
+const (
+	oidcRandomBytes = 32
+	// maxUsernameLen matches users.username VARCHAR(50), leaving room for a suffix
+	maxUsernameLen       = 40
+	usernameAttemptLimit = 5
+)
+
+var (
+	ErrUnknownOIDCProvider = apperrors.NewNotFoundError("unknown identity provider")
+	ErrInvalidOIDCState    = apperrors.NewBadRequestError("invalid or expired sign-in request")
+	errOIDCEmailUnverified = apperrors.NewForbiddenError("the identity provider did not confirm an email address for this account")
+	errOIDCLinkRequired    = apperrors.NewConflictError("an account with this email already exists; sign in and link this provider from your account")
+	errOIDCIdentityTaken   = apperrors.NewConflictError("this identity is already linked to another account")
+)
+
+// OIDCService signs users in through external OpenID Connect providers
+type OIDCService interface {
+	Providers() []string
+	// Start returns the provider URL and the state the callback must present; the
+	// caller binds state to the browser (a cookie) and passes it back to Callback.
+	// linkUserID is the signed-in user linking the provider, 0 for a plain sign-in.
+	Start(ctx context.Context, provider string, linkUserID int32) (authURL, state string, err error)
+	Callback(ctx context.Context, provider, state, browserState, code string) (sqlc.User, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type oidcService struct {
+	oidcRepo  repository.OIDCRepository
+	userRepo  repository.UserRepository
+	providers map[string]*oidc.Provider
+}
+
+// NewOIDCService creates a new OIDCService for the configured providers
+func NewOIDCService(oidcRepo repository.OIDCRepository, userRepo repository.UserRepository, providers []*oidc.Provider) OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &oidcService{oidcRepo: oidcRepo, userRepo: userRepo, providers: byName}
+}
+
+// Providers lists the configured provider names
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Start records a pending sign-in and returns the provider URL to send the user to
+func (s *oidcService) Start(ctx context.Context, provider string, linkUserID int32) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	var state, nonce, verifier string
+	for _, v := range []*string{&state, &nonce, &verifier} {
+		token, err := utils.GenerateOpaqueToken(oidcRandomBytes)
+		if err != nil {
+			return "", "", err
+		}
+		*v = token
+	}
+
+	err := s.oidcRepo.CreateAuthRequest(ctx, sqlc.CreateOIDCAuthRequestParams{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OIDCAuthRequestTTL),
+		LinkUserID:   sql.NullInt32{Int32: linkUserID, Valid: linkUserID != 0},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	return authURL, state, err
+}
+
+// Callback completes a sign-in. The identity's linked user is returned; without
+// one, a link started by a signed-in user is completed, an account whose email
+// both sides have verified is linked, or a new account is created.
+// browserState must equal state, so a callback URL replayed into another
+// browser (login CSRF) is refused.
+func (s *oidcService) Callback(ctx context.Context, provider, state, browserState, code string) (sqlc.User, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return sqlc.User{}, ErrUnknownOIDCProvider
+	}
+	if browserState == "" || subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		return sqlc.User{}, ErrInvalidOIDCState
+	}
+
+	req, err := s.oidcRepo.ConsumeAuthRequest(ctx, utils.HashToken(state))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && req.Provider != provider) {
+		return sqlc.User{}, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	claims, err := p.Authenticate(ctx, code, req.CodeVerifier, req.Nonce)
+	if err != nil {
+		return sqlc.User{}, apperrors.NewUnauthorizedError("sign-in with the identity provider failed").Wrap(err)
+	}
+
+	user, err := s.oidcRepo.GetUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		if req.LinkUserID.Valid && req.LinkUserID.Int32 != user.ID {
+			return sqlc.User{}, errOIDCIdentityTaken
+		}
+		if err := checkNotSuspended(user); err != nil {
+			return sqlc.User{}, err
+		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, err
+	}
+
+	if req.LinkUserID.Valid {
+		return s.link(ctx, req.LinkUserID.Int32, provider, claims)
+	}
+
+	// Linking or creating by email is only safe when the provider vouches for it
+	if claims.Email == "" || !claims.EmailVerified {
+		return sqlc.User{}, errOIDCEmailUnverified
+	}
+
+	existing, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	if err == nil {
+		// An unverified local email may belong to someone who registered with an
+		// address they do not own; only the account holder can link it then
+		if !existing.EmailVerifiedAt.Valid {
+			return sqlc.User{}, errOIDCLinkRequired
+		}
+		return s.link(ctx, existing.ID, provider, claims)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, err
+	}
+
+	return s.createUser(ctx, provider, claims)
+}
+
+// link attaches the identity to an existing, active user
+func (s *oidcService) link(ctx context.Context, userID int32, provider string, claims oidc.Claims) (sqlc.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return sqlc.User{}, apperrors.FromDB(err, "user")
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return sqlc.User{}, err
+	}
+	user, err = s.oidcRepo.Link(ctx, user.ID, provider, claims.Subject, claims.Email)
+	return user, apperrors.FromDB(err, "identity")
+}
+
+// DeleteExpired drops sign-ins that were started but never completed
+func (s *oidcService) DeleteExpired(ctx context.Context) (int64, error) {
+	return s.oidcRepo.DeleteExpiredAuthRequests(ctx)
+}
+
+// createUser creates an account without a usable password; the user can set
+// one later through the password reset flow
+func (s *oidcService) createUser(ctx context.Context, provider string, claims oidc.Claims) (sqlc.User, error) {
+	password, err := utils.GenerateOpaqueToken(oidcRandomBytes)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	base := usernameFromClaims(claims)
+	username := base
+	for attempt := 1; ; attempt++ {
+		user, err := s.oidcRepo.CreateUser(ctx, sqlc.CreateUserParams{
+			Username:     username,
+			Email:        claims.Email,
+			PasswordHash: hashedPassword,
+			Role:         permissions.RoleUser,
+		}, provider, claims.Subject)
+
+		constraint, taken := apperrors.UniqueViolation(err)
+		if !taken || constraint != "users_username_key" || attempt == usernameAttemptLimit {
+			return user, apperrors.FromDB(err, "user")
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return sqlc.User{}, err
+		}
+		username = fmt.Sprintf("%s-%04d", base, n.Int64())
+	}
+}
+
+// usernameFromClaims picks a readable username from the provider's profile
+func usernameFromClaims(claims oidc.Claims) string {
+	candidate := claims.PreferredUsername
+	if candidate == "" {
+		candidate, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	username := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		default:
+			return -1
+		}
+	}, candidate)
+	if len(username) > maxUsernameLen {
+		username = username[:maxUsernameLen]
+	}
+	if username == "" {
+		username = "user"
+	}
+	return username
+}
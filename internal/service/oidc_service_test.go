@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/oidc"
+	"my_project/internal/oidc/oidctest"
+	"my_project/internal/repository"
+)
+
+func TestOIDCSignIn(t *testing.T) {
+	ctx := context.Background()
+	stub := oidctest.NewServer("client", "secret")
+	defer stub.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:         "stub",
+		Issuer:       stub.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/v1/auth/oidc/stub/callback",
+	}, stub.Client())
+	svc := NewOIDCService(
+		repository.NewOIDCRepository(testDB.GetDB(), testDB.GetQueries()),
+		repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries()),
+		[]*oidc.Provider{provider},
+	)
+
+	start := func(user oidctest.User, linkUserID int32) (code, state string) {
+		t.Helper()
+		stub.SetUser(user)
+		authURL, browserState, err := svc.Start(ctx, "stub", linkUserID)
+		if err != nil {
+			t.Fatalf("failed to start sign-in: %v", err)
+		}
+		code, state, err = stub.Authorize(authURL)
+		if err != nil {
+			t.Fatalf("authorize failed: %v", err)
+		}
+		if state != browserState {
+			t.Fatalf("expected the provider to return the state handed to the browser")
+		}
+		return code, state
+	}
+	link := func(user oidctest.User, linkUserID int32) (int32, error) {
+		t.Helper()
+		code, state := start(user, linkUserID)
+		u, err := svc.Callback(ctx, "stub", state, state, code)
+		return u.ID, err
+	}
+	signIn := func(user oidctest.User) (int32, error) {
+		t.Helper()
+		return link(user, 0)
+	}
+
+	_, _, err := svc.Start(ctx, "nope", 0)
+	assertStatus(t, err, http.StatusNotFound)
+
+	// New identity with a verified email creates an account
+	created, err := signIn(oidctest.User{Subject: "sub-new", Email: "oidc-new@example.com", EmailVerified: true, PreferredUsername: "oidc new"})
+	if err != nil {
+		t.Fatalf("expected an account to be created: %v", err)
+	}
+	again, err := signIn(oidctest.User{Subject: "sub-new", Email: "changed@example.com"})
+	if err != nil || again != created {
+		t.Fatalf("expected the linked account %d, got %d (%v)", created, again, err)
+	}
+
+	// A matching email is only linked automatically once the local address is verified too
+	existing := createTestUser(t, "oidclink", permissions.RoleUser)
+	_, err = signIn(oidctest.User{Subject: "sub-link", Email: existing.Email, EmailVerified: true})
+	assertStatus(t, err, http.StatusConflict)
+	if _, err := testDB.GetQueries().MarkUserEmailVerified(ctx, existing.ID); err != nil {
+		t.Fatalf("failed to verify email: %v", err)
+	}
+	linked, err := signIn(oidctest.User{Subject: "sub-link", Email: existing.Email, EmailVerified: true})
+	if err != nil || linked != existing.ID {
+		t.Fatalf("expected to link to user %d, got %d (%v)", existing.ID, linked, err)
+	}
+
+	// Unverified emails are neither linked nor used for new accounts
+	_, err = signIn(oidctest.User{Subject: "sub-unverified", Email: "oidc-unverified@example.com"})
+	assertStatus(t, err, http.StatusForbidden)
+
+	// A signed-in user can link an identity whatever its email says
+	owner := createTestUser(t, "oidcowner", permissions.RoleUser)
+	explicit, err := link(oidctest.User{Subject: "sub-explicit", Email: "elsewhere@example.com"}, owner.ID)
+	if err != nil || explicit != owner.ID {
+		t.Fatalf("expected to link to user %d, got %d (%v)", owner.ID, explicit, err)
+	}
+	if again, err := signIn(oidctest.User{Subject: "sub-explicit"}); err != nil || again != owner.ID {
+		t.Errorf("expected the explicitly linked account %d, got %d (%v)", owner.ID, again, err)
+	}
+	_, err = link(oidctest.User{Subject: "sub-new"}, owner.ID)
+	assertStatus(t, err, http.StatusConflict)
+
+	// The callback must come back to the browser that started the sign-in
+	code, state := start(oidctest.User{Subject: "sub-new"}, 0)
+	_, err = svc.Callback(ctx, "stub", state, "", code)
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.Callback(ctx, "stub", state, state+"x", code)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	// A state only completes one sign-in
+	if _, err := svc.Callback(ctx, "stub", state, state, code); err != nil {
+		t.Fatalf("callback failed: %v", err)
+	}
+	_, err = svc.Callback(ctx, "stub", state, state, code)
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestUsernameFromClaims(t *testing.T) {
+	tests := map[string]oidc.Claims{
+		"jane.doe": {PreferredUsername: "jane.doe"},
+		"janedoe":  {PreferredUsername: "jane doe!"},
+		"alice":    {Email: "alice@example.com"},
+		"user":     {PreferredUsername: "ñ"},
+	}
+	for want, claims := range tests {
+		if got := usernameFromClaims(claims); got != want {
+			t.Errorf("usernameFromClaims(%+v) = %q, want %q", claims, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/mailer"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// PasswordResetTokenTTL is how long a password reset link stays valid
+const PasswordResetTokenTTL = time.Hour
+
+const passwordResetTokenBytes = 32
+
+// ErrInvalidResetToken covers unknown, already used and expired reset tokens alike
+var ErrInvalidResetToken = apperrors.NewBadRequestError("invalid or expired password reset token")
+
+// PasswordResetService lets users who forgot their password set a new one
+type PasswordResetService interface {
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type passwordResetService struct {
+	resetRepo repository.PasswordResetRepository
+	userRepo  repository.UserRepository
+	mailer    mailer.Mailer
+	resetURL  string
+}
+
+// NewPasswordResetService creates a new PasswordResetService; resetURL is the
+// frontend page that receives the token as ?token=
+func NewPasswordResetService(resetRepo repository.PasswordResetRepository, userRepo repository.UserRepository, m mailer.Mailer, resetURL string) PasswordResetService {
+	return &passwordResetService{resetRepo: resetRepo, userRepo: userRepo, mailer: m, resetURL: resetURL}
+}
+
+// RequestReset emails a single-use reset link. Unknown emails are not an error,
+// so callers cannot use this endpoint to find out which emails are registered.
+func (s *passwordResetService) RequestReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken(passwordResetTokenBytes)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.resetRepo.Create(ctx, sqlc.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetTokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes and can be used once.\n\n%s?token=%s\n\nIf you did not ask for this, you can ignore this email.",
+			user.Username, int(PasswordResetTokenTTL.Minutes()), s.resetURL, token),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		// Not reported to the caller: a failure here must look the same as an unknown email
+		log.Printf("failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password and signs the user out everywhere
+func (s *passwordResetService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.resetRepo.Reset(ctx, utils.HashToken(token), hashedPassword)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	return err
+}
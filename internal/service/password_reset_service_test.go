@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/mailer"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// recordingMailer keeps sent messages in memory
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// lastToken extracts the token from the link in the most recent message
+func (m *recordingMailer) lastToken(t *testing.T) string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.sent) == 0 {
+		t.Fatalf("expected a reset email to be sent")
+	}
+	for _, field := range strings.Fields(m.sent[len(m.sent)-1].Body) {
+		if u, err := url.Parse(field); err == nil && u.Query().Get("token") != "" {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no reset link in email body")
+	return ""
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	sessionRepo := repository.NewSessionRepository(testDB.GetDB(), testDB.GetQueries())
+	mail := &recordingMailer{}
+	svc := NewPasswordResetService(
+		repository.NewPasswordResetRepository(testDB.GetDB(), testDB.GetQueries()),
+		userRepo, mail, "http://localhost/reset",
+	)
+	sessions := NewSessionService(sessionRepo, userRepo)
+
+	user := createTestUser(t, "forgetful", permissions.RoleUser)
+
+	t.Run("unknown email is silently accepted", func(t *testing.T) {
+		if err := svc.RequestReset(ctx, "nobody@example.com"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(mail.sent) != 0 {
+			t.Errorf("expected no email for an unknown address")
+		}
+	})
+
+	t.Run("newer request invalidates older token", func(t *testing.T) {
+		if err := svc.RequestReset(ctx, user.Email); err != nil {
+			t.Fatalf("failed to request reset: %v", err)
+		}
+		stale := mail.lastToken(t)
+		if err := svc.RequestReset(ctx, user.Email); err != nil {
+			t.Fatalf("failed to request reset: %v", err)
+		}
+
+		assertStatus(t, svc.ResetPassword(ctx, stale, "newpassword"), http.StatusBadRequest)
+	})
+
+	t.Run("token resets password once and revokes sessions", func(t *testing.T) {
+		pair, err := sessions.Issue(ctx, user, SessionMeta{})
+		if err != nil {
+			t.Fatalf("failed to issue session: %v", err)
+		}
+
+		if err := svc.RequestReset(ctx, user.Email); err != nil {
+			t.Fatalf("failed to request reset: %v", err)
+		}
+		token := mail.lastToken(t)
+
+		if err := svc.ResetPassword(ctx, token, "newpassword"); err != nil {
+			t.Fatalf("failed to reset password: %v", err)
+		}
+
+		updated, err := userRepo.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("failed to reload user: %v", err)
+		}
+		if !utils.CheckPassword(updated.PasswordHash, "newpassword") {
+			t.Errorf("expected the new password to be stored")
+		}
+
+		if _, _, err := sessions.Refresh(ctx, pair.RefreshToken, SessionMeta{}); err == nil {
+			t.Errorf("expected existing sessions to be revoked")
+		}
+		// Access tokens issued before the reset are rejected too
+		revoked, err := userRepo.GetByID(ctx, user.ID)
+		if err != nil || !revoked.TokensRevokedAt.Valid {
+			t.Errorf("expected access tokens to be revoked, got %+v (%v)", revoked.TokensRevokedAt, err)
+		}
+
+		assertStatus(t, svc.ResetPassword(ctx, token, "anotherpassword"), http.StatusBadRequest)
+	})
+}
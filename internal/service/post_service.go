@@ -2,50 +2,390 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
 	"my_project/internal/database/sqlc"
 	"my_project/internal/repository"
+	"my_project/utils"
 )
 
+// Post lifecycle states stored in posts.status
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+// scheduledPublishBatch caps how many due posts one scheduler tick publishes
+const scheduledPublishBatch = 100
+
+// maxPostTags caps how many tags a single post may carry
+const maxPostTags = 10
+
+// tagPattern restricts tag names to lowercase slugs such as "go" or "postgres-16"
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// postTransitions lists the states each status may move to
+var postTransitions = map[string][]string{
+	PostStatusDraft:     {PostStatusPublished, PostStatusArchived},
+	PostStatusPublished: {PostStatusArchived},
+	PostStatusArchived:  {PostStatusPublished},
+}
+
 // PostService defines the business logic for posts
 type PostService interface {
-	CreatePost(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.CreatePostRow, error)
-	GetPost(ctx context.Context, id int32) (sqlc.Post, error)
-	ListPosts(ctx context.Context, limit, offset int32) ([]sqlc.ListPostsRow, error)
-	ListPostsByUser(ctx context.Context, userID int32, limit, offset int32) ([]sqlc.ListPostsByUserRow, error)
-	UpdatePost(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error)
-	DeletePost(ctx context.Context, id int32) error
+	CreatePost(ctx context.Context, arg sqlc.CreatePostParams, tags []string) (sqlc.GetPostDetailRow, error)
+	GetPost(ctx context.Context, viewer *Actor, id int32) (sqlc.GetPostDetailRow, error)
+	ListPosts(ctx context.Context, filter PostFilter, after *PostKey, limit int32) ([]sqlc.ListPostsRow, error)
+	ListPostsByUser(ctx context.Context, viewer *Actor, userID int32, after *PostKey, limit int32) ([]sqlc.ListPostsByUserRow, error)
+	UpdatePost(ctx context.Context, actor Actor, arg sqlc.UpdatePostParams, tags []string, audit AuditEvent) (sqlc.GetPostDetailRow, error)
+	PublishPost(ctx context.Context, actor Actor, id int32) (sqlc.Post, error)
+	ArchivePost(ctx context.Context, actor Actor, id int32) (sqlc.Post, error)
+	DeletePost(ctx context.Context, actor Actor, id int32, audit AuditEvent) error
+	PublishScheduled(ctx context.Context) (int, error)
+	RestorePost(ctx context.Context, id int32) (sqlc.Post, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	ListRevisions(ctx context.Context, actor Actor, postID int32) ([]sqlc.ListPostRevisionsRow, error)
+	DiffRevisions(ctx context.Context, actor Actor, postID, revision, against int32) (RevisionDiff, error)
+	RestoreRevision(ctx context.Context, actor Actor, postID, revision int32) (sqlc.Post, error)
+
+	ListTags(ctx context.Context, limit int32) ([]sqlc.ListTagsWithCountsRow, error)
+}
+
+// PostKey is the keyset position of a post in newest-first listings
+type PostKey struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int32     `json:"id"`
+}
+
+// PostFilter narrows the public post listing
+type PostFilter struct {
+	Tags     []string
+	MatchAll bool // require every tag instead of any of them
+}
+
+// RevisionDiff is a line-level comparison between two revisions of a post
+type RevisionDiff struct {
+	From    int32            `json:"from"`
+	To      int32            `json:"to"`
+	Title   []utils.DiffLine `json:"title"`
+	Content []utils.DiffLine `json:"content"`
 }
 
 type postService struct {
 	postRepo repository.PostRepository
+	audit    AuditLogger
 }
 
 // NewPostService creates a new PostService instance
-func NewPostService(repo repository.PostRepository) PostService {
-	return &postService{postRepo: repo}
+func NewPostService(repo repository.PostRepository, audit AuditLogger) PostService {
+	return &postService{postRepo: repo, audit: audit}
+}
+
+func (s *postService) CreatePost(ctx context.Context, arg sqlc.CreatePostParams, tags []string) (sqlc.GetPostDetailRow, error) {
+	if arg.Status == "" {
+		arg.Status = PostStatusPublished
+	}
+	if arg.Status != PostStatusDraft && arg.Status != PostStatusPublished {
+		return sqlc.GetPostDetailRow{}, apperrors.NewValidationError("status must be draft or published")
+	}
+	if arg.PublishAt.Valid {
+		if arg.Status != PostStatusDraft {
+			return sqlc.GetPostDetailRow{}, apperrors.NewValidationError("only drafts can be scheduled")
+		}
+		if err := validatePublishAt(arg.PublishAt.Time); err != nil {
+			return sqlc.GetPostDetailRow{}, err
+		}
+	}
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return sqlc.GetPostDetailRow{}, err
+	}
+	post, err := s.postRepo.Create(ctx, arg, tags)
+	return post, apperrors.FromDB(err, "post")
+}
+
+// GetPost returns a post; unpublished posts are only visible to their author and moderators
+func (s *postService) GetPost(ctx context.Context, viewer *Actor, id int32) (sqlc.GetPostDetailRow, error) {
+	post, err := s.postRepo.GetDetail(ctx, id)
+	if err != nil {
+		return sqlc.GetPostDetailRow{}, apperrors.FromDB(err, "post")
+	}
+
+	if post.Status != PostStatusPublished && !canSeeUnpublished(viewer, post.UserID) {
+		return sqlc.GetPostDetailRow{}, apperrors.NewNotFoundError("post not found")
+	}
+	return post, nil
+}
+
+// ListPosts returns published posts newest first, starting after the given key
+func (s *postService) ListPosts(ctx context.Context, filter PostFilter, after *PostKey, limit int32) ([]sqlc.ListPostsRow, error) {
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	arg := sqlc.ListPostsParams{
+		Tags:      tags,
+		MatchAll:  filter.MatchAll,
+		PageLimit: limit,
+	}
+	if after != nil {
+		arg.AfterCreatedAt = sql.NullTime{Time: after.CreatedAt, Valid: true}
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+	}
+	return s.postRepo.List(ctx, arg)
+}
+
+// ListPostsByUser includes drafts and archived posts only when authors list their own posts
+func (s *postService) ListPostsByUser(ctx context.Context, viewer *Actor, userID int32, after *PostKey, limit int32) ([]sqlc.ListPostsByUserRow, error) {
+	arg := sqlc.ListPostsByUserParams{
+		UserID:             userID,
+		IncludeUnpublished: viewer != nil && viewer.UserID == userID,
+		PageLimit:          limit,
+	}
+	if after != nil {
+		arg.AfterCreatedAt = sql.NullTime{Time: after.CreatedAt, Valid: true}
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+	}
+	return s.postRepo.ListByUser(ctx, arg)
+}
+
+// UpdatePost edits a post; nil tags keep the current ones, an empty slice clears them.
+// audit tells where the request came from; it is recorded when a moderator edits
+// someone else's post.
+func (s *postService) UpdatePost(ctx context.Context, actor Actor, arg sqlc.UpdatePostParams, tags []string, audit AuditEvent) (sqlc.GetPostDetailRow, error) {
+	post, err := s.authorizedPost(ctx, actor, arg.ID)
+	if err != nil {
+		return sqlc.GetPostDetailRow{}, err
+	}
+	if arg.PublishAt.Valid {
+		if post.Status != PostStatusDraft {
+			return sqlc.GetPostDetailRow{}, apperrors.NewValidationError("only drafts can be scheduled")
+		}
+		if err := validatePublishAt(arg.PublishAt.Time); err != nil {
+			return sqlc.GetPostDetailRow{}, err
+		}
+	}
+
+	if tags != nil {
+		if tags, err = normalizeTags(tags); err != nil {
+			return sqlc.GetPostDetailRow{}, err
+		}
+		if tags == nil {
+			tags = []string{}
+		}
+	}
+	before, updated, err := s.postRepo.Update(ctx, arg, tags, actor.UserID)
+	if err != nil {
+		return sqlc.GetPostDetailRow{}, apperrors.FromDB(err, "post")
+	}
+	audit.Action = AuditPostUpdate
+	s.recordModeration(ctx, actor, audit, before, updated)
+	return updated, nil
+}
+
+func (s *postService) PublishPost(ctx context.Context, actor Actor, id int32) (sqlc.Post, error) {
+	return s.transition(ctx, actor, id, PostStatusPublished)
+}
+
+func (s *postService) ArchivePost(ctx context.Context, actor Actor, id int32) (sqlc.Post, error) {
+	return s.transition(ctx, actor, id, PostStatusArchived)
+}
+
+// DeletePost soft-deletes the post; it can be restored until the purge job removes it.
+// audit is recorded as for UpdatePost.
+func (s *postService) DeletePost(ctx context.Context, actor Actor, id int32, audit AuditEvent) error {
+	if _, err := s.authorizedPost(ctx, actor, id); err != nil {
+		return err
+	}
+	deleted, err := s.postRepo.Delete(ctx, id)
+	if err != nil {
+		return apperrors.FromDB(err, "post")
+	}
+	audit.Action = AuditPostDelete
+	s.recordModeration(ctx, actor, audit, deleted, nil)
+	return nil
+}
+
+// RestorePost brings back a deleted post that has not been purged yet
+func (s *postService) RestorePost(ctx context.Context, id int32) (sqlc.Post, error) {
+	post, err := s.postRepo.Restore(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Post{}, apperrors.NewNotFoundError(fmt.Sprintf("No deleted post with ID %d", id))
+	}
+	return post, err
+}
+
+// PurgeDeleted hard-deletes posts deleted before deletedBefore; called by the background scheduler
+func (s *postService) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return s.postRepo.PurgeDeleted(ctx, deletedBefore)
+}
+
+// PublishScheduled publishes drafts whose publish_at has passed; called by the background scheduler
+func (s *postService) PublishScheduled(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		ids, err := s.postRepo.PublishDue(ctx, scheduledPublishBatch)
+		total += len(ids)
+		if err != nil || len(ids) < scheduledPublishBatch {
+			return total, err
+		}
+	}
+}
+
+// ListRevisions returns the edit history; only the author and moderators may see it
+func (s *postService) ListRevisions(ctx context.Context, actor Actor, postID int32) ([]sqlc.ListPostRevisionsRow, error) {
+	if _, err := s.authorizedPost(ctx, actor, postID); err != nil {
+		return nil, err
+	}
+	return s.postRepo.ListRevisions(ctx, postID)
+}
+
+// DiffRevisions compares revision with against; against defaults to the previous
+// revision, and revision 1 is compared with an empty post
+func (s *postService) DiffRevisions(ctx context.Context, actor Actor, postID, revision, against int32) (RevisionDiff, error) {
+	if _, err := s.authorizedPost(ctx, actor, postID); err != nil {
+		return RevisionDiff{}, err
+	}
+
+	if against == 0 {
+		against = revision - 1
+	}
+
+	to, err := s.revision(ctx, postID, revision)
+	if err != nil {
+		return RevisionDiff{}, err
+	}
+
+	var from sqlc.PostRevision
+	if against > 0 {
+		if from, err = s.revision(ctx, postID, against); err != nil {
+			return RevisionDiff{}, err
+		}
+	}
+
+	diff := RevisionDiff{From: against, To: revision}
+	if diff.Title, err = utils.LineDiff(from.Title, to.Title); err == nil {
+		diff.Content, err = utils.LineDiff(from.Content, to.Content)
+	}
+	if errors.Is(err, utils.ErrDiffTooLarge) {
+		return RevisionDiff{}, apperrors.NewUnprocessableEntityError("revisions differ too much to compare line by line").Wrap(err)
+	}
+	return diff, err
 }
 
-func (s *postService) CreatePost(ctx context.Context, arg sqlc.CreatePostParams) (sqlc.CreatePostRow, error) {
-	return s.postRepo.Create(ctx, arg)
+// RestoreRevision makes an old revision current again by recording it as a new revision
+func (s *postService) RestoreRevision(ctx context.Context, actor Actor, postID, revision int32) (sqlc.Post, error) {
+	if _, err := s.authorizedPost(ctx, actor, postID); err != nil {
+		return sqlc.Post{}, err
+	}
+
+	post, err := s.postRepo.RestoreRevision(ctx, postID, revision, actor.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.Post{}, apperrors.NewNotFoundError(fmt.Sprintf("revision %d not found", revision)).Wrap(err)
+	}
+	return post, apperrors.FromDB(err, "post")
+}
+
+// ListTags returns the most used tags across published posts
+func (s *postService) ListTags(ctx context.Context, limit int32) ([]sqlc.ListTagsWithCountsRow, error) {
+	return s.postRepo.ListTags(ctx, limit)
+}
+
+func (s *postService) revision(ctx context.Context, postID, revision int32) (sqlc.PostRevision, error) {
+	rev, err := s.postRepo.GetRevision(ctx, postID, revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.PostRevision{}, apperrors.NewNotFoundError(fmt.Sprintf("revision %d not found", revision)).Wrap(err)
+	}
+	return rev, err
+}
+
+// transition moves a post to the target status if the lifecycle allows it
+func (s *postService) transition(ctx context.Context, actor Actor, id int32, target string) (sqlc.Post, error) {
+	post, err := s.authorizedPost(ctx, actor, id)
+	if err != nil {
+		return sqlc.Post{}, err
+	}
+
+	if !canTransition(post.Status, target) {
+		return sqlc.Post{}, apperrors.NewConflictError(fmt.Sprintf("cannot move post from %s to %s", post.Status, target))
+	}
+	post, err = s.postRepo.UpdateStatus(ctx, id, target)
+	return post, apperrors.FromDB(err, "post")
+}
+
+// authorizedPost loads a post and checks that the actor may mutate it
+func (s *postService) authorizedPost(ctx context.Context, actor Actor, id int32) (sqlc.Post, error) {
+	post, err := s.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return sqlc.Post{}, apperrors.FromDB(err, "post")
+	}
+
+	if err := authorizeOwner(actor, post.UserID, permissions.PostsModerate, "post"); err != nil {
+		return sqlc.Post{}, err
+	}
+	return post, nil
 }
 
-func (s *postService) GetPost(ctx context.Context, id int32) (sqlc.Post, error) {
-	return s.postRepo.GetByID(ctx, id)
+// recordModeration adds event to the audit log when actor changed a post they do
+// not own; before is the post as it was locked for the change
+func (s *postService) recordModeration(ctx context.Context, actor Actor, event AuditEvent, before sqlc.GetPostDetailRow, after any) {
+	if before.UserID == actor.UserID {
+		return
+	}
+	event.ActorID = actor.UserID
+	event.TargetType, event.TargetID = AuditTargetPost, before.ID
+	event.Before, event.After = before, after
+	s.audit.Record(ctx, event)
 }
 
-func (s *postService) ListPosts(ctx context.Context, limit, offset int32) ([]sqlc.ListPostsRow, error) {
-	return s.postRepo.List(ctx, limit, offset)
+func canTransition(from, to string) bool {
+	for _, next := range postTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
 }
 
-func (s *postService) ListPostsByUser(ctx context.Context, userID int32, limit, offset int32) ([]sqlc.ListPostsByUserRow, error) {
-	return s.postRepo.ListByUser(ctx, userID, limit, offset)
+func validatePublishAt(publishAt time.Time) error {
+	if !publishAt.After(time.Now()) {
+		return apperrors.NewValidationError("publish_at must be in the future")
+	}
+	return nil
 }
 
-func (s *postService) UpdatePost(ctx context.Context, arg sqlc.UpdatePostParams) (sqlc.Post, error) {
-	return s.postRepo.Update(ctx, arg)
+func canSeeUnpublished(viewer *Actor, authorID int32) bool {
+	return viewer != nil && (viewer.UserID == authorID || viewer.Can(permissions.PostsModerate))
 }
 
-func (s *postService) DeletePost(ctx context.Context, id int32) error {
-	return s.postRepo.Delete(ctx, id)
+// normalizeTags lowercases, trims and de-duplicates tags, rejecting malformed names
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, apperrors.NewValidationError(fmt.Sprintf("invalid tag %q: use up to 32 lowercase letters, digits or dashes", tag))
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxPostTags {
+		return nil, apperrors.NewValidationError(fmt.Sprintf("a post can have at most %d tags", maxPostTags))
+	}
+	return normalized, nil
 }
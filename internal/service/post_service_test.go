@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
+	"my_project/internal/database"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/internal/scheduler"
+	"my_project/utils"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var testDB database.Service
+
+func mustStartPostgresContainer() (func(context.Context, ...testcontainers.TerminateOption) error, error) {
+	var (
+		dbName = "database"
+		dbPwd  = "password"
+		dbUser = "user"
+	)
+
+	dbContainer, err := postgres.Run(
+		context.Background(),
+		"postgres:latest",
+		postgres.WithDatabase(dbName),
+		postgres.WithUsername(dbUser),
+		postgres.WithPassword(dbPwd),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	dbHost, err := dbContainer.Host(context.Background())
+	if err != nil {
+		return dbContainer.Terminate, err
+	}
+
+	dbPort, err := dbContainer.MappedPort(context.Background(), "5432/tcp")
+	if err != nil {
+		return dbContainer.Terminate, err
+	}
+
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		dbUser, dbPwd, dbHost, dbPort.Port(), dbName)
+	os.Setenv("DATABASE_URL", databaseURL)
+
+	return dbContainer.Terminate, err
+}
+
+func TestMain(m *testing.M) {
+	teardown, err := mustStartPostgresContainer()
+	if err != nil {
+		log.Fatalf("could not start postgres container: %v", err)
+	}
+
+	testDB = database.New()
+	utils.ConfigureSecretKey([]byte("a-test-encryption-key-of-32-chars"))
+
+	exitCode := m.Run()
+
+	if teardown != nil {
+		if err := teardown(context.Background()); err != nil {
+			log.Fatalf("could not teardown postgres container: %v", err)
+		}
+	}
+
+	os.Exit(exitCode)
+}
+
+func newTestPostService() PostService {
+	return NewPostService(
+		repository.NewPostRepository(testDB.GetDB(), testDB.GetQueries()),
+		NewAuditLogger(repository.NewAuditRepository(testDB.GetQueries())),
+	)
+}
+
+func createTestUser(t *testing.T, name, role string) sqlc.User {
+	t.Helper()
+
+	user, err := testDB.GetQueries().CreateUser(context.Background(), sqlc.CreateUserParams{
+		Username:     name,
+		Email:        name + "@example.com",
+		PasswordHash: "x",
+		Role:         role,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user %s: %v", name, err)
+	}
+	return user
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		t.Fatalf("expected AppError with status %d, got %v", status, err)
+	}
+	if appErr.Status != status {
+		t.Fatalf("expected status %d, got %d (%s)", status, appErr.Status, appErr.Message)
+	}
+}
+
+func TestPostOwnership(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	owner := createTestUser(t, "owner", permissions.RoleUser)
+	other := createTestUser(t, "other", permissions.RoleUser)
+	moderator := createTestUser(t, "moderator", permissions.RoleModerator)
+
+	post, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: owner.ID, Title: "title", Content: "content"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	ownerActor := Actor{UserID: owner.ID, Role: owner.Role}
+	otherActor := Actor{UserID: other.ID, Role: other.Role}
+	moderatorActor := Actor{UserID: moderator.ID, Role: moderator.Role}
+
+	t.Run("other user cannot update", func(t *testing.T) {
+		_, err := svc.UpdatePost(ctx, otherActor, sqlc.UpdatePostParams{ID: post.ID, Title: "hijacked", Content: "hijacked"}, nil, AuditEvent{})
+		assertStatus(t, err, http.StatusForbidden)
+
+		stored, err := svc.GetPost(ctx, nil, post.ID)
+		if err != nil {
+			t.Fatalf("failed to get post: %v", err)
+		}
+		if stored.Title != "title" {
+			t.Errorf("expected title to be unchanged, got %q", stored.Title)
+		}
+	})
+
+	t.Run("other user cannot delete", func(t *testing.T) {
+		err := svc.DeletePost(ctx, otherActor, post.ID, AuditEvent{})
+		assertStatus(t, err, http.StatusForbidden)
+	})
+
+	t.Run("owner can update", func(t *testing.T) {
+		updated, err := svc.UpdatePost(ctx, ownerActor, sqlc.UpdatePostParams{ID: post.ID, Title: "edited", Content: "edited"}, nil, AuditEvent{})
+		if err != nil {
+			t.Fatalf("owner update failed: %v", err)
+		}
+		if updated.Title != "edited" {
+			t.Errorf("expected title 'edited', got %q", updated.Title)
+		}
+	})
+
+	t.Run("moderator can update", func(t *testing.T) {
+		if _, err := svc.UpdatePost(ctx, moderatorActor, sqlc.UpdatePostParams{ID: post.ID, Title: "moderated", Content: "moderated"}, nil, AuditEvent{}); err != nil {
+			t.Fatalf("moderator update failed: %v", err)
+		}
+	})
+
+	t.Run("missing post is not found", func(t *testing.T) {
+		err := svc.DeletePost(ctx, ownerActor, post.ID+1000, AuditEvent{})
+		assertStatus(t, err, http.StatusNotFound)
+	})
+
+	t.Run("owner can delete", func(t *testing.T) {
+		if err := svc.DeletePost(ctx, ownerActor, post.ID, AuditEvent{}); err != nil {
+			t.Fatalf("owner delete failed: %v", err)
+		}
+	})
+}
+
+func TestPostModerationAudit(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+	audit := NewAuditLogger(repository.NewAuditRepository(testDB.GetQueries()))
+	owner := createTestUser(t, "auditowner", permissions.RoleUser)
+	moderator := createTestUser(t, "auditmod", permissions.RoleModerator)
+	ownerActor := Actor{UserID: owner.ID, Role: owner.Role}
+	modActor := Actor{UserID: moderator.ID, Role: moderator.Role}
+
+	post, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: owner.ID, Title: "original", Content: "content"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	events := func() []sqlc.AuditEvent {
+		t.Helper()
+		events, err := audit.List(ctx, AuditFilter{TargetType: AuditTargetPost, TargetID: post.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to list audit events: %v", err)
+		}
+		return events
+	}
+
+	if _, err := svc.UpdatePost(ctx, ownerActor, sqlc.UpdatePostParams{ID: post.ID, Title: "by owner", Content: "content"}, nil, AuditEvent{}); err != nil {
+		t.Fatalf("owner update failed: %v", err)
+	}
+	if got := events(); len(got) != 0 {
+		t.Fatalf("expected owners editing their own posts not to be audited, got %+v", got)
+	}
+
+	if _, err := svc.UpdatePost(ctx, modActor, sqlc.UpdatePostParams{ID: post.ID, Title: "by moderator", Content: "content"}, nil, AuditEvent{RequestID: "req-mod"}); err != nil {
+		t.Fatalf("moderator update failed: %v", err)
+	}
+	if err := svc.DeletePost(ctx, modActor, post.ID, AuditEvent{}); err != nil {
+		t.Fatalf("moderator delete failed: %v", err)
+	}
+
+	got := events()
+	if len(got) != 2 || got[0].Action != AuditPostDelete || got[1].Action != AuditPostUpdate {
+		t.Fatalf("expected an update and a delete, newest first, got %+v", got)
+	}
+	var before, after sqlc.GetPostDetailRow
+	update := got[1]
+	if err := json.Unmarshal(update.BeforeState, &before); err != nil || before.Title != "by owner" {
+		t.Errorf("expected the state the update replaced, got %s (%v)", update.BeforeState, err)
+	}
+	if err := json.Unmarshal(update.AfterState, &after); err != nil || after.Title != "by moderator" {
+		t.Errorf("expected the updated state, got %s (%v)", update.AfterState, err)
+	}
+	if update.ActorID.Int32 != moderator.ID || update.RequestID != "req-mod" {
+		t.Errorf("expected the moderator and request to be recorded, got %+v", update)
+	}
+	if err := json.Unmarshal(got[0].BeforeState, &before); err != nil || before.Title != "by moderator" {
+		t.Errorf("expected the deleted state, got %s (%v)", got[0].BeforeState, err)
+	}
+}
+
+func TestPostLifecycle(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	author := createTestUser(t, "author", permissions.RoleUser)
+	reader := createTestUser(t, "reader", permissions.RoleUser)
+	authorActor := Actor{UserID: author.ID, Role: author.Role}
+	readerActor := Actor{UserID: reader.ID, Role: reader.Role}
+
+	draft, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "draft", Content: "content", Status: PostStatusDraft}, nil)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+
+	_, err = svc.GetPost(ctx, &readerActor, draft.ID)
+	assertStatus(t, err, http.StatusNotFound)
+
+	if _, err := svc.GetPost(ctx, &authorActor, draft.ID); err != nil {
+		t.Fatalf("author should see own draft: %v", err)
+	}
+
+	published, err := svc.PublishPost(ctx, authorActor, draft.ID)
+	if err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	if published.Status != PostStatusPublished || !published.PublishedAt.Valid {
+		t.Errorf("expected published post with published_at, got %q", published.Status)
+	}
+
+	_, err = svc.PublishPost(ctx, authorActor, draft.ID)
+	assertStatus(t, err, http.StatusConflict)
+
+	if _, err := svc.ArchivePost(ctx, authorActor, draft.ID); err != nil {
+		t.Fatalf("failed to archive: %v", err)
+	}
+}
+
+func TestPostTags(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	author := createTestUser(t, "tagger", permissions.RoleUser)
+	authorActor := Actor{UserID: author.ID, Role: author.Role}
+
+	goPost, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "go", Content: "content"}, []string{"Go", " go ", "tagtest-a"})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	if len(goPost.Tags) != 2 || goPost.Tags[0] != "go" {
+		t.Fatalf("expected normalized tags [go tagtest-a], got %v", goPost.Tags)
+	}
+
+	bothPost, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "both", Content: "content"}, []string{"tagtest-a", "tagtest-b"})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	count := func(filter PostFilter) int {
+		t.Helper()
+		posts, err := svc.ListPosts(ctx, filter, nil, 50)
+		if err != nil {
+			t.Fatalf("failed to list posts: %v", err)
+		}
+		return len(posts)
+	}
+
+	if got := count(PostFilter{Tags: []string{"tagtest-a", "tagtest-b"}}); got != 2 {
+		t.Errorf("any-match: expected 2 posts, got %d", got)
+	}
+	if got := count(PostFilter{Tags: []string{"tagtest-a", "tagtest-b"}, MatchAll: true}); got != 1 {
+		t.Errorf("all-match: expected 1 post, got %d", got)
+	}
+
+	t.Run("invalid tag is rejected", func(t *testing.T) {
+		_, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "bad", Content: "content"}, []string{"no spaces"})
+		assertStatus(t, err, http.StatusBadRequest)
+	})
+
+	t.Run("update without tags keeps them, empty slice clears them", func(t *testing.T) {
+		kept, err := svc.UpdatePost(ctx, authorActor, sqlc.UpdatePostParams{ID: bothPost.ID, Title: "both", Content: "edited"}, nil, AuditEvent{})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if len(kept.Tags) != 2 {
+			t.Errorf("expected tags to be kept, got %v", kept.Tags)
+		}
+
+		cleared, err := svc.UpdatePost(ctx, authorActor, sqlc.UpdatePostParams{ID: bothPost.ID, Title: "both", Content: "edited"}, []string{}, AuditEvent{})
+		if err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+		if len(cleared.Tags) != 0 {
+			t.Errorf("expected tags to be cleared, got %v", cleared.Tags)
+		}
+	})
+}
+
+func TestListPostsByUserKeyset(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	author := createTestUser(t, "pager", permissions.RoleUser)
+	for i := 0; i < 5; i++ {
+		if _, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "page", Content: "content"}, nil); err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+	}
+
+	seen := make(map[int32]bool)
+	var after *PostKey
+	for pages := 0; pages < 3; pages++ {
+		posts, err := svc.ListPostsByUser(ctx, nil, author.ID, after, 2)
+		if err != nil {
+			t.Fatalf("failed to list posts: %v", err)
+		}
+		for _, p := range posts {
+			if seen[p.ID] {
+				t.Fatalf("post %d returned on more than one page", p.ID)
+			}
+			seen[p.ID] = true
+		}
+		if len(posts) < 2 {
+			break
+		}
+		last := posts[len(posts)-1]
+		after = &PostKey{CreatedAt: last.CreatedAt.Time, ID: last.ID}
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("expected to page through 5 posts, saw %d", len(seen))
+	}
+}
+
+func TestPostSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	author := createTestUser(t, "softdeleter", permissions.RoleUser)
+	authorActor := Actor{UserID: author.ID, Role: author.Role}
+	post, err := svc.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "title", Content: "content"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	if err := svc.DeletePost(ctx, authorActor, post.ID, AuditEvent{}); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	_, err = svc.GetPost(ctx, nil, post.ID)
+	assertStatus(t, err, http.StatusNotFound)
+	assertStatus(t, svc.DeletePost(ctx, authorActor, post.ID, AuditEvent{}), http.StatusNotFound)
+
+	if _, err := svc.RestorePost(ctx, post.ID); err != nil {
+		t.Fatalf("failed to restore post: %v", err)
+	}
+	if _, err := svc.GetPost(ctx, nil, post.ID); err != nil {
+		t.Errorf("expected the restored post to be visible: %v", err)
+	}
+	_, err = svc.RestorePost(ctx, post.ID)
+	assertStatus(t, err, http.StatusNotFound)
+
+	// Only posts deleted before the cutoff are purged
+	if err := svc.DeletePost(ctx, authorActor, post.ID, AuditEvent{}); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	if _, err := svc.PurgeDeleted(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	if _, err := svc.RestorePost(ctx, post.ID); err != nil {
+		t.Fatalf("expected the post to survive a purge inside the retention window: %v", err)
+	}
+
+	if err := svc.DeletePost(ctx, authorActor, post.ID, AuditEvent{}); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	if _, err := svc.PurgeDeleted(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	_, err = svc.RestorePost(ctx, post.ID)
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestPublishScheduledJob(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestPostService()
+
+	author := createTestUser(t, "scheduler", permissions.RoleUser)
+	post, err := svc.CreatePost(ctx, sqlc.CreatePostParams{
+		UserID:    author.ID,
+		Title:     "scheduled",
+		Content:   "content",
+		Status:    PostStatusDraft,
+		PublishAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to schedule post: %v", err)
+	}
+	// Make it due without waiting for the clock
+	if _, err := testDB.GetDB().ExecContext(ctx, `UPDATE posts SET publish_at = NOW() - INTERVAL '1 minute' WHERE id = $1`, post.ID); err != nil {
+		t.Fatalf("failed to backdate publish_at: %v", err)
+	}
+
+	// Run it the way the server does, through the background runner
+	jobs := scheduler.NewRunner()
+	jobs.Add(scheduler.Job{
+		Name:     "publish-scheduled-posts",
+		Interval: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			_, err := svc.PublishScheduled(ctx)
+			return err
+		},
+	})
+	jobs.Start()
+	defer jobs.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := svc.GetPost(ctx, nil, post.ID)
+		if err == nil && got.Status == PostStatusPublished {
+			if !got.PublishedAt.Valid {
+				t.Error("expected published_at to be set")
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the runner to publish the due draft, last error %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
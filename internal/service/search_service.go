@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"strings"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+// Search scopes accepted by SearchService
+const (
+	SearchScopeAll      = "all"
+	SearchScopePosts    = "posts"
+	SearchScopeComments = "comments"
+)
+
+// maxSearchQueryLength bounds the text handed to websearch_to_tsquery
+const maxSearchQueryLength = 200
+
+// SearchQuery describes a full-text search request; zero values disable a filter
+type SearchQuery struct {
+	Text     string
+	Scope    string
+	AuthorID int32
+	From     time.Time // inclusive
+	To       time.Time // exclusive
+	Limit    int32
+	Offset   int32
+}
+
+// highlightMarks turns the match delimiters emitted by the search queries into <mark> tags
+var highlightMarks = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+// SearchResults holds ranked matches with highlighted snippets. TitleHighlight and
+// Snippet are safe HTML: the text is escaped and only <mark> tags are added.
+type SearchResults struct {
+	Posts    []sqlc.SearchPostsRow    `json:"posts"`
+	Comments []sqlc.SearchCommentsRow `json:"comments"`
+}
+
+// SearchService defines full-text search over published content
+type SearchService interface {
+	Search(ctx context.Context, query SearchQuery) (SearchResults, error)
+}
+
+type searchService struct {
+	searchRepo repository.SearchRepository
+}
+
+// NewSearchService creates a new SearchService instance
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{searchRepo: repo}
+}
+
+// Search matches posts and/or comments; each list is paginated independently with the same limit and offset
+func (s *searchService) Search(ctx context.Context, query SearchQuery) (SearchResults, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return SearchResults{}, apperrors.NewValidationError("search query is required")
+	}
+	if len(query.Text) > maxSearchQueryLength {
+		return SearchResults{}, apperrors.NewValidationError("search query is too long")
+	}
+	if query.Scope == "" {
+		query.Scope = SearchScopeAll
+	}
+	if query.Scope != SearchScopeAll && query.Scope != SearchScopePosts && query.Scope != SearchScopeComments {
+		return SearchResults{}, apperrors.NewValidationError("type must be all, posts or comments")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return SearchResults{}, apperrors.NewValidationError("from must be before to")
+	}
+
+	authorID := sql.NullInt32{Int32: query.AuthorID, Valid: query.AuthorID > 0}
+	from := sql.NullTime{Time: query.From, Valid: !query.From.IsZero()}
+	to := sql.NullTime{Time: query.To, Valid: !query.To.IsZero()}
+
+	results := SearchResults{
+		Posts:    []sqlc.SearchPostsRow{},
+		Comments: []sqlc.SearchCommentsRow{},
+	}
+
+	if query.Scope != SearchScopeComments {
+		posts, err := s.searchRepo.SearchPosts(ctx, sqlc.SearchPostsParams{
+			Query:       query.Text,
+			AuthorID:    authorID,
+			CreatedFrom: from,
+			CreatedTo:   to,
+			PageLimit:   query.Limit,
+			PageOffset:  query.Offset,
+		})
+		if err != nil {
+			return SearchResults{}, err
+		}
+		for i := range posts {
+			posts[i].TitleHighlight = highlightHTML(posts[i].TitleHighlight)
+			posts[i].Snippet = highlightHTML(posts[i].Snippet)
+		}
+		if posts != nil {
+			results.Posts = posts
+		}
+	}
+
+	if query.Scope != SearchScopePosts {
+		comments, err := s.searchRepo.SearchComments(ctx, sqlc.SearchCommentsParams{
+			Query:       query.Text,
+			AuthorID:    authorID,
+			CreatedFrom: from,
+			CreatedTo:   to,
+			PageLimit:   query.Limit,
+			PageOffset:  query.Offset,
+		})
+		if err != nil {
+			return SearchResults{}, err
+		}
+		for i := range comments {
+			comments[i].Snippet = highlightHTML(comments[i].Snippet)
+		}
+		if comments != nil {
+			results.Comments = comments
+		}
+	}
+
+	return results, nil
+}
+
+// highlightHTML escapes a ts_headline result and marks its matches
+func highlightHTML(headline string) string {
+	return highlightMarks.Replace(html.EscapeString(headline))
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+)
+
+func TestSearchPosts(t *testing.T) {
+	ctx := context.Background()
+	posts := newTestPostService()
+	search := NewSearchService(repository.NewSearchRepository(testDB.GetQueries()))
+
+	author := createTestUser(t, "searcher", permissions.RoleUser)
+
+	inContent, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "notes", Content: "a few words about zebrafish"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	inTitle, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "zebrafish", Content: "care and feeding"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	if _, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "zebrafish draft", Content: "hidden", Status: PostStatusDraft}, nil); err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+
+	results, err := search.Search(ctx, SearchQuery{Text: "zebrafish", Scope: SearchScopePosts, Limit: 10})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(results.Posts) != 2 {
+		t.Fatalf("expected 2 published matches, got %d", len(results.Posts))
+	}
+	if results.Posts[0].ID != inTitle.ID || results.Posts[1].ID != inContent.ID {
+		t.Errorf("expected title match to rank first, got ids %d, %d", results.Posts[0].ID, results.Posts[1].ID)
+	}
+	if !strings.Contains(results.Posts[1].Snippet, "<mark>zebrafish</mark>") {
+		t.Errorf("expected highlighted snippet, got %q", results.Posts[1].Snippet)
+	}
+
+	t.Run("author filter", func(t *testing.T) {
+		other := createTestUser(t, "searcher-other", permissions.RoleUser)
+		results, err := search.Search(ctx, SearchQuery{Text: "zebrafish", AuthorID: other.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("search failed: %v", err)
+		}
+		if len(results.Posts) != 0 {
+			t.Errorf("expected no posts for other author, got %d", len(results.Posts))
+		}
+	})
+
+	t.Run("empty query is rejected", func(t *testing.T) {
+		_, err := search.Search(ctx, SearchQuery{Text: "  ", Limit: 10})
+		assertStatus(t, err, http.StatusBadRequest)
+	})
+}
+
+func TestSearchHighlightEscapesContent(t *testing.T) {
+	ctx := context.Background()
+	posts := newTestPostService()
+	search := NewSearchService(repository.NewSearchRepository(testDB.GetQueries()))
+
+	author := createTestUser(t, "highlighter", permissions.RoleUser)
+	if _, err := posts.CreatePost(ctx, sqlc.CreatePostParams{
+		UserID:  author.ID,
+		Title:   `<img src=x onerror=alert(1)> quokka`,
+		Content: "<script>alert(1)</script> the quokka \x02smiles\x03",
+	}, nil); err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	results, err := search.Search(ctx, SearchQuery{Text: "quokka", Scope: SearchScopePosts, Limit: 10})
+	if err != nil || len(results.Posts) != 1 {
+		t.Fatalf("expected one match, got %+v (%v)", results.Posts, err)
+	}
+	hit := results.Posts[0]
+	for _, field := range []string{hit.TitleHighlight, hit.Snippet} {
+		if strings.Contains(field, "<script") || strings.Contains(field, "<img") {
+			t.Errorf("expected markup in content to be escaped, got %q", field)
+		}
+		if !strings.Contains(field, "<mark>quokka</mark>") {
+			t.Errorf("expected the match to be highlighted, got %q", field)
+		}
+	}
+	if strings.Count(hit.Snippet, "<mark>") != 1 {
+		t.Errorf("expected delimiters typed by the author to be dropped, got %q", hit.Snippet)
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	got := highlightHTML("a <b> & \x02c\x03 \"d\"")
+	want := "a &lt;b&gt; &amp; <mark>c</mark> &#34;d&#34;"
+	if got != want {
+		t.Errorf("highlightHTML() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+
+	"github.com/google/uuid"
+)
+
+// RefreshTokenTTL is how long a refresh token (and its session) stays valid
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+const refreshTokenBytes = 32
+
+var (
+	ErrInvalidRefreshToken = apperrors.NewUnauthorizedError("invalid or expired refresh token")
+	ErrRefreshTokenReused  = apperrors.NewUnauthorizedError("refresh token reuse detected")
+)
+
+// SessionMeta describes the client a session was issued to
+type SessionMeta struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
+
+// TokenPair is the result of issuing or rotating a session
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// SessionService issues short-lived access tokens backed by rotating refresh tokens
+type SessionService interface {
+	Issue(ctx context.Context, user sqlc.User, meta SessionMeta) (TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (TokenPair, sqlc.User, error)
+	Logout(ctx context.Context, refreshToken string) (int32, error)
+	RevokeAll(ctx context.Context, userID int32) error
+}
+
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+}
+
+// NewSessionService creates a new SessionService instance
+func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository) SessionService {
+	return &sessionService{sessionRepo: sessionRepo, userRepo: userRepo}
+}
+
+// Issue starts a new session family for the user
+func (s *sessionService) Issue(ctx context.Context, user sqlc.User, meta SessionMeta) (TokenPair, error) {
+	refreshToken, params, err := newSessionParams(user.ID, uuid.New(), meta)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if _, err := s.sessionRepo.Create(ctx, params); err != nil {
+		return TokenPair{}, err
+	}
+	return newTokenPair(user, refreshToken)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already rotated
+// or revoked is treated as theft and revokes the whole session family.
+func (s *sessionService) Refresh(ctx context.Context, refreshToken string, meta SessionMeta) (TokenPair, sqlc.User, error) {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return TokenPair{}, sqlc.User{}, ErrInvalidRefreshToken
+	}
+
+	if session.RevokedAt.Valid {
+		if err := s.sessionRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+			return TokenPair{}, sqlc.User{}, err
+		}
+		return TokenPair{}, sqlc.User{}, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return TokenPair{}, sqlc.User{}, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return TokenPair{}, sqlc.User{}, ErrInvalidRefreshToken
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return TokenPair{}, sqlc.User{}, err
+	}
+
+	nextToken, params, err := newSessionParams(user.ID, session.FamilyID, meta)
+	if err != nil {
+		return TokenPair{}, sqlc.User{}, err
+	}
+
+	if _, err := s.sessionRepo.Rotate(ctx, session.ID, params); err != nil {
+		if errors.Is(err, repository.ErrSessionRevoked) {
+			// Another request rotated this token first: same as a replay
+			if err := s.sessionRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+				return TokenPair{}, sqlc.User{}, err
+			}
+			return TokenPair{}, sqlc.User{}, ErrRefreshTokenReused
+		}
+		return TokenPair{}, sqlc.User{}, err
+	}
+
+	pair, err := newTokenPair(user, nextToken)
+	if err != nil {
+		return TokenPair{}, sqlc.User{}, err
+	}
+	return pair, user, nil
+}
+
+// Logout revokes the session the refresh token belongs to and returns its user
+func (s *sessionService) Logout(ctx context.Context, refreshToken string) (int32, error) {
+	session, err := s.sessionRepo.GetByTokenHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return 0, ErrInvalidRefreshToken
+	}
+	return session.UserID, s.sessionRepo.Revoke(ctx, session.ID)
+}
+
+// RevokeAll ends every active session of a user
+func (s *sessionService) RevokeAll(ctx context.Context, userID int32) error {
+	return s.sessionRepo.RevokeAllForUser(ctx, userID)
+}
+
+func newSessionParams(userID int32, familyID uuid.UUID, meta SessionMeta) (string, sqlc.CreateSessionParams, error) {
+	refreshToken, err := utils.GenerateOpaqueToken(refreshTokenBytes)
+	if err != nil {
+		return "", sqlc.CreateSessionParams{}, err
+	}
+
+	return refreshToken, sqlc.CreateSessionParams{
+		UserID:           userID,
+		FamilyID:         familyID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		DeviceName:       meta.DeviceName,
+		UserAgent:        meta.UserAgent,
+		IpAddress:        meta.IPAddress,
+		ExpiresAt:        time.Now().Add(RefreshTokenTTL),
+	}, nil
+}
+
+func newTokenPair(user sqlc.User, refreshToken string) (TokenPair, error) {
+	accessToken, err := utils.CreateToken(user.ID, user.Email, user.Role, user.EmailVerifiedAt.Valid)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// fakeSessionRepo keeps sessions in memory; Rotate mirrors the conditional revoke of the real one
+type fakeSessionRepo struct {
+	mu       sync.Mutex
+	sessions []sqlc.Session
+}
+
+func (r *fakeSessionRepo) Create(_ context.Context, arg sqlc.CreateSessionParams) (sqlc.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(arg), nil
+}
+
+func (r *fakeSessionRepo) create(arg sqlc.CreateSessionParams) sqlc.Session {
+	session := sqlc.Session{
+		ID:               int32(len(r.sessions) + 1),
+		UserID:           arg.UserID,
+		FamilyID:         arg.FamilyID,
+		RefreshTokenHash: arg.RefreshTokenHash,
+		ExpiresAt:        arg.ExpiresAt,
+	}
+	r.sessions = append(r.sessions, session)
+	return session
+}
+
+func (r *fakeSessionRepo) GetByTokenHash(_ context.Context, hash string) (sqlc.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.sessions {
+		if s.RefreshTokenHash == hash {
+			return s, nil
+		}
+	}
+	return sqlc.Session{}, sql.ErrNoRows
+}
+
+func (r *fakeSessionRepo) Rotate(_ context.Context, oldID int32, next sqlc.CreateSessionParams) (sqlc.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := &r.sessions[oldID-1]
+	if old.RevokedAt.Valid {
+		return sqlc.Session{}, repository.ErrSessionRevoked
+	}
+	old.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return r.create(next), nil
+}
+
+func (r *fakeSessionRepo) Revoke(_ context.Context, id int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[id-1].RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeFamily(_ context.Context, familyID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.sessions {
+		if r.sessions[i].FamilyID == familyID && !r.sessions[i].RevokedAt.Valid {
+			r.sessions[i].RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeAllForUser(_ context.Context, userID int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.sessions {
+		if r.sessions[i].UserID == userID {
+			r.sessions[i].RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+// activeSessions counts sessions that are neither revoked nor expired
+func (r *fakeSessionRepo) activeSessions() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active := 0
+	for _, s := range r.sessions {
+		if !s.RevokedAt.Valid && s.ExpiresAt.After(time.Now()) {
+			active++
+		}
+	}
+	return active
+}
+
+// fakeUserRepo serves GetByID from memory; other methods are not used by SessionService
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[int32]sqlc.User
+}
+
+func (r fakeUserRepo) GetByID(_ context.Context, id int32) (sqlc.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return sqlc.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func newFakeSessionService() (SessionService, *fakeSessionRepo, sqlc.User) {
+	user := sqlc.User{ID: 7, Username: "session-user", Email: "session-user@example.com", Role: "user"}
+	sessions := &fakeSessionRepo{}
+	return NewSessionService(sessions, fakeUserRepo{users: map[int32]sqlc.User{user.ID: user}}), sessions, user
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	svc, sessions, user := newFakeSessionService()
+
+	issued, err := svc.Issue(ctx, user, SessionMeta{DeviceName: "laptop"})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	rotated, refreshedUser, err := svc.Refresh(ctx, issued.RefreshToken, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if refreshedUser.ID != user.ID || rotated.RefreshToken == issued.RefreshToken || rotated.AccessToken == "" {
+		t.Fatalf("expected a new token pair for user %d, got %+v", user.ID, rotated)
+	}
+	if sessions.activeSessions() != 1 {
+		t.Fatalf("expected only the rotated session to be active, got %d", sessions.activeSessions())
+	}
+
+	// Replaying the old token is treated as theft and ends the whole family
+	if _, _, err := svc.Refresh(ctx, issued.RefreshToken, SessionMeta{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused for a replayed token, got %v", err)
+	}
+	if sessions.activeSessions() != 0 {
+		t.Fatalf("expected the family to be revoked after reuse, got %d active", sessions.activeSessions())
+	}
+	if _, _, err := svc.Refresh(ctx, rotated.RefreshToken, SessionMeta{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("expected the newest token of a revoked family to be rejected, got %v", err)
+	}
+
+	if _, _, err := svc.Refresh(ctx, "unknown", SessionMeta{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken for an unknown token, got %v", err)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	ctx := context.Background()
+	svc, sessions, user := newFakeSessionService()
+
+	issued, err := svc.Issue(ctx, user, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	const racers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, racers)
+	for range racers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := svc.Refresh(ctx, issued.RefreshToken, SessionMeta{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrRefreshTokenReused):
+			t.Errorf("unexpected error from a losing refresh: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one refresh to win, got %d", succeeded)
+	}
+	// The losers count as a replay, so even the winner's new session is revoked
+	if sessions.activeSessions() != 0 {
+		t.Errorf("expected the family to be revoked, got %d active", sessions.activeSessions())
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	svc, sessions, user := newFakeSessionService()
+
+	issued, err := svc.Issue(ctx, user, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	userID, err := svc.Logout(ctx, issued.RefreshToken)
+	if err != nil || userID != user.ID {
+		t.Fatalf("expected logout of user %d, got %d (%v)", user.ID, userID, err)
+	}
+	if sessions.activeSessions() != 0 {
+		t.Errorf("expected the session to be revoked")
+	}
+	if _, _, err := svc.Refresh(ctx, issued.RefreshToken, SessionMeta{}); err == nil {
+		t.Errorf("expected a logged-out token to be rejected")
+	}
+
+	if _, err := svc.Logout(ctx, "unknown"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expected ErrInvalidRefreshToken for an unknown token, got %v", err)
+	}
+}
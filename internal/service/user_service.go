@@ -2,26 +2,120 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "my_project/internal/app/errors"
+	"my_project/internal/app/permissions"
 	"my_project/internal/database/sqlc"
 	"my_project/internal/repository"
 	"my_project/utils"
 )
 
+// errInvalidCredentials does not say whether the email or the password was wrong
+var errInvalidCredentials = apperrors.NewUnauthorizedError("invalid credentials")
+
+// ErrWrongCurrentPassword is returned when an account change is confirmed with the wrong password
+var ErrWrongCurrentPassword = apperrors.NewForbiddenError("current password is incorrect")
+
+// ErrAccountSuspended is returned when a suspended user signs in or uses a token
+var ErrAccountSuspended = apperrors.NewForbiddenError("account is suspended")
+
+// errTokenRevoked is returned for access tokens issued before an admin signed the user out
+var errTokenRevoked = apperrors.NewUnauthorizedError("token has been revoked")
+
+// Account statuses accepted by UserFilter
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDeleted   = "deleted"
+)
+
+// DeletedUserUsername is the placeholder account (created by a migration) that
+// keeps the posts and comments of purged users when their content is anonymized
+const DeletedUserUsername = "[deleted user]"
+
+// userPurgeBatch caps how many deleted users one query picks up for purging
+const userPurgeBatch = 100
+
+// userSorts are the orders accepted by UserFilter; a leading "-" sorts descending
+var userSorts = map[string]bool{
+	"":            true,
+	"created_at":  true,
+	"-created_at": true,
+	"username":    true,
+	"-username":   true,
+}
+
+// likeEscaper makes user input match literally inside an ILIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// UserFilter selects users for the admin listing; empty fields match everything
+type UserFilter struct {
+	Query  string
+	Role   string
+	Status string
+	Sort   string
+	Limit  int32
+	Offset int32
+}
+
+// AdminUserUpdate holds the account fields an admin changes; nil fields are kept
+type AdminUserUpdate struct {
+	Username *string
+	Email    *string
+	Role     *string
+}
+
+// ProfileUpdate holds the profile fields to change; nil fields are kept and an
+// empty string clears an optional field
+type ProfileUpdate struct {
+	Username    *string
+	DisplayName *string
+	Bio         *string
+	AvatarURL   *string
+}
+
+// dummyPasswordHash is checked against for unknown emails, so they take as long as a wrong password
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := utils.HashPassword("not-a-real-password")
+	return hash
+})
+
 type UserService interface {
 	Register(ctx context.Context, username, email, password string) (sqlc.User, error)
-	Login(ctx context.Context, email, password string) (string, sqlc.User, error)
+	Login(ctx context.Context, email, password, ipAddress string) (sqlc.User, error)
 	GetUser(ctx context.Context, id int64) (sqlc.User, error)
-	ListUsers(ctx context.Context) ([]sqlc.User, error)
+	ListUsers(ctx context.Context, afterID, limit int32) ([]sqlc.User, error)
 	DeleteUser(ctx context.Context, id int64) error
+	ChangeRole(ctx context.Context, actorID int32, id int64, role string) (sqlc.User, error)
+	UnlockLogin(ctx context.Context, actorID int32, id int64) error
+	UpdateProfile(ctx context.Context, id int32, update ProfileUpdate) (sqlc.User, error)
+	ChangePassword(ctx context.Context, id int32, currentPassword, newPassword string) (sqlc.User, error)
+	CheckEmailChange(ctx context.Context, id int32, currentPassword, newEmail string) (sqlc.User, error)
+	DeleteAccount(ctx context.Context, id int32, currentPassword string) error
+	SearchUsers(ctx context.Context, filter UserFilter) ([]sqlc.User, error)
+	UpdateUser(ctx context.Context, actorID int32, id int64, update AdminUserUpdate) (sqlc.User, error)
+	SuspendUser(ctx context.Context, actorID int32, id int64, reason string, until *time.Time) (sqlc.User, error)
+	UnsuspendUser(ctx context.Context, id int64) (sqlc.User, error)
+	RevokeAccess(ctx context.Context, id int64) error
+	CheckAccess(ctx context.Context, id int32, issuedAt time.Time) (sqlc.User, error)
+	RestoreUser(ctx context.Context, id int64) (sqlc.User, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time, anonymize bool) (int, error)
 }
 
 type userService struct {
 	userRepo repository.UserRepository
+	throttle LoginThrottleService
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, throttle LoginThrottleService) UserService {
+	return &userService{userRepo: userRepo, throttle: throttle}
 }
 
 // Đăng ký
@@ -29,7 +123,10 @@ func (s *userService) Register(ctx context.Context, username, email, password st
 	// Check email tồn tại
 	_, err := s.userRepo.GetByEmail(ctx, email)
 	if err == nil {
-		return sqlc.User{}, errors.New("email already exists")
+		return sqlc.User{}, apperrors.UserEmailExists(email)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, err
 	}
 
 	// Hash mật khẩu
@@ -43,40 +140,392 @@ func (s *userService) Register(ctx context.Context, username, email, password st
 		Username:     username,
 		Email:        email,
 		PasswordHash: hashedPassword,
-		Role:         "user",
+		Role:         permissions.RoleUser,
 	}
-	return s.userRepo.Create(ctx, arg)
+	user, err := s.userRepo.Create(ctx, arg)
+	if constraint, ok := apperrors.UniqueViolation(err); ok {
+		// Lost a race with a concurrent registration, or the username is taken
+		if constraint == "users_email_key" {
+			return sqlc.User{}, apperrors.UserEmailExists(email).Wrap(err)
+		}
+		return sqlc.User{}, apperrors.UserUsernameExists(username).Wrap(err)
+	}
+	return user, apperrors.FromDB(err, "user")
 }
 
-// Login
-func (s *userService) Login(ctx context.Context, email, password string) (string, sqlc.User, error) {
+// Login kiểm tra thông tin đăng nhập; token do SessionService cấp.
+// Lần sai được đếm theo email và IP, kể cả email không tồn tại.
+func (s *userService) Login(ctx context.Context, email, password, ipAddress string) (sqlc.User, error) {
+	if err := s.throttle.Check(ctx, email, ipAddress); err != nil {
+		return sqlc.User{}, err
+	}
+
 	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.CheckPassword(dummyPasswordHash(), password)
+		return sqlc.User{}, s.loginFailed(ctx, email, ipAddress, 0)
+	}
 	if err != nil {
-		return "", sqlc.User{}, errors.New("invalid credentials")
+		return sqlc.User{}, err
 	}
 
 	// Check mật khẩu
 	if !utils.CheckPassword(user.PasswordHash, password) {
-		return "", sqlc.User{}, errors.New("invalid credentials")
+		return sqlc.User{}, s.loginFailed(ctx, email, ipAddress, user.ID)
 	}
 
-	// Sinh JWT
-	token, err := utils.CreateToken(user.ID, user.Email)
-	if err != nil {
-		return "", sqlc.User{}, err
+	// With 2FA on, the password alone is not a successful login: failures are
+	// cleared by MFAService.CompleteLogin once the second factor checks out
+	if !user.TotpEnabledAt.Valid {
+		if err := s.throttle.RecordSuccess(ctx, email); err != nil {
+			return sqlc.User{}, err
+		}
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return sqlc.User{}, err
 	}
+	return user, nil
+}
 
-	return token, user, nil
+func (s *userService) loginFailed(ctx context.Context, email, ipAddress string, userID int32) error {
+	if err := s.throttle.RecordFailure(ctx, email, ipAddress, userID); err != nil {
+		return err
+	}
+	return errInvalidCredentials
 }
 
 func (s *userService) GetUser(ctx context.Context, id int64) (sqlc.User, error) {
-	return s.userRepo.GetByID(ctx, int32(id))
+	user, err := s.userRepo.GetByID(ctx, int32(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(id)
+	}
+	return user, err
 }
 
-func (s *userService) ListUsers(ctx context.Context) ([]sqlc.User, error) {
-	return s.userRepo.List(ctx)
+// ListUsers returns up to limit users with an id greater than afterID
+func (s *userService) ListUsers(ctx context.Context, afterID, limit int32) ([]sqlc.User, error) {
+	return s.userRepo.List(ctx, afterID, limit)
 }
 
+// DeleteUser soft-deletes a user and signs them out; the account and its content
+// are hidden but can be restored until the purge job removes them
 func (s *userService) DeleteUser(ctx context.Context, id int64) error {
-	return s.userRepo.Delete(ctx, int32(id))
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Username == DeletedUserUsername {
+		return apperrors.NewForbiddenError("the deleted user placeholder cannot be deleted")
+	}
+
+	err = s.userRepo.Delete(ctx, int32(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.UserNotFound(id)
+	}
+	return apperrors.FromDB(err, "user")
+}
+
+// RestoreUser brings back a deleted user who has not been purged yet. Sessions
+// revoked by the deletion stay revoked.
+func (s *userService) RestoreUser(ctx context.Context, id int64) (sqlc.User, error) {
+	user, err := s.userRepo.Restore(ctx, int32(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.NewNotFoundError(fmt.Sprintf("No deleted user with ID %d", id))
+	}
+	return user, err
+}
+
+// PurgeDeleted hard-deletes users deleted before deletedBefore; called by the
+// background scheduler. With anonymize their content is kept under DeletedUserUsername.
+func (s *userService) PurgeDeleted(ctx context.Context, deletedBefore time.Time, anonymize bool) (int, error) {
+	total := 0
+	for {
+		ids, err := s.userRepo.ListPurgeable(ctx, deletedBefore, userPurgeBatch)
+		if err != nil {
+			return total, err
+		}
+		for _, id := range ids {
+			if err := s.userRepo.Purge(ctx, id, anonymize); err != nil {
+				return total, err
+			}
+			total++
+		}
+		if len(ids) < userPurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+// ChangeRole promotes or demotes a user. Admins cannot change their own role,
+// which keeps at least the acting admin in place.
+func (s *userService) ChangeRole(ctx context.Context, actorID int32, id int64, role string) (sqlc.User, error) {
+	if !permissions.IsValidRole(role) {
+		return sqlc.User{}, apperrors.InvalidRole(role)
+	}
+	if int64(actorID) == id {
+		return sqlc.User{}, apperrors.NewForbiddenError("You cannot change your own role")
+	}
+
+	user, err := s.userRepo.UpdateRole(ctx, int32(id), role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(id)
+	}
+	return user, err
+}
+
+// UnlockLogin lifts a failed-login lockout on the user's account
+func (s *userService) UnlockLogin(ctx context.Context, actorID int32, id int64) error {
+	user, err := s.userRepo.GetByID(ctx, int32(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.UserNotFound(id)
+	}
+	if err != nil {
+		return err
+	}
+	return s.throttle.Unlock(ctx, actorID, user.Email)
+}
+
+// UpdateProfile changes the fields set in update
+func (s *userService) UpdateProfile(ctx context.Context, id int32, update ProfileUpdate) (sqlc.User, error) {
+	arg := sqlc.UpdateUserProfileParams{ID: id}
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		if username == "" {
+			return sqlc.User{}, apperrors.NewValidationError("username cannot be empty")
+		}
+		arg.Username = sql.NullString{String: username, Valid: true}
+	}
+	if update.DisplayName != nil {
+		arg.DisplayName = sql.NullString{String: strings.TrimSpace(*update.DisplayName), Valid: true}
+	}
+	if update.Bio != nil {
+		arg.Bio = sql.NullString{String: strings.TrimSpace(*update.Bio), Valid: true}
+	}
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" && !isWebURL(avatarURL) {
+			return sqlc.User{}, apperrors.NewValidationError("avatar_url must be an http or https URL")
+		}
+		arg.AvatarUrl = sql.NullString{String: avatarURL, Valid: true}
+	}
+
+	user, err := s.userRepo.UpdateProfile(ctx, arg)
+	if _, taken := apperrors.UniqueViolation(err); taken {
+		return sqlc.User{}, apperrors.UserUsernameExists(arg.Username.String).Wrap(err)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(int64(id))
+	}
+	return user, err
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// session and access token of the user is revoked; the caller gets a new
+// session from the handler, other devices have to sign in again
+func (s *userService) ChangePassword(ctx context.Context, id int32, currentPassword, newPassword string) (sqlc.User, error) {
+	user, err := s.checkPassword(ctx, id, currentPassword)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, id, hashedPassword); err != nil {
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+// CheckEmailChange confirms the password and that newEmail is free; the change
+// itself happens once EmailVerificationService has confirmed the new address
+func (s *userService) CheckEmailChange(ctx context.Context, id int32, currentPassword, newEmail string) (sqlc.User, error) {
+	user, err := s.checkPassword(ctx, id, currentPassword)
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if strings.EqualFold(user.Email, newEmail) {
+		return sqlc.User{}, apperrors.NewBadRequestError("this is already your email address")
+	}
+
+	_, err = s.userRepo.GetByEmail(ctx, newEmail)
+	if err == nil {
+		return sqlc.User{}, apperrors.UserEmailExists(newEmail)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, err
+	}
+	return user, nil
+}
+
+// DeleteAccount deletes the user's own account after checking the password
+func (s *userService) DeleteAccount(ctx context.Context, id int32, currentPassword string) error {
+	if _, err := s.checkPassword(ctx, id, currentPassword); err != nil {
+		return err
+	}
+	return s.DeleteUser(ctx, int64(id))
+}
+
+// SearchUsers returns one page of users matching filter
+func (s *userService) SearchUsers(ctx context.Context, filter UserFilter) ([]sqlc.User, error) {
+	if filter.Role != "" && !permissions.IsValidRole(filter.Role) {
+		return nil, apperrors.InvalidRole(filter.Role)
+	}
+	switch filter.Status {
+	case "", UserStatusActive, UserStatusSuspended, UserStatusDeleted:
+	default:
+		return nil, apperrors.NewValidationError("status must be active, suspended or deleted")
+	}
+	if !userSorts[filter.Sort] {
+		return nil, apperrors.NewValidationError("sort must be one of created_at, -created_at, username, -username")
+	}
+
+	query := strings.TrimSpace(filter.Query)
+	return s.userRepo.Search(ctx, sqlc.SearchUsersParams{
+		Query:      sql.NullString{String: likeEscaper.Replace(query), Valid: query != ""},
+		Role:       sql.NullString{String: filter.Role, Valid: filter.Role != ""},
+		Status:     sql.NullString{String: filter.Status, Valid: filter.Status != ""},
+		Sort:       filter.Sort,
+		PageLimit:  filter.Limit,
+		PageOffset: filter.Offset,
+	})
+}
+
+// UpdateUser lets an admin change a user's username, email or role. A changed
+// email is no longer verified; admins cannot change their own role.
+func (s *userService) UpdateUser(ctx context.Context, actorID int32, id int64, update AdminUserUpdate) (sqlc.User, error) {
+	arg := sqlc.AdminUpdateUserParams{ID: int32(id)}
+	if update.Username != nil {
+		username := strings.TrimSpace(*update.Username)
+		if username == "" {
+			return sqlc.User{}, apperrors.NewValidationError("username cannot be empty")
+		}
+		arg.Username = sql.NullString{String: username, Valid: true}
+	}
+	if update.Email != nil {
+		arg.Email = sql.NullString{String: strings.TrimSpace(*update.Email), Valid: true}
+	}
+	if update.Role != nil {
+		if !permissions.IsValidRole(*update.Role) {
+			return sqlc.User{}, apperrors.InvalidRole(*update.Role)
+		}
+		if int64(actorID) == id {
+			return sqlc.User{}, apperrors.NewForbiddenError("You cannot change your own role")
+		}
+		arg.Role = sql.NullString{String: *update.Role, Valid: true}
+	}
+
+	user, err := s.userRepo.AdminUpdate(ctx, arg)
+	if constraint, ok := apperrors.UniqueViolation(err); ok {
+		if constraint == "users_email_key" {
+			return sqlc.User{}, apperrors.UserEmailExists(arg.Email.String).Wrap(err)
+		}
+		return sqlc.User{}, apperrors.UserUsernameExists(arg.Username.String).Wrap(err)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(id)
+	}
+	return user, err
+}
+
+// SuspendUser blocks a user from signing in and revokes their sessions and tokens.
+// Without until the suspension lasts until UnsuspendUser.
+func (s *userService) SuspendUser(ctx context.Context, actorID int32, id int64, reason string, until *time.Time) (sqlc.User, error) {
+	if int64(actorID) == id {
+		return sqlc.User{}, apperrors.NewForbiddenError("You cannot suspend your own account")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return sqlc.User{}, apperrors.NewValidationError("a suspension reason is required")
+	}
+
+	arg := sqlc.SuspendUserParams{
+		ID:               int32(id),
+		SuspensionReason: sql.NullString{String: reason, Valid: true},
+	}
+	if until != nil {
+		if !until.After(time.Now()) {
+			return sqlc.User{}, apperrors.NewValidationError("until must be in the future")
+		}
+		arg.SuspendedUntil = sql.NullTime{Time: *until, Valid: true}
+	}
+
+	user, err := s.userRepo.Suspend(ctx, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(id)
+	}
+	return user, err
+}
+
+// UnsuspendUser lifts a suspension; the user has to sign in again
+func (s *userService) UnsuspendUser(ctx context.Context, id int64) (sqlc.User, error) {
+	user, err := s.userRepo.Unsuspend(ctx, int32(id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.UserNotFound(id)
+	}
+	return user, err
+}
+
+// RevokeAccess signs a user out everywhere: sessions are revoked and access
+// tokens issued so far are rejected
+func (s *userService) RevokeAccess(ctx context.Context, id int64) error {
+	if _, err := s.GetUser(ctx, id); err != nil {
+		return err
+	}
+	return s.userRepo.RevokeAccess(ctx, int32(id))
+}
+
+// CheckAccess decides whether an access token issued at issuedAt still stands
+// for the user and returns the account; AuthMiddleware calls it on every request
+// and takes the role from it rather than from the token
+func (s *userService) CheckAccess(ctx context.Context, id int32, issuedAt time.Time) (sqlc.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, apperrors.NewUnauthorizedError("account no longer exists")
+	}
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if err := checkNotSuspended(user); err != nil {
+		return sqlc.User{}, err
+	}
+	// iat has second precision, so compare against the second of the revocation
+	if user.TokensRevokedAt.Valid && issuedAt.Before(user.TokensRevokedAt.Time.Truncate(time.Second)) {
+		return sqlc.User{}, errTokenRevoked
+	}
+	return user, nil
+}
+
+// isSuspended reports whether the user is suspended at the given time
+func isSuspended(user sqlc.User, at time.Time) bool {
+	return user.SuspendedAt.Valid && (!user.SuspendedUntil.Valid || user.SuspendedUntil.Time.After(at))
+}
+
+// checkNotSuspended returns ErrAccountSuspended, with the reason and end, for suspended users
+func checkNotSuspended(user sqlc.User) error {
+	if !isSuspended(user, time.Now()) {
+		return nil
+	}
+	details := map[string]any{"reason": user.SuspensionReason.String}
+	if user.SuspendedUntil.Valid {
+		details["until"] = user.SuspendedUntil.Time
+	}
+	return ErrAccountSuspended.WithDetails(details)
+}
+
+func (s *userService) checkPassword(ctx context.Context, id int32, password string) (sqlc.User, error) {
+	user, err := s.GetUser(ctx, int64(id))
+	if err != nil {
+		return sqlc.User{}, err
+	}
+	if !utils.CheckPassword(user.PasswordHash, password) {
+		return sqlc.User{}, ErrWrongCurrentPassword
+	}
+	return user, nil
+}
+
+func isWebURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
 }
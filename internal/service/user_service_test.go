@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"my_project/internal/app/permissions"
+	"my_project/internal/database/sqlc"
+	"my_project/internal/repository"
+	"my_project/utils"
+)
+
+// createTestUserWithPassword creates a user whose password is "password"
+func createTestUserWithPassword(t *testing.T, name string) sqlc.User {
+	t.Helper()
+
+	user := createTestUser(t, name, permissions.RoleUser)
+	hash, err := utils.HashPassword("password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := testDB.GetQueries().UpdateUserPassword(context.Background(), sqlc.UpdateUserPasswordParams{ID: user.ID, PasswordHash: hash}); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+	return user
+}
+
+func newTestUserService() UserService {
+	throttle := NewLoginThrottleService(
+		repository.NewLoginThrottleRepository(testDB.GetDB(), testDB.GetQueries()),
+		DefaultAccountLoginPolicy, DefaultIPLoginPolicy,
+	)
+	return NewUserService(repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries()), throttle)
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	user := createTestUser(t, "profile", permissions.RoleUser)
+	createTestUser(t, "profiletaken", permissions.RoleUser)
+
+	updated, err := svc.UpdateProfile(ctx, user.ID, ProfileUpdate{
+		DisplayName: ptr("Profile Person"),
+		Bio:         ptr("hello"),
+		AvatarURL:   ptr("https://example.com/a.png"),
+	})
+	if err != nil {
+		t.Fatalf("failed to update profile: %v", err)
+	}
+	if updated.Username != "profile" || updated.DisplayName.String != "Profile Person" || updated.Bio.String != "hello" {
+		t.Errorf("unexpected profile %+v", updated)
+	}
+
+	// Omitted fields are kept, an empty string clears
+	updated, err = svc.UpdateProfile(ctx, user.ID, ProfileUpdate{Username: ptr("profile2"), Bio: ptr("")})
+	if err != nil {
+		t.Fatalf("failed to update profile: %v", err)
+	}
+	if updated.Username != "profile2" || updated.DisplayName.String != "Profile Person" || updated.Bio.Valid || !updated.AvatarUrl.Valid {
+		t.Errorf("expected a partial update, got %+v", updated)
+	}
+
+	_, err = svc.UpdateProfile(ctx, user.ID, ProfileUpdate{Username: ptr("profiletaken")})
+	assertStatus(t, err, http.StatusConflict)
+
+	_, err = svc.UpdateProfile(ctx, user.ID, ProfileUpdate{AvatarURL: ptr("javascript:alert(1)")})
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestChangePasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	sessions := NewSessionService(repository.NewSessionRepository(testDB.GetDB(), testDB.GetQueries()), userRepo)
+	user := createTestUserWithPassword(t, "changer")
+
+	tokens, err := sessions.Issue(ctx, user, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	_, err = svc.ChangePassword(ctx, user.ID, "wrong", "newpassword")
+	assertStatus(t, err, http.StatusForbidden)
+
+	if _, err := svc.ChangePassword(ctx, user.ID, "password", "newpassword"); err != nil {
+		t.Fatalf("failed to change password: %v", err)
+	}
+	if _, _, err := sessions.Refresh(ctx, tokens.RefreshToken, SessionMeta{}); err == nil {
+		t.Errorf("expected existing sessions to be revoked")
+	}
+	_, err = svc.CheckAccess(ctx, user.ID, time.Now().Add(-time.Minute))
+	assertStatus(t, err, http.StatusUnauthorized)
+	if _, err := svc.Login(ctx, user.Email, "newpassword", "127.0.0.1"); err != nil {
+		t.Errorf("expected the new password to work: %v", err)
+	}
+}
+
+func TestChangeRole(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	sessions := NewSessionService(repository.NewSessionRepository(testDB.GetDB(), testDB.GetQueries()), userRepo)
+	admin := createTestUser(t, "roleadmin", permissions.RoleAdmin)
+	user := createTestUser(t, "promoted", permissions.RoleUser)
+	issuedAt := time.Now().Add(-time.Minute)
+
+	tokens, err := sessions.Issue(ctx, user, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+
+	_, err = svc.ChangeRole(ctx, admin.ID, int64(admin.ID), permissions.RoleUser)
+	assertStatus(t, err, http.StatusForbidden)
+	_, err = svc.ChangeRole(ctx, admin.ID, int64(user.ID), "superuser")
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.ChangeRole(ctx, admin.ID, 999999, permissions.RoleModerator)
+	assertStatus(t, err, http.StatusNotFound)
+
+	changed, err := svc.ChangeRole(ctx, admin.ID, int64(user.ID), permissions.RoleModerator)
+	if err != nil {
+		t.Fatalf("failed to change role: %v", err)
+	}
+	if changed.Role != permissions.RoleModerator {
+		t.Errorf("expected role %s, got %s", permissions.RoleModerator, changed.Role)
+	}
+
+	// Tokens carrying the old role must not outlive the change
+	if _, _, err := sessions.Refresh(ctx, tokens.RefreshToken, SessionMeta{}); err == nil {
+		t.Errorf("expected existing sessions to be revoked")
+	}
+	_, err = svc.CheckAccess(ctx, user.ID, issuedAt)
+	assertStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	user := createTestUserWithPassword(t, "leaver")
+
+	assertStatus(t, svc.DeleteAccount(ctx, user.ID, "wrong"), http.StatusForbidden)
+	if err := svc.DeleteAccount(ctx, user.ID, "password"); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	_, err := svc.GetUser(ctx, int64(user.ID))
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	admin := createTestUser(t, "lookupadmin", permissions.RoleAdmin)
+	alpha := createTestUser(t, "lookup_alpha", permissions.RoleModerator)
+	createTestUser(t, "lookupxalpha", permissions.RoleUser)
+	createTestUser(t, "lookup%beta", permissions.RoleUser)
+	createTestUser(t, `lookup\gamma`, permissions.RoleUser)
+
+	if _, err := svc.SuspendUser(ctx, admin.ID, int64(alpha.ID), "spam", nil); err != nil {
+		t.Fatalf("failed to suspend: %v", err)
+	}
+
+	usernames := func(filter UserFilter) []string {
+		t.Helper()
+		filter.Limit = 10
+		users, err := svc.SearchUsers(ctx, filter)
+		if err != nil {
+			t.Fatalf("failed to search users: %v", err)
+		}
+		names := make([]string, len(users))
+		for i, u := range users {
+			names[i] = u.Username
+		}
+		return names
+	}
+
+	// "_" is matched literally, not as a wildcard
+	if got := usernames(UserFilter{Query: "LOOKUP_"}); len(got) != 1 || got[0] != "lookup_alpha" {
+		t.Errorf("expected only lookup_alpha, got %v", got)
+	}
+	// so are "%" and the escape character itself
+	if got := usernames(UserFilter{Query: "p%b"}); len(got) != 1 || got[0] != "lookup%beta" {
+		t.Errorf("expected only lookup%%beta, got %v", got)
+	}
+	if got := usernames(UserFilter{Query: `p\g`}); len(got) != 1 || got[0] != `lookup\gamma` {
+		t.Errorf(`expected only lookup\gamma, got %v`, got)
+	}
+	if got := usernames(UserFilter{Query: "lookup", Role: permissions.RoleModerator}); len(got) != 1 || got[0] != "lookup_alpha" {
+		t.Errorf("expected only the moderator, got %v", got)
+	}
+	if got := usernames(UserFilter{Query: "lookup", Status: UserStatusActive, Sort: "-username"}); len(got) != 4 || got[0] != "lookupxalpha" {
+		t.Errorf("expected active users in descending order, got %v", got)
+	}
+
+	_, err := svc.SearchUsers(ctx, UserFilter{Sort: "password_hash"})
+	assertStatus(t, err, http.StatusBadRequest)
+	_, err = svc.SearchUsers(ctx, UserFilter{Status: "deleted"})
+	assertStatus(t, err, http.StatusBadRequest)
+}
+
+func TestAdminUpdateUser(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	admin := createTestUser(t, "editadmin", permissions.RoleAdmin)
+	user := createTestUser(t, "edited", permissions.RoleUser)
+	createTestUser(t, "edittaken", permissions.RoleUser)
+	if _, err := testDB.GetQueries().MarkUserEmailVerified(ctx, user.ID); err != nil {
+		t.Fatalf("failed to verify email: %v", err)
+	}
+	mail := &recordingMailer{}
+	verification := NewEmailVerificationService(
+		repository.NewEmailVerificationRepository(testDB.GetDB(), testDB.GetQueries()),
+		repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries()), mail, "http://localhost/verify",
+	)
+	if err := verification.SendVerification(ctx, user); err != nil {
+		t.Fatalf("failed to send verification: %v", err)
+	}
+	oldLink := mail.lastToken(t)
+
+	updated, err := svc.UpdateUser(ctx, admin.ID, int64(user.ID), AdminUserUpdate{
+		Email: ptr("edited-new@example.com"),
+		Role:  ptr(permissions.RoleModerator),
+	})
+	if err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	if updated.Email != "edited-new@example.com" || updated.Role != permissions.RoleModerator || updated.Username != "edited" {
+		t.Errorf("unexpected user %+v", updated)
+	}
+	if updated.EmailVerifiedAt.Valid {
+		t.Errorf("expected a changed email to need verification again")
+	}
+	// A link mailed to the old address must not verify the new one
+	_, err = verification.Verify(ctx, oldLink)
+	assertStatus(t, err, http.StatusBadRequest)
+	// Tokens carrying the old role must not outlive the change
+	_, err = svc.CheckAccess(ctx, user.ID, time.Now().Add(-time.Minute))
+	assertStatus(t, err, http.StatusUnauthorized)
+
+	_, err = svc.UpdateUser(ctx, admin.ID, int64(user.ID), AdminUserUpdate{Email: ptr("edittaken@example.com")})
+	assertStatus(t, err, http.StatusConflict)
+	_, err = svc.UpdateUser(ctx, admin.ID, int64(admin.ID), AdminUserUpdate{Role: ptr(permissions.RoleUser)})
+	assertStatus(t, err, http.StatusForbidden)
+	_, err = svc.UpdateUser(ctx, admin.ID, 999999, AdminUserUpdate{Username: ptr("nobody")})
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestSuspendUser(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	userRepo := repository.NewUserRepository(testDB.GetDB(), testDB.GetQueries())
+	sessions := NewSessionService(repository.NewSessionRepository(testDB.GetDB(), testDB.GetQueries()), userRepo)
+	admin := createTestUser(t, "suspendadmin", permissions.RoleAdmin)
+	user := createTestUserWithPassword(t, "suspended")
+
+	tokens, err := sessions.Issue(ctx, user, SessionMeta{})
+	if err != nil {
+		t.Fatalf("failed to issue session: %v", err)
+	}
+	issuedAt := time.Now().Add(-time.Minute)
+
+	_, err = svc.SuspendUser(ctx, admin.ID, int64(admin.ID), "oops", nil)
+	assertStatus(t, err, http.StatusForbidden)
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.SuspendUser(ctx, admin.ID, int64(user.ID), "spam", &past)
+	assertStatus(t, err, http.StatusBadRequest)
+
+	if _, err := svc.SuspendUser(ctx, admin.ID, int64(user.ID), "spam", nil); err != nil {
+		t.Fatalf("failed to suspend: %v", err)
+	}
+	_, err = svc.Login(ctx, user.Email, "password", "127.0.0.1")
+	assertStatus(t, err, http.StatusForbidden)
+	_, err = svc.CheckAccess(ctx, user.ID, time.Now())
+	assertStatus(t, err, http.StatusForbidden)
+	if _, _, err := sessions.Refresh(ctx, tokens.RefreshToken, SessionMeta{}); err == nil {
+		t.Errorf("expected sessions to be revoked on suspension")
+	}
+
+	if _, err := svc.UnsuspendUser(ctx, int64(user.ID)); err != nil {
+		t.Fatalf("failed to unsuspend: %v", err)
+	}
+	if _, err := svc.Login(ctx, user.Email, "password", "127.0.0.1"); err != nil {
+		t.Errorf("expected login to work after unsuspending: %v", err)
+	}
+	// Tokens from before the suspension stay revoked
+	_, err = svc.CheckAccess(ctx, user.ID, issuedAt)
+	assertStatus(t, err, http.StatusUnauthorized)
+	if _, err := svc.CheckAccess(ctx, user.ID, time.Now().Add(time.Second)); err != nil {
+		t.Errorf("expected a new token to be accepted: %v", err)
+	}
+}
+
+func TestSuspensionExpires(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	admin := createTestUser(t, "expireadmin", permissions.RoleAdmin)
+	user := createTestUserWithPassword(t, "expiring")
+
+	until := time.Now().Add(time.Hour)
+	if _, err := svc.SuspendUser(ctx, admin.ID, int64(user.ID), "cool down", &until); err != nil {
+		t.Fatalf("failed to suspend: %v", err)
+	}
+	_, err := svc.Login(ctx, user.Email, "password", "127.0.0.1")
+	assertStatus(t, err, http.StatusForbidden)
+
+	if _, err := testDB.GetDB().ExecContext(ctx, `UPDATE users SET suspended_until = now() - interval '1 second' WHERE id = $1`, user.ID); err != nil {
+		t.Fatalf("failed to move suspension end: %v", err)
+	}
+	if _, err := svc.Login(ctx, user.Email, "password", "127.0.0.1"); err != nil {
+		t.Errorf("expected the suspension to have lapsed: %v", err)
+	}
+}
+
+func TestRevokeAccess(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	user := createTestUser(t, "revoked", permissions.RoleUser)
+	issuedAt := time.Now().Add(-time.Minute)
+
+	if _, err := svc.CheckAccess(ctx, user.ID, issuedAt); err != nil {
+		t.Fatalf("expected the token to be valid: %v", err)
+	}
+	if err := svc.RevokeAccess(ctx, int64(user.ID)); err != nil {
+		t.Fatalf("failed to revoke access: %v", err)
+	}
+	_, err := svc.CheckAccess(ctx, user.ID, issuedAt)
+	assertStatus(t, err, http.StatusUnauthorized)
+	assertStatus(t, svc.RevokeAccess(ctx, 999999), http.StatusNotFound)
+}
+
+func TestSoftDeleteUser(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	posts := newTestPostService()
+	user := createTestUserWithPassword(t, "comeback")
+
+	post, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: user.ID, Title: "title", Content: "content"}, nil)
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	if err := svc.DeleteUser(ctx, int64(user.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	_, err = svc.GetUser(ctx, int64(user.ID))
+	assertStatus(t, err, http.StatusNotFound)
+	_, err = svc.Login(ctx, user.Email, "password", "127.0.0.1")
+	assertStatus(t, err, http.StatusUnauthorized)
+	_, err = posts.GetPost(ctx, nil, post.ID)
+	assertStatus(t, err, http.StatusNotFound)
+
+	listed, err := svc.SearchUsers(ctx, UserFilter{Query: "comeback", Limit: 10})
+	if err != nil || len(listed) != 0 {
+		t.Errorf("expected deleted users to be hidden, got %v (%v)", listed, err)
+	}
+	listed, err = svc.SearchUsers(ctx, UserFilter{Query: "comeback", Status: UserStatusDeleted, Limit: 10})
+	if err != nil || len(listed) != 1 {
+		t.Errorf("expected the deleted user with status=deleted, got %v (%v)", listed, err)
+	}
+
+	if _, err := svc.RestoreUser(ctx, int64(user.ID)); err != nil {
+		t.Fatalf("failed to restore user: %v", err)
+	}
+	if _, err := svc.GetUser(ctx, int64(user.ID)); err != nil {
+		t.Errorf("expected the restored user to be visible: %v", err)
+	}
+	if _, err := posts.GetPost(ctx, nil, post.ID); err != nil {
+		t.Errorf("expected the restored user's post to be visible: %v", err)
+	}
+	_, err = svc.RestoreUser(ctx, int64(user.ID))
+	assertStatus(t, err, http.StatusNotFound)
+
+	var placeholderID int32
+	if err := testDB.GetDB().QueryRowContext(ctx, `SELECT id FROM users WHERE username = $1`, DeletedUserUsername).Scan(&placeholderID); err != nil {
+		t.Fatalf("expected the placeholder user from the migration: %v", err)
+	}
+	assertStatus(t, svc.DeleteUser(ctx, int64(placeholderID)), http.StatusForbidden)
+	_, err = svc.Login(ctx, "deleted-user@invalid", "!", "127.0.0.1")
+	assertStatus(t, err, http.StatusUnauthorized)
+	listed, err = svc.SearchUsers(ctx, UserFilter{Query: DeletedUserUsername, Limit: 10})
+	if err != nil || len(listed) != 0 {
+		t.Errorf("expected the placeholder to be hidden from the admin listing, got %v (%v)", listed, err)
+	}
+}
+
+func TestDeletedUserContentHidden(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	comments, posts := newTestCommentService()
+	author := createTestUser(t, "hiddenauthor", permissions.RoleUser)
+	commenter := createTestUser(t, "hiddencommenter", permissions.RoleUser)
+	moderator := createTestUser(t, "hiddenmod", permissions.RoleModerator)
+
+	post, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: author.ID, Title: "title", Content: "content"}, []string{"hiddentag"})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+	if _, err := posts.PublishPost(ctx, Actor{UserID: author.ID, Role: author.Role}, post.ID); err != nil {
+		t.Fatalf("failed to publish post: %v", err)
+	}
+	comment, err := comments.CreateComment(ctx, sqlc.CreateCommentParams{PostID: post.ID, UserID: commenter.ID, Content: "hello"})
+	if err != nil {
+		t.Fatalf("failed to create comment: %v", err)
+	}
+
+	tagCount := func() int64 {
+		t.Helper()
+		tags, err := posts.ListTags(ctx, 1000)
+		if err != nil {
+			t.Fatalf("failed to list tags: %v", err)
+		}
+		for _, tag := range tags {
+			if tag.Name == "hiddentag" {
+				return tag.PostCount
+			}
+		}
+		return 0
+	}
+	if got := tagCount(); got != 1 {
+		t.Fatalf("expected the tag on 1 post, got %d", got)
+	}
+
+	// A deleted commenter's comments are neither counted nor reachable by id
+	if err := svc.DeleteUser(ctx, int64(commenter.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	detail, err := posts.GetPost(ctx, nil, post.ID)
+	if err != nil {
+		t.Fatalf("failed to get post: %v", err)
+	}
+	if detail.CommentCount != 0 {
+		t.Errorf("expected the deleted user's comment not to be counted, got %d", detail.CommentCount)
+	}
+	_, err = comments.UpdateComment(ctx, Actor{UserID: moderator.ID, Role: moderator.Role}, sqlc.UpdateCommentParams{ID: comment.ID, Content: "edited"})
+	assertStatus(t, err, http.StatusNotFound)
+
+	// A deleted author's posts drop out of the tag cloud
+	if err := svc.DeleteUser(ctx, int64(author.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+	if got := tagCount(); got != 0 {
+		t.Errorf("expected the deleted author's post not to count, got %d", got)
+	}
+	// and take their comment threads with them
+	_, err = comments.ListComments(ctx, post.ID, 10, 0)
+	assertStatus(t, err, http.StatusNotFound)
+	_, err = comments.CreateComment(ctx, sqlc.CreateCommentParams{PostID: post.ID, UserID: moderator.ID, Content: "still here?"})
+	assertStatus(t, err, http.StatusNotFound)
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestUserService()
+	posts := newTestPostService()
+
+	createWithPost := func(name string) (sqlc.User, int32) {
+		t.Helper()
+		user := createTestUser(t, name, permissions.RoleUser)
+		post, err := posts.CreatePost(ctx, sqlc.CreatePostParams{UserID: user.ID, Title: name, Content: "content"}, nil)
+		if err != nil {
+			t.Fatalf("failed to create post: %v", err)
+		}
+		if err := svc.DeleteUser(ctx, int64(user.ID)); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+		return user, post.ID
+	}
+
+	anonymized, anonymizedPost := createWithPost("anonymized")
+	if _, err := svc.PurgeDeleted(ctx, time.Now().Add(-time.Hour), true); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	if _, err := svc.RestoreUser(ctx, int64(anonymized.ID)); err != nil {
+		t.Fatalf("expected the user to survive a purge inside the retention window: %v", err)
+	}
+	if err := svc.DeleteUser(ctx, int64(anonymized.ID)); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	if _, err := svc.PurgeDeleted(ctx, time.Now().Add(time.Minute), true); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	post, err := posts.GetPost(ctx, nil, anonymizedPost)
+	if err != nil {
+		t.Fatalf("expected the anonymized post to stay: %v", err)
+	}
+	if post.Username != DeletedUserUsername {
+		t.Errorf("expected the post to be credited to %q, got %q", DeletedUserUsername, post.Username)
+	}
+	_, err = svc.RestoreUser(ctx, int64(anonymized.ID))
+	assertStatus(t, err, http.StatusNotFound)
+
+	_, cascadedPost := createWithPost("cascaded")
+	if _, err := svc.PurgeDeleted(ctx, time.Now().Add(time.Minute), false); err != nil {
+		t.Fatalf("failed to purge: %v", err)
+	}
+	_, err = posts.GetPost(ctx, nil, cascadedPost)
+	assertStatus(t, err, http.StatusNotFound)
+}
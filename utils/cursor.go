@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrInvalidCursor được trả về khi cursor bị sửa, sai định dạng hoặc dùng nhầm endpoint
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var cursorKey = getCursorSecret()
+
+func getCursorSecret() []byte {
+	if secret := os.Getenv("CURSOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return jwtSecretMaterial()
+}
+
+// EncodeCursor đóng gói vị trí phân trang thành chuỗi opaque "payload.signature" (base64url).
+// scope gắn cursor với một loại danh sách để không dùng lẫn giữa các endpoint.
+func EncodeCursor(scope string, position any) (string, error) {
+	payload, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signCursor(scope, encoded), nil
+}
+
+// DecodeCursor kiểm tra chữ ký rồi giải mã cursor vào position
+func DecodeCursor(scope, cursor string, position any) error {
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(scope, encoded))) {
+		return ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func signCursor(scope, encoded string) string {
+	mac := hmac.New(sha256.New, cursorKey)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
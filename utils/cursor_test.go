@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPosition struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int32     `json:"id"`
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := testPosition{CreatedAt: time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), ID: 42}
+
+	cursor, err := EncodeCursor("posts", want)
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	var got testPosition
+	if err := DecodeCursor("posts", cursor, &got); err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	cursor, err := EncodeCursor("posts", testPosition{ID: 1})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	forged, err := EncodeCursor("posts", testPosition{ID: 2})
+	if err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	payload, _, _ := strings.Cut(forged, ".")
+	_, signature, _ := strings.Cut(cursor, ".")
+
+	tests := map[string]struct {
+		scope  string
+		cursor string
+	}{
+		"swapped payload": {"posts", payload + "." + signature},
+		"other scope":     {"users", cursor},
+		"no signature":    {"posts", payload},
+		"garbage":         {"posts", "not-a-cursor"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var pos testPosition
+			if err := DecodeCursor(tt.scope, tt.cursor, &pos); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("expected ErrInvalidCursor, got %v", err)
+			}
+		})
+	}
+}
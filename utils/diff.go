@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// DiffOp mô tả loại thay đổi của một dòng
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine là một dòng trong kết quả diff
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxDiffCells giới hạn kích thước bảng LCS (số dòng khác nhau của before × after)
+const MaxDiffCells = 1_000_000
+
+// ErrDiffTooLarge is returned when the changed parts are too large to compare line by line
+var ErrDiffTooLarge = errors.New("diff too large")
+
+// LineDiff so sánh hai đoạn văn bản theo từng dòng (dựa trên LCS).
+// Phần đầu và cuối giống nhau được bỏ qua trước khi dựng bảng, nên chỉ đoạn thay đổi bị tính vào MaxDiffCells.
+func LineDiff(before, after string) ([]DiffLine, error) {
+	a, b := splitLines(before), splitLines(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	head, tail := a[:prefix], a[len(a)-suffix:]
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(a), len(b)
+	if (n+1)*(m+1) > MaxDiffCells {
+		return nil, ErrDiffTooLarge
+	}
+
+	// lcs[i][j] = độ dài chuỗi con chung dài nhất của a[i:] và b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := make([]DiffLine, 0, len(head)+n+m+len(tail))
+	for _, line := range head {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < m; j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	for _, line := range tail {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return diff, nil
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
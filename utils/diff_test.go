@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   []DiffLine
+	}{
+		{
+			name:   "identical",
+			before: "a\nb",
+			after:  "a\nb",
+			want:   []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			name:   "changed middle line",
+			before: "a\nb\nc",
+			after:  "a\nx\nc",
+			want:   []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffInsert, "x"}, {DiffEqual, "c"}},
+		},
+		{
+			name:   "from empty",
+			before: "",
+			after:  "a",
+			want:   []DiffLine{{DiffInsert, "a"}},
+		},
+		{
+			name:   "windows line endings",
+			before: "a\r\nb",
+			after:  "a\nb\nc",
+			want:   []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}, {DiffInsert, "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LineDiff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("LineDiff() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LineDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineDiffLimit(t *testing.T) {
+	lines := func(prefix string, n int) string {
+		out := make([]string, n)
+		for i := range out {
+			out[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return strings.Join(out, "\n")
+	}
+
+	// Two unrelated 2000-line texts would need a 4M-cell table
+	if _, err := LineDiff(lines("a", 2000), lines("b", 2000)); !errors.Is(err, ErrDiffTooLarge) {
+		t.Fatalf("expected ErrDiffTooLarge, got %v", err)
+	}
+
+	// A small edit inside a long text only compares the changed lines
+	long := lines("line", 50000)
+	edited := strings.Replace(long, "line25000\n", "changed\n", 1)
+	diff, err := LineDiff(long, edited)
+	if err != nil {
+		t.Fatalf("LineDiff() error = %v", err)
+	}
+	if len(diff) != 50001 || diff[25000] != (DiffLine{DiffDelete, "line25000"}) || diff[25001] != (DiffLine{DiffInsert, "changed"}) {
+		t.Errorf("unexpected diff around the edit: %v", diff[24999:25003])
+	}
+}
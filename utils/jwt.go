@@ -1,44 +1,133 @@
 package utils
 
 import (
-	"os"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte(getJWTSecret())
+// AccessTokenTTL là thời hạn của access token; phiên dài hạn được gia hạn bằng refresh token
+const AccessTokenTTL = 15 * time.Minute
 
-func getJWTSecret() string {
-	if secret := os.Getenv("JWT_SECRET"); secret != "" {
-		return secret
+// MFATokenTTL là thời gian người dùng có để nhập mã 2FA sau khi đúng mật khẩu
+const MFATokenTTL = 5 * time.Minute
+
+const mfaTokenType = "mfa"
+
+// ErrWrongTokenType được trả về khi dùng token "mfa pending" thay cho access token và ngược lại
+var ErrWrongTokenType = errors.New("wrong token type")
+
+// jtiBytes là độ dài (byte) của claim jti ngẫu nhiên
+const jtiBytes = 16
+
+// ErrMissingTokenID được trả về khi token thiếu claim jti
+var ErrMissingTokenID = errors.New("token has no jti claim")
+
+// CreateToken sinh JWT
+func CreateToken(userID int32, email, role string, emailVerified bool) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":            userID,
+		"email":          email,
+		"email_verified": emailVerified,
+		"role":           role,
 	}
-	return "secret"
+	return signToken(claims, AccessTokenTTL)
 }
 
-func JWTSigningKey() []byte {
-	return jwtKey
+// ParseToken parse access token và validate; token "mfa pending" bị từ chối
+func ParseToken(tokenStr string) (jwt.MapClaims, error) {
+	claims, err := parseClaims(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims["typ"] == mfaTokenType {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
 }
 
-// CreateToken sinh JWT
-func CreateToken(userID int32, email string) (string, error) {
+// CreateMFAToken sinh token ngắn hạn chứng minh bước mật khẩu đã qua, chờ mã 2FA.
+// jti do người gọi tạo và lưu phía server để token chỉ dùng được một lần.
+func CreateMFAToken(userID int32, jti string) (string, error) {
 	claims := jwt.MapClaims{
-		"sub":   userID,
-		"email": email,
-		"exp":   time.Now().Add(24 * time.Hour).Unix(),
+		"sub": userID,
+		"typ": mfaTokenType,
+		"jti": jti,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return signToken(claims, MFATokenTTL)
 }
 
-// ParseToken parse JWT và validate
-func ParseToken(tokenStr string) (jwt.MapClaims, error) {
+// ParseMFAToken validate token "mfa pending" và trả về user id cùng jti
+func ParseMFAToken(tokenStr string) (int32, string, error) {
+	claims, err := parseClaims(tokenStr)
+	if err != nil {
+		return 0, "", err
+	}
+	if claims["typ"] != mfaTokenType {
+		return 0, "", ErrWrongTokenType
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, "", ErrWrongTokenType
+	}
+	return int32(sub), claims["jti"].(string), nil
+}
+
+// signToken thêm các claim chuẩn (iss, aud, iat, exp, jti ngẫu nhiên nếu chưa có) và ký bằng khoá đang hoạt động
+func signToken(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	set := jwtKeys.Load()
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		var err error
+		if jti, err = GenerateOpaqueToken(jtiBytes); err != nil {
+			return "", err
+		}
+	}
+
+	now := time.Now()
+	claims["iss"] = set.cfg.Issuer
+	claims["aud"] = set.cfg.Audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	claims["jti"] = jti
+
+	key := set.cfg.Keys[0]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// parseClaims verify chữ ký theo kid và các claim chuẩn
+func parseClaims(tokenStr string) (jwt.MapClaims, error) {
+	set := jwtKeys.Load()
 	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if err != nil || !token.Valid {
+	_, err := jwt.ParseWithClaims(tokenStr, claims, set.keyFunc,
+		jwt.WithValidMethods(set.methods),
+		jwt.WithIssuer(set.cfg.Issuer),
+		jwt.WithAudience(set.cfg.Audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
 		return nil, err
 	}
+	if jti, _ := claims["jti"].(string); jti == "" {
+		return nil, ErrMissingTokenID
+	}
 	return claims, nil
 }
+
+// keyFunc chọn khoá theo kid; thuật toán phải khớp với khoá để chặn tấn công đổi alg
+func (s *jwtKeySet) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.byID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Giá trị mặc định của claim iss/aud, đổi bằng JWT_ISSUER và JWT_AUDIENCE
+const (
+	DefaultJWTIssuer   = "manager-user"
+	DefaultJWTAudience = "manager-user-api"
+)
+
+// minJWTSecretLen là độ dài tối thiểu của JWT_SECRET ở production (HS256 cần >= 256 bit)
+const minJWTSecretLen = 32
+
+// devJWTSecret chỉ dùng khi chạy local; production bắt buộc cấu hình key
+const devJWTSecret = "secret"
+
+// JWTKey là một khoá ký JWT, định danh bằng kid trong header của token
+type JWTKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	signKey   any // nil với khoá đã nghỉ hưu, chỉ còn dùng để verify
+	verifyKey any
+}
+
+// CanSign cho biết khoá có private key (hoặc secret) để ký token mới hay không
+func (k JWTKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+func (k JWTKey) verifyOnly() JWTKey {
+	k.signKey = nil
+	return k
+}
+
+// JWTConfig mô tả cách ký và verify token. Keys[0] ký token mới; các khoá còn lại
+// vẫn được chấp nhận khi verify để rotate key mà không đăng xuất mọi người.
+type JWTConfig struct {
+	Issuer   string
+	Audience string
+	Keys     []JWTKey
+}
+
+type jwtKeySet struct {
+	cfg     JWTConfig
+	byID    map[string]JWTKey
+	methods []string
+}
+
+var jwtKeys atomic.Pointer[jwtKeySet]
+
+func init() {
+	// Cấu hình dev/test; server gọi ConfigureJWT với LoadJWTConfig lúc khởi động
+	if err := ConfigureJWT(JWTConfig{
+		Issuer:   DefaultJWTIssuer,
+		Audience: DefaultJWTAudience,
+		Keys:     []JWTKey{NewHMACKey(jwtSecretMaterial())},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// ConfigureJWT thay bộ khoá dùng bởi CreateToken/ParseToken
+func ConfigureJWT(cfg JWTConfig) error {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		return errors.New("jwt: issuer and audience are required")
+	}
+	if len(cfg.Keys) == 0 || !cfg.Keys[0].CanSign() {
+		return errors.New("jwt: the first key must be able to sign")
+	}
+
+	set := &jwtKeySet{cfg: cfg, byID: make(map[string]JWTKey, len(cfg.Keys))}
+	for _, key := range cfg.Keys {
+		if _, dup := set.byID[key.ID]; dup {
+			return fmt.Errorf("jwt: duplicate key id %q", key.ID)
+		}
+		set.byID[key.ID] = key
+		alg := key.Method.Alg()
+		if !slices.Contains(set.methods, alg) {
+			set.methods = append(set.methods, alg)
+		}
+	}
+	jwtKeys.Store(set)
+	return nil
+}
+
+// LoadJWTConfig đọc cấu hình từ env:
+//   - JWT_PRIVATE_KEY_FILE: PEM RSA (RS256) hoặc Ed25519 (EdDSA) để ký; không có thì dùng HS256 với JWT_SECRET
+//   - JWT_PREVIOUS_KEY_FILES, JWT_PREVIOUS_SECRETS: danh sách (phân tách bởi dấu phẩy) khoá cũ, chỉ để verify
+//   - JWT_ISSUER, JWT_AUDIENCE
+//
+// Ở production thiếu khoá hoặc secret quá ngắn là lỗi thay vì rơi về secret mặc định.
+func LoadJWTConfig(production bool) (JWTConfig, error) {
+	cfg := JWTConfig{
+		Issuer:   envOr("JWT_ISSUER", DefaultJWTIssuer),
+		Audience: envOr("JWT_AUDIENCE", DefaultJWTAudience),
+	}
+
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		key, err := readJWTKeyFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		if !key.CanSign() {
+			return cfg, errors.New("JWT_PRIVATE_KEY_FILE: expected a private key")
+		}
+		// Cursor mặc định ký bằng JWT_SECRET
+		if production && os.Getenv("JWT_SECRET") == "" && os.Getenv("CURSOR_SECRET") == "" {
+			return cfg, errors.New("JWT_SECRET or CURSOR_SECRET must be set in production")
+		}
+		cfg.Keys = append(cfg.Keys, key)
+	} else {
+		secret := os.Getenv("JWT_SECRET")
+		switch {
+		case secret == "" && production:
+			return cfg, errors.New("JWT_SECRET or JWT_PRIVATE_KEY_FILE must be set in production")
+		case secret == "":
+			secret = devJWTSecret
+		case production && len(secret) < minJWTSecretLen:
+			return cfg, fmt.Errorf("JWT_SECRET must be at least %d characters in production", minJWTSecretLen)
+		}
+		cfg.Keys = append(cfg.Keys, NewHMACKey([]byte(secret)))
+	}
+
+	for _, path := range splitList(os.Getenv("JWT_PREVIOUS_KEY_FILES")) {
+		key, err := readJWTKeyFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("JWT_PREVIOUS_KEY_FILES: %w", err)
+		}
+		cfg.Keys = append(cfg.Keys, key.verifyOnly())
+	}
+	for _, secret := range splitList(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+		cfg.Keys = append(cfg.Keys, NewHMACKey([]byte(secret)).verifyOnly())
+	}
+	return cfg, nil
+}
+
+// jwtSecretMaterial là JWT_SECRET (hoặc secret dev), dùng làm mặc định cho các khoá HMAC khác
+func jwtSecretMaterial() []byte {
+	return []byte(envOr("JWT_SECRET", devJWTSecret))
+}
+
+// NewHMACKey tạo khoá HS256; kid suy ra từ hash của secret
+func NewHMACKey(secret []byte) JWTKey {
+	sum := sha256.Sum256(secret)
+	return JWTKey{
+		ID:        "hs-" + hex.EncodeToString(sum[:8]),
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// ParseJWTKeyPEM đọc khoá RSA hoặc Ed25519 (PKCS#8, PKCS#1 hoặc PKIX). Khoá công khai
+// chỉ dùng để verify. kid là JWK thumbprint (RFC 7638) của khoá công khai.
+func ParseJWTKeyPEM(data []byte) (JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return JWTKey{}, errors.New("no PEM block found")
+	}
+
+	var signKey, verifyKey any
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		signKey = key
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		signKey = key
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		verifyKey = key
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return JWTKey{}, err
+		}
+		verifyKey = key
+	default:
+		return JWTKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	key := JWTKey{signKey: signKey}
+	switch k := signKey.(type) {
+	case *rsa.PrivateKey:
+		verifyKey = &k.PublicKey
+	case ed25519.PrivateKey:
+		verifyKey = k.Public()
+	case nil:
+	default:
+		return JWTKey{}, fmt.Errorf("unsupported private key type %T", signKey)
+	}
+
+	switch verifyKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return JWTKey{}, fmt.Errorf("unsupported public key type %T", verifyKey)
+	}
+	key.verifyKey = verifyKey
+
+	jwk, _ := key.jwk()
+	key.ID = jwk.thumbprint()
+	return key, nil
+}
+
+func readJWTKeyFile(path string) (JWTKey, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return JWTKey{}, err
+	}
+	key, err := ParseJWTKeyPEM(data)
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// JWK là khoá công khai theo RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet là nội dung của /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS trả về các khoá công khai đang được chấp nhận; khoá HMAC là bí mật nên không công bố
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range jwtKeys.Load().cfg.Keys {
+		if jwk, ok := key.jwk(); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func (k JWTKey) jwk() (JWK, bool) {
+	jwk := JWK{Kid: k.ID, Use: "sig"}
+	if k.Method != nil {
+		jwk.Alg = k.Method.Alg()
+	}
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// thumbprint tính JWK thumbprint (RFC 7638): SHA-256 của các member bắt buộc, theo thứ tự từ điển
+func (j JWK) thumbprint() string {
+	var members any
+	switch j.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.Kty, j.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Crv, j.Kty, j.X}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// useJWTConfig cấu hình khoá cho một test và khôi phục cấu hình cũ khi test kết thúc
+func useJWTConfig(t *testing.T, keys ...JWTKey) {
+	t.Helper()
+	previous := jwtKeys.Load()
+	t.Cleanup(func() { jwtKeys.Store(previous) })
+
+	if err := ConfigureJWT(JWTConfig{Issuer: DefaultJWTIssuer, Audience: DefaultJWTAudience, Keys: keys}); err != nil {
+		t.Fatalf("failed to configure jwt: %v", err)
+	}
+}
+
+func pemKey(t *testing.T, key any) JWTKey {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	parsed, err := ParseJWTKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("failed to parse key: %v", err)
+	}
+	return parsed
+}
+
+func rsaKey(t *testing.T) JWTKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate rsa key: %v", err)
+	}
+	return pemKey(t, key)
+}
+
+func ed25519Key(t *testing.T) JWTKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	return pemKey(t, key)
+}
+
+func TestTokenRoundTripPerAlgorithm(t *testing.T) {
+	keys := map[string]JWTKey{
+		"HS256": NewHMACKey([]byte("a-test-secret-that-is-long-enough")),
+		"RS256": rsaKey(t),
+		"EdDSA": ed25519Key(t),
+	}
+	for alg, key := range keys {
+		t.Run(alg, func(t *testing.T) {
+			useJWTConfig(t, key)
+
+			tokenStr, err := CreateToken(7, "a@example.com", "user", true)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			token, _, _ := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+			if token.Method.Alg() != alg || token.Header["kid"] != key.ID {
+				t.Errorf("expected alg %s and kid %s, got %v", alg, key.ID, token.Header)
+			}
+
+			claims, err := ParseToken(tokenStr)
+			if err != nil {
+				t.Fatalf("failed to parse token: %v", err)
+			}
+			for _, name := range []string{"iss", "aud", "iat", "exp", "jti"} {
+				if _, ok := claims[name]; !ok {
+					t.Errorf("expected claim %q in %v", name, claims)
+				}
+			}
+			if claims["sub"] != float64(7) || claims["role"] != "user" {
+				t.Errorf("unexpected claims %v", claims)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := rsaKey(t), ed25519Key(t)
+
+	useJWTConfig(t, oldKey)
+	issued, err := CreateToken(1, "a@example.com", "user", true)
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// Old key retired but still trusted: existing sessions keep working
+	useJWTConfig(t, newKey, oldKey.verifyOnly())
+	if _, err := ParseToken(issued); err != nil {
+		t.Errorf("expected a token from the previous key to verify: %v", err)
+	}
+	fresh, _ := CreateToken(1, "a@example.com", "user", true)
+	token, _, _ := jwt.NewParser().ParseUnverified(fresh, jwt.MapClaims{})
+	if token.Header["kid"] != newKey.ID {
+		t.Errorf("expected new tokens to be signed with %s, got %v", newKey.ID, token.Header["kid"])
+	}
+
+	jwks := JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != newKey.ID || jwks.Keys[1].Kid != oldKey.ID {
+		t.Errorf("expected both public keys in the JWKS, got %+v", jwks.Keys)
+	}
+
+	// Old key removed
+	useJWTConfig(t, newKey)
+	if _, err := ParseToken(issued); err == nil {
+		t.Errorf("expected a token from a removed key to be rejected")
+	}
+}
+
+func TestParseTokenRejectsForeignTokens(t *testing.T) {
+	key := NewHMACKey([]byte("a-test-secret-that-is-long-enough"))
+	useJWTConfig(t, key)
+
+	sign := func(method jwt.SigningMethod, signKey any, claims jwt.MapClaims) string {
+		base := jwt.MapClaims{
+			"sub": 1,
+			"iss": DefaultJWTIssuer,
+			"aud": DefaultJWTAudience,
+			"iat": time.Now().Unix(),
+			"exp": time.Now().Add(time.Minute).Unix(),
+			"jti": "id",
+		}
+		for k, v := range claims {
+			if v == nil {
+				delete(base, k)
+			} else {
+				base[k] = v
+			}
+		}
+		token := jwt.NewWithClaims(method, base)
+		token.Header["kid"] = key.ID
+		s, err := token.SignedString(signKey)
+		if err != nil {
+			t.Fatalf("failed to sign: %v", err)
+		}
+		return s
+	}
+	secret := key.signKey
+
+	if _, err := ParseToken(sign(jwt.SigningMethodHS256, secret, nil)); err != nil {
+		t.Fatalf("expected the baseline token to verify: %v", err)
+	}
+
+	tests := map[string]string{
+		"other issuer":   sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"iss": "someone-else"}),
+		"other audience": sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"aud": "another-api"}),
+		"future iat":     sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"iat": time.Now().Add(time.Hour).Unix()}),
+		"no exp":         sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"exp": nil}),
+		"no jti":         sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"jti": nil}),
+		"other alg":      sign(jwt.SigningMethodHS512, secret, nil),
+		"wrong secret":   sign(jwt.SigningMethodHS256, []byte("another-secret"), nil),
+	}
+	for name, tokenStr := range tests {
+		if _, err := ParseToken(tokenStr); err == nil {
+			t.Errorf("%s: expected the token to be rejected", name)
+		}
+	}
+}
+
+func TestLoadJWTConfigInProduction(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	if _, err := LoadJWTConfig(true); err == nil {
+		t.Errorf("expected a missing secret to fail in production")
+	}
+	if _, err := LoadJWTConfig(false); err != nil {
+		t.Errorf("expected the development default outside production: %v", err)
+	}
+
+	t.Setenv("JWT_SECRET", "short")
+	if _, err := LoadJWTConfig(true); err == nil {
+		t.Errorf("expected a short secret to fail in production")
+	}
+
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	t.Setenv("JWT_PRIVATE_KEY_FILE", path)
+	t.Setenv("JWT_PREVIOUS_SECRETS", "a-previous-secret-that-is-long-enough")
+	cfg, err := LoadJWTConfig(true)
+	if err != nil {
+		t.Fatalf("expected the key file to be accepted: %v", err)
+	}
+	if len(cfg.Keys) != 2 || cfg.Keys[0].Method != jwt.SigningMethodEdDSA || cfg.Keys[1].CanSign() {
+		t.Errorf("expected an EdDSA signing key and a verify-only secret, got %+v", cfg.Keys)
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// ErrInvalidCiphertext được trả về khi dữ liệu mã hoá bị sửa hoặc dùng sai key
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// ErrSecretKeyNotConfigured được trả về khi EncryptSecret/DecryptSecret chạy trước ConfigureSecretKey
+var ErrSecretKeyNotConfigured = errors.New("secret encryption key is not configured")
+
+// minSecretKeyLen là độ dài tối thiểu của SECRET_ENCRYPTION_KEY
+const minSecretKeyLen = 32
+
+var secretKey atomic.Pointer[[]byte]
+
+// LoadSecretKey đọc SECRET_ENCRYPTION_KEY. Khoá này bắt buộc và độc lập với JWT secret,
+// để xoay hoặc lộ JWT secret không ảnh hưởng tới dữ liệu đã mã hoá.
+func LoadSecretKey() ([]byte, error) {
+	key := os.Getenv("SECRET_ENCRYPTION_KEY")
+	if key == "" {
+		return nil, errors.New("SECRET_ENCRYPTION_KEY must be set")
+	}
+	if len(key) < minSecretKeyLen {
+		return nil, fmt.Errorf("SECRET_ENCRYPTION_KEY must be at least %d characters", minSecretKeyLen)
+	}
+	return []byte(key), nil
+}
+
+// ConfigureSecretKey đặt khoá dùng bởi EncryptSecret/DecryptSecret; key AES-256 là SHA-256 của material
+func ConfigureSecretKey(material []byte) {
+	sum := sha256.Sum256(material)
+	key := sum[:]
+	secretKey.Store(&key)
+}
+
+// EncryptSecret mã hoá AES-GCM giá trị nhạy cảm trước khi lưu DB (base64url "nonce||ciphertext")
+func EncryptSecret(plaintext string) (string, error) {
+	gcm, err := newSecretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret giải mã giá trị tạo bởi EncryptSecret
+func DecryptSecret(ciphertext string) (string, error) {
+	gcm, err := newSecretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+func newSecretCipher() (cipher.AEAD, error) {
+	key := secretKey.Load()
+	if key == nil {
+		return nil, ErrSecretKeyNotConfigured
+	}
+	block, err := aes.NewCipher(*key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Tham số TOTP theo RFC 6238, khớp mặc định của các app authenticator
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+
+	totpSecretBytes = 20
+	totpSkewSteps   = 1 // chấp nhận lệch đồng hồ ±1 bước (30 giây)
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret sinh secret ngẫu nhiên dạng base32 (không padding)
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPStep là số thứ tự bước thời gian (30 giây) chứa t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode tính mã TOTP của secret tại thời điểm t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(TOTPStep(t))), nil
+}
+
+// ValidateTOTP kiểm tra mã người dùng nhập, cho phép lệch totpSkewSteps bước, và trả về
+// bước khớp để người gọi từ chối mã đã dùng (bước <= bước được chấp nhận gần nhất)
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+	for skew := -totpSkewSteps; skew <= totpSkewSteps; skew++ {
+		at := t.Add(time.Duration(skew) * TOTPPeriod)
+		expected, err := TOTPCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return TOTPStep(at), true
+		}
+	}
+	return 0, false
+}
+
+// TOTPURI trả về otpauth:// URI để app authenticator quét qua QR code
+func TOTPURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// hotp implements RFC 4226 dynamic truncation
+func hotp(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 appendix B (SHA-1), truncated to 6 digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range tests {
+		got, err := TOTPCode(secret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) failed: %v", unix, err)
+		}
+		if got != want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	now := time.Now()
+
+	previous, _ := TOTPCode(secret, now.Add(-TOTPPeriod))
+	if step, ok := ValidateTOTP(secret, previous, now); !ok || step != TOTPStep(now)-1 {
+		t.Errorf("expected the previous step to be accepted as step %d, got (%d, %v)", TOTPStep(now)-1, step, ok)
+	}
+
+	stale, _ := TOTPCode(secret, now.Add(-3*TOTPPeriod))
+	if _, ok := ValidateTOTP(secret, stale, now); ok {
+		t.Errorf("expected a code three steps old to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Errorf("expected a short code to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("My App", "alice@example.com", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/My%20App:alice@example.com?") || !strings.Contains(uri, "secret=ABC") {
+		t.Errorf("unexpected URI %s", uri)
+	}
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	ConfigureSecretKey([]byte("a-test-encryption-key-of-32-chars"))
+
+	sealed, err := EncryptSecret("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("ciphertext contains the plaintext")
+	}
+
+	opened, err := DecryptSecret(sealed)
+	if err != nil || opened != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("got (%q, %v)", opened, err)
+	}
+
+	tampered := []byte(sealed)
+	// The first character only carries nonce bits; the last may be partly padding
+	tampered[0] ^= 'A' ^ 'B'
+	if _, err := DecryptSecret(string(tampered)); err != ErrInvalidCiphertext {
+		t.Errorf("expected ErrInvalidCiphertext for tampered data, got %v", err)
+	}
+}
+
+func TestLoadSecretKey(t *testing.T) {
+	t.Setenv("JWT_SECRET", "a-jwt-secret-that-is-long-enough-too")
+	t.Setenv("SECRET_ENCRYPTION_KEY", "")
+	if _, err := LoadSecretKey(); err == nil {
+		t.Errorf("expected a missing key to fail instead of falling back to the JWT secret")
+	}
+
+	t.Setenv("SECRET_ENCRYPTION_KEY", "short")
+	if _, err := LoadSecretKey(); err == nil {
+		t.Errorf("expected a short key to fail")
+	}
+
+	t.Setenv("SECRET_ENCRYPTION_KEY", "an-encryption-key-that-is-long-enough")
+	if key, err := LoadSecretKey(); err != nil || string(key) != "an-encryption-key-that-is-long-enough" {
+		t.Errorf("got (%q, %v)", key, err)
+	}
+}